@@ -0,0 +1,39 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"net"
+	"strings"
+	"time"
+
+	pb "github.com/Ekotlikoff/gochess/api"
+	"github.com/Ekotlikoff/gochess/internal/fakeengine"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func main() {
+	addr := flag.String("addr", "localhost:50051", "address to listen on")
+	moves := flag.String("moves", "",
+		"comma separated moves to play in UCI notation, or resign or draw")
+	acceptDraws := flag.Bool("acceptdraws", false, "accept draw offers")
+	seed := flag.Int64("seed", time.Now().UnixNano(),
+		"seed for the random moves")
+	flag.Parse()
+	script := []string{}
+	if *moves != "" {
+		script = strings.Split(*moves, ",")
+	}
+	listener, err := net.Listen("tcp", *addr)
+	if err != nil {
+		log.Fatal(err)
+	}
+	server := grpc.NewServer()
+	pb.RegisterRustChessServer(server,
+		fakeengine.NewServer(script, *acceptDraws, *seed))
+	healthpb.RegisterHealthServer(server, health.NewServer())
+	log.Println("Fake engine listening on", listener.Addr())
+	log.Fatal(server.Serve(listener))
+}
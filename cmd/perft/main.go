@@ -0,0 +1,42 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/Ekotlikoff/gochess/internal/model"
+)
+
+func main() {
+	fen := flag.String("fen", model.StartingFEN, "position to search")
+	depth := flag.Int("depth", 4, "depth to search")
+	divide := flag.Bool("divide", false, "print the node count per move")
+	flag.Parse()
+	game, err := model.NewGameFromFEN(*fen)
+	if err != nil {
+		log.Fatal(err)
+	}
+	start := time.Now()
+	nodes := uint64(0)
+	if *divide {
+		counts := game.Divide(*depth)
+		moves := []string{}
+		for move := range counts {
+			moves = append(moves, move)
+		}
+		sort.Strings(moves)
+		for _, move := range moves {
+			fmt.Printf("%s: %d\n", move, counts[move])
+			nodes += counts[move]
+		}
+		fmt.Println()
+	} else {
+		nodes = game.Perft(*depth)
+	}
+	elapsed := time.Since(start)
+	fmt.Println("Nodes searched:", nodes)
+	fmt.Println("Time:", elapsed)
+}
@@ -12,6 +12,7 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/Ekotlikoff/gochess/internal/model"
 	httpserver "github.com/Ekotlikoff/gochess/internal/server/backend/http"
 	matchserver "github.com/Ekotlikoff/gochess/internal/server/backend/match"
 	websocketserver "github.com/Ekotlikoff/gochess/internal/server/backend/websocket"
@@ -34,11 +35,19 @@ type (
 		EnableBotMatching       bool
 		EngineConnectionTimeout string
 		EngineAddr              string
+		EngineAddrs             []string
+		EngineMaxGames          int
+		EngineDepth             int
+		EngineMoveTime          string
+		UCIEnginePath           string
 		GatewayPort             int
 		HTTPPort                int
 		WSPort                  int
 		MaxMatchingDuration     string
 		MatchPlayerTimeSeconds  int
+		TimeControl             string
+		Chess960                bool
+		Variant                 string
 		LogFile                 string
 		EnableTracing           bool
 		Quiet                   bool
@@ -72,14 +81,54 @@ func startChessServer(config Configuration) {
 	var matchingServer matchserver.MatchingServer
 	if !config.EnableBotMatching {
 		matchingServer = matchserver.NewMatchingServer()
+	} else if config.UCIEnginePath != "" {
+		matchingServer = matchserver.NewMatchingServerWithUCIEngine(
+			config.UCIEnginePath, maxMatchingDuration)
 	} else {
-		matchingServer = matchserver.NewMatchingServerWithEngine(
-			config.EngineAddr, maxMatchingDuration, engineConnTimeout)
+		engineAddrs := config.EngineAddrs
+		if len(engineAddrs) == 0 {
+			engineAddrs = []string{config.EngineAddr}
+		}
+		matchingServer = matchserver.NewMatchingServerWithEngines(
+			engineAddrs, config.EngineMaxGames, maxMatchingDuration,
+			engineConnTimeout)
+	}
+	if config.EnableBotMatching {
+		engineMoveTime, err := time.ParseDuration(config.EngineMoveTime)
+		if err != nil {
+			log.Fatal(err)
+		}
+		matchingServer.SetBuiltinEngine(config.EngineDepth, engineMoveTime)
+	}
+	timeControl := matchserver.NewTimeControl(
+		int64(config.MatchPlayerTimeSeconds*1000), 0)
+	if config.TimeControl != "" {
+		var err error
+		timeControl, err = matchserver.ParseTimeControl(config.TimeControl)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	matchGenerator := matchserver.CreateTimeControlMatchGenerator(
+		timeControl, model.Standard)
+	if config.Chess960 {
+		matchGenerator = matchserver.CreateTimeControlChess960MatchGenerator(
+			timeControl)
+	} else if config.Variant != "" {
+		variant, err := model.VariantFromName(config.Variant)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if variant == model.Bughouse {
+			matchGenerator = matchserver.
+				CreateTimeControlBughouseMatchGenerator(timeControl)
+		} else {
+			matchGenerator = matchserver.CreateTimeControlMatchGenerator(
+				timeControl, variant)
+		}
 	}
 	exitChan := make(chan bool, 1)
-	go matchingServer.StartCustomMatchServers(10,
-		matchserver.CreateCustomMatchGenerator(config.MatchPlayerTimeSeconds),
-		exitChan)
+	go matchingServer.StartCustomMatchServers(10, matchGenerator, exitChan)
 	if config.BackendType == HTTPBackend {
 		go httpserver.Serve(&matchingServer, config.HTTPPort)
 	} else if config.BackendType == WebsocketBackend {
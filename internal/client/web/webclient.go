@@ -7,6 +7,7 @@ import (
 	"sync"
 	"syscall/js"
 
+	"github.com/Ekotlikoff/gochess/internal/eval"
 	"github.com/Ekotlikoff/gochess/internal/model"
 )
 
@@ -95,10 +96,10 @@ func (cm *ClientModel) GetTurn() model.Color {
 	return cm.game.Turn()
 }
 
-func (cm *ClientModel) GetPointAdvantage(color model.Color) int8 {
+func (cm *ClientModel) GetEvaluation(color model.Color) int {
 	cm.gameMutex.Lock()
 	defer cm.gameMutex.Unlock()
-	return cm.game.PointAdvantage(color)
+	return eval.Evaluate(cm.game).For(color)
 }
 
 func (cm *ClientModel) GetPromotionMoveRequest() model.MoveRequest {
@@ -377,17 +377,10 @@ func (cm *ClientModel) listenForAsyncUpdateHttp() {
 func (cm *ClientModel) handleResponseAsync(responseAsync matchserver.ResponseAsync) {
 	if responseAsync.GameOver {
 		cm.remoteGameEnd()
-		winType := ""
-		if responseAsync.Resignation {
-			winType = "resignation"
-		} else if responseAsync.Draw {
-			winType = "draw"
+		if responseAsync.Draw {
 			cm.ClearRequestedDraw()
-		} else if responseAsync.Timeout {
-			winType = "timeout"
-		} else {
-			winType = "mate"
 		}
+		winType := responseAsync.Termination.String()
 		log.Println("Winner:", responseAsync.Winner, "by", winType)
 		cm.viewSetGameOver(responseAsync.Winner, winType)
 		return
@@ -400,9 +393,12 @@ func (cm *ClientModel) handleResponseAsync(responseAsync matchserver.ResponseAsy
 
 func (cm *ClientModel) handleResponseSync(responseSync matchserver.ResponseSync) {
 	if responseSync.MoveSuccess {
-		cm.SetPlayerElapsedMs(cm.playerColor, int64(responseSync.ElapsedMs))
+		// The clocks count increments and delays, which the time spent on
+		// moves does not, so the displayed time is derived from them.
+		cm.SetPlayerElapsedMs(cm.playerColor,
+			cm.GetMaxTimeMs()-responseSync.Clock.RemainingMs)
 		cm.SetPlayerElapsedMs(cm.GetOpponentColor(),
-			int64(responseSync.ElapsedMsOpponent))
+			cm.GetMaxTimeMs()-responseSync.OpponentClock.RemainingMs)
 	}
 }
 
@@ -492,7 +488,7 @@ func (cm *ClientModel) lookForMatch() {
 	cm.SetPlayerColor(matchResponse.Color)
 	cm.SetOpponentName(matchResponse.OpponentName)
 	cm.SetMaxTimeMs(matchResponse.MaxTimeMs)
-	cm.resetGame()
+	cm.resetGameFromMatch(matchResponse)
 	// - TODO once matched briefly display matched icon?
 	cm.SetGameType(Remote)
 	cm.SetIsMatched(true)
@@ -650,6 +646,33 @@ func (cm *ClientModel) resetGame() {
 	cm.viewInitBoard(cm.playerColor)
 }
 
+func (cm *ClientModel) resetGameFromMatch(
+	matchResponse matchserver.MatchedResponse) {
+	if matchResponse.StartFEN == "" {
+		cm.resetGame()
+		return
+	}
+	variant, err := model.VariantFromName(matchResponse.Variant)
+	if err != nil {
+		variant = model.Standard
+	}
+	newGame := func(fen string) (*model.Game, error) {
+		return model.NewGameVariantFromFEN(variant, fen)
+	}
+	if matchResponse.Chess960 {
+		newGame = model.NewGameChess960FromFEN
+	}
+	game, err := newGame(matchResponse.StartFEN)
+	if err != nil {
+		log.Println("FATAL: Invalid start position from the server: ", err)
+		cm.resetGame()
+		return
+	}
+	cm.SetGame(game)
+	cm.viewClearBoard()
+	cm.viewInitBoard(cm.playerColor)
+}
+
 func retryWrapper(f func() (*http.Response, error), uri string, successCode int,
 	onMaxRetries func()) (*http.Response, error) {
 	retries := 0
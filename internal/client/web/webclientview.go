@@ -115,10 +115,10 @@ func (cm *ClientModel) viewClearMatchDetails() {
 
 func (cm *ClientModel) viewSetMatchDetailsPoints(
 	color model.Color, elementID string) {
-	points := cm.GetPointAdvantage(color)
+	centipawns := cm.GetEvaluation(color)
 	pointSummary := ""
-	if points > 0 {
-		pointSummary = strconv.Itoa(int(points))
+	if centipawns > 0 {
+		pointSummary = fmt.Sprintf("+%.1f", float64(centipawns)/100)
 	}
 	matchDetailsPoints := cm.document.Call("getElementById", elementID)
 	matchDetailsPoints.Set("innerText", pointSummary)
@@ -131,8 +131,12 @@ func (cm *ClientModel) formatTime(ms int64) string {
 func (cm *ClientModel) viewSetGameOver(winner, winType string) {
 	addClass(cm.document.Call("getElementById", "gameover_modal"), "gameover_modal")
 	removeClass(cm.document.Call("getElementById", "gameover_modal"), "hidden")
+	text := fmt.Sprintf("Winner: %s by %s", winner, winType)
+	if winner == "" {
+		text = fmt.Sprintf("Game over by %s", winType)
+	}
 	cm.document.Call("getElementById", "gameover_modal_text").Set("innerText",
-		fmt.Sprintf("Winner: %s by %s", winner, winType))
+		text)
 }
 
 func (clientModel *ClientModel) viewClearBoard() {
@@ -163,6 +167,16 @@ func (clientModel *ClientModel) viewInitBoard(playerColor model.Color) {
 
 func (cm *ClientModel) viewHandleMove(
 	moveRequest model.MoveRequest, newPos model.Position, elMoving js.Value) {
+	if moveRequest.IsDrop() || (cm.game.Chess960() &&
+		(hasClass(elMoving, "wk") || hasClass(elMoving, "bk"))) ||
+		cm.game.Variant() == model.Atomic {
+		// A drop has no piece to move, a Chess960 king may castle onto its
+		// own rook and an Atomic capture may explode several pieces, redraw
+		// the board.
+		cm.viewClearBoard()
+		cm.viewInitBoard(cm.playerColor)
+		return
+	}
 	originalPositionClass :=
 		getPositionClass(moveRequest.Position, cm.playerColor)
 	newPositionClass := getPositionClass(newPos, cm.playerColor)
@@ -333,3 +347,7 @@ func getPositionClass(position model.Position, playerColor model.Color) string {
 	}
 	return class
 }
+
+func hasClass(element js.Value, class string) bool {
+	return element.Get("classList").Call("contains", class).Bool()
+}
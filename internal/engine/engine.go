@@ -0,0 +1,176 @@
+// Package engine is a chess engine that plays any variant of the model by
+// alpha-beta search, for the matchserver's bots when no remote engine is
+// reachable
+package engine
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Ekotlikoff/gochess/internal/model"
+)
+
+const (
+	// DefaultDepth is the depth searched when neither a depth nor a move
+	// time is set
+	DefaultDepth = 4
+	// maxDepth is the deepest iteration a search limited only by time runs
+	maxDepth = 64
+
+	mateScore     = 100000
+	mateThreshold = mateScore - 1000
+	infinity      = mateScore + 1
+
+	tableSize = 1 << 18
+	// checkInterval is how many nodes are searched between checks of the
+	// clock
+	checkInterval = 1024
+)
+
+type (
+	// Engine searches for the best move with iterative deepening, stopping
+	// at its depth or once its time per move runs out. It keeps its
+	// transposition table between searches, so an engine should play one
+	// game, and it is not safe for concurrent use.
+	Engine struct {
+		depth    int
+		moveTime time.Duration
+		table    transpositionTable
+		ctx      context.Context
+		deadline time.Time
+		nodes    uint64
+		canStop  bool
+		stopped  bool
+	}
+
+	// SearchResult is the best move found, with its score in centipawns for
+	// the side to move and the depth of the last completed iteration. The
+	// line is the expected play from the best move on.
+	SearchResult struct {
+		Move  model.MoveRequest
+		Score int
+		Depth int
+		Nodes uint64
+		Line  []model.MoveRequest
+	}
+)
+
+// NewEngine create an engine that searches to the depth in plies and for at
+// most the move time. A depth of zero searches until the time runs out and a
+// move time of zero until the depth is reached.
+func NewEngine(depth int, moveTime time.Duration) *Engine {
+	if depth <= 0 && moveTime <= 0 {
+		depth = DefaultDepth
+	} else if depth <= 0 || depth > maxDepth {
+		depth = maxDepth
+	}
+	return &Engine{
+		depth: depth, moveTime: moveTime,
+		table: newTranspositionTable(tableSize),
+	}
+}
+
+// Search find the best move for the side to move in the game, which it
+// searches a copy of. The best move of the deepest completed iteration is
+// returned, the first iteration always completes.
+func (engine *Engine) Search(game *model.Game) (SearchResult, error) {
+	return engine.SearchContext(context.Background(), game, 0)
+}
+
+// SearchContext search as Search does, for no longer than the move time if
+// it is positive and shorter than the engine's own, stopping right away
+// once the context is done
+func (engine *Engine) SearchContext(
+	ctx context.Context, game *model.Game, moveTime time.Duration,
+) (SearchResult, error) {
+	position := game.Clone()
+	moves := position.LegalMoves()
+	if len(moves) == 0 {
+		return SearchResult{}, errors.New("no legal moves to search")
+	}
+	engine.nodes, engine.canStop, engine.stopped = 0, false, false
+	engine.ctx, engine.deadline = ctx, time.Time{}
+	if engine.moveTime > 0 && (moveTime <= 0 || engine.moveTime < moveTime) {
+		moveTime = engine.moveTime
+	}
+	if moveTime > 0 {
+		engine.deadline = time.Now().Add(moveTime)
+	}
+	result := SearchResult{Move: moves[0]}
+	for depth := 1; depth <= engine.depth; depth++ {
+		score, move := engine.searchRoot(position, moves, depth)
+		if engine.stopped {
+			break
+		}
+		result = SearchResult{Move: move, Score: score, Depth: depth}
+		engine.canStop = true
+		if IsMateScore(score) {
+			// A shorter mate would have been found at a shallower depth.
+			break
+		}
+	}
+	result.Nodes = engine.nodes
+	result.Line = engine.principalVariation(position, result.Move,
+		result.Depth)
+	return result, nil
+}
+
+// principalVariation follow the stored best moves from the best move on, at
+// most to the depth, to get the line the search expects
+func (engine *Engine) principalVariation(
+	position *model.Game, move model.MoveRequest, depth int,
+) []model.MoveRequest {
+	line := []model.MoveRequest{}
+	for len(line) < depth {
+		// The stored move may belong to another position with the same slot.
+		legal := false
+		for _, legalMove := range position.LegalMoves() {
+			legal = legal || sameMove(legalMove, move)
+		}
+		if !legal || position.Move(move) != nil {
+			break
+		}
+		line = append(line, move)
+		next := engine.hashMove(position.Hash())
+		if next == nil {
+			break
+		}
+		move = *next
+	}
+	for range line {
+		position.Undo()
+	}
+	return line
+}
+
+// IsMateScore get whether the score is a forced mate, for the side to move
+// if it is positive
+func IsMateScore(score int) bool {
+	return score > mateThreshold || score < -mateThreshold
+}
+
+// MateIn get the number of moves to mate for a forced mate score, positive
+// if the side to move mates, or 0 if the score is not a mate
+func MateIn(score int) int {
+	if score > mateThreshold {
+		return (mateScore - score + 1) / 2
+	} else if score < -mateThreshold {
+		return -(mateScore + score + 1) / 2
+	}
+	return 0
+}
+
+// timeUp get whether the search should stop, checking every checkInterval
+// nodes whether the context is done, and the clock once the first iteration
+// has completed
+func (engine *Engine) timeUp() bool {
+	if engine.stopped {
+		return true
+	} else if engine.nodes%checkInterval != 0 {
+		return false
+	}
+	engine.stopped = engine.ctx.Err() != nil || (engine.canStop &&
+		!engine.deadline.IsZero() && time.Now().After(engine.deadline))
+	return engine.stopped
+}
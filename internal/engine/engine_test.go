@@ -0,0 +1,162 @@
+package engine
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Ekotlikoff/gochess/internal/eval"
+	"github.com/Ekotlikoff/gochess/internal/model"
+)
+
+func TestMateInOne(t *testing.T) {
+	game, _ := model.NewGameFromFEN("6k1/5ppp/8/8/8/8/8/R5K1 w - - 0 1")
+	result, err := NewEngine(3, 0).Search(game)
+	if err != nil || result.Move.UCI() != "a1a8" || !IsMateScore(result.Score) ||
+		result.Score < 0 {
+		t.Error("Expected mate with a1a8 got ", result.Move.UCI(), result.Score)
+	}
+}
+
+func TestMateInTwoLine(t *testing.T) {
+	game, _ := model.NewGameFromFEN("7k/8/8/8/8/8/R7/1R5K w - - 0 1")
+	result, _ := NewEngine(5, 0).Search(game)
+	if MateIn(result.Score) != 2 || len(result.Line) != 3 ||
+		result.Line[0] != result.Move {
+		t.Fatal("Expected a mate in two line got ", MateIn(result.Score),
+			result.Line)
+	}
+	for _, move := range result.Line {
+		if err := game.Move(move); err != nil {
+			t.Fatal("Expected a legal line got ", err)
+		}
+	}
+	if !game.IsCheckmate() {
+		t.Error("Expected the line to end in checkmate got ", game.FEN())
+	}
+}
+
+func TestMateIn(t *testing.T) {
+	for score, expected := range map[int]int{
+		mateScore - 1: 1, mateScore - 3: 2, -(mateScore - 2): -1,
+		-(mateScore - 4): -2, 250: 0, -250: 0,
+	} {
+		if MateIn(score) != expected {
+			t.Error("Expected ", expected, " got ", MateIn(score))
+		}
+	}
+}
+
+func TestWinsHangingQueen(t *testing.T) {
+	game, _ := model.NewGameFromFEN("4k3/8/8/3q4/8/8/3R4/4K3 w - - 0 1")
+	result, _ := NewEngine(3, 0).Search(game)
+	if result.Move.UCI() != "d2d5" ||
+		result.Score < eval.PieceValue(model.Rook) {
+		t.Error("Expected d2d5 to win the queen got ", result.Move.UCI(),
+			result.Score)
+	}
+}
+
+func TestAvoidsLosingQueen(t *testing.T) {
+	// Taking the defended pawn loses the queen to the knight.
+	game, _ := model.NewGameFromFEN("4k3/8/5n2/3p4/8/8/8/3QK3 w - - 0 1")
+	result, _ := NewEngine(3, 0).Search(game)
+	if result.Move.UCI() == "d1d5" {
+		t.Error("Expected the queen not to take the defended pawn")
+	}
+}
+
+func TestSearchLeavesGameUnchanged(t *testing.T) {
+	game := model.NewGame()
+	fen := game.FEN()
+	result, err := NewEngine(3, 0).Search(game)
+	if err != nil || game.FEN() != fen || len(game.MoveHistory()) != 0 {
+		t.Error("Expected the game to be unchanged got ", game.FEN())
+	}
+	if result.Depth != 3 || result.Nodes == 0 {
+		t.Error("Expected a depth 3 search got ", result)
+	}
+	if err := game.Move(result.Move); err != nil {
+		t.Error("Expected a legal move got ", result.Move, err)
+	}
+}
+
+func TestNoLegalMoves(t *testing.T) {
+	game, _ := model.NewGameFromFEN("7k/6Q1/6K1/8/8/8/8/8 b - - 0 1")
+	if _, err := NewEngine(2, 0).Search(game); err == nil {
+		t.Error("Expected an error searching a checkmated position")
+	}
+}
+
+func TestMoveTime(t *testing.T) {
+	engine := NewEngine(0, 200*time.Millisecond)
+	start := time.Now()
+	result, err := engine.Search(model.NewGame())
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Error("Expected the search to stop after its move time got ", elapsed)
+	}
+	if err != nil || result.Depth < 1 {
+		t.Error("Expected at least one completed iteration got ", result)
+	}
+}
+
+func TestTranspositionTableKeepsMateDistance(t *testing.T) {
+	engine := NewEngine(3, 0)
+	game, _ := model.NewGameFromFEN("6k1/5ppp/8/8/8/8/8/R5K1 w - - 0 1")
+	first, _ := engine.Search(game)
+	second, _ := engine.Search(game)
+	if first.Score != second.Score || first.Move.UCI() != second.Move.UCI() {
+		t.Error("Expected the same result from the table got ", first, second)
+	}
+}
+
+func TestSearchVariants(t *testing.T) {
+	for _, variant := range model.Variants {
+		game := model.NewGameVariant(variant)
+		result, err := NewEngine(2, 0).Search(game)
+		if err != nil {
+			t.Error(variant.Name(), err)
+			continue
+		}
+		if err := game.Move(result.Move); err != nil {
+			t.Error("Expected a legal ", variant.Name(), " move got ",
+				result.Move, err)
+		}
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	game := model.NewGame()
+	if score := evaluate(game); score != 0 {
+		t.Error("Expected an even start position got ", score)
+	}
+	game.MoveUCI("e2e4")
+	if score := evaluate(game); score >= 0 {
+		t.Error("Expected black to be worse after e4 got ", score)
+	}
+	game, _ = model.NewGameFromFEN("4k3/8/8/8/8/8/8/3QK3 b - - 0 1")
+	if score := evaluate(game); score > -eval.PieceValue(model.Queen)+100 {
+		t.Error("Expected black to be a queen down got ", score)
+	}
+}
+
+func TestSearchContext(t *testing.T) {
+	engine := NewEngine(0, time.Minute)
+	start := time.Now()
+	result, err := engine.SearchContext(context.Background(), model.NewGame(),
+		100*time.Millisecond)
+	if elapsed := time.Since(start); err != nil || elapsed > time.Second {
+		t.Error("Expected the search to stop after the shorter move time got ",
+			elapsed, err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(),
+		10*time.Millisecond)
+	defer cancel()
+	start = time.Now()
+	NewEngine(maxDepth, 0).SearchContext(ctx, model.NewGame(), 0)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Error("Expected the search to stop with its context got ", elapsed)
+	} else if result.Depth < 1 {
+		t.Error("Expected at least one completed iteration got ", result)
+	}
+}
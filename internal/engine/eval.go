@@ -0,0 +1,12 @@
+package engine
+
+import (
+	"github.com/Ekotlikoff/gochess/internal/eval"
+	"github.com/Ekotlikoff/gochess/internal/model"
+)
+
+// evaluate score the position in centipawns from the side to move's point
+// of view, with the static evaluation UIs display
+func evaluate(game *model.Game) int {
+	return eval.Evaluate(game).For(game.Turn())
+}
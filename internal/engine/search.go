@@ -0,0 +1,237 @@
+package engine
+
+import (
+	"sort"
+
+	"github.com/Ekotlikoff/gochess/internal/eval"
+	"github.com/Ekotlikoff/gochess/internal/model"
+)
+
+// searchRoot search each of the root's moves to the depth, the previous
+// iteration's best move first, and get the best move and its score
+func (engine *Engine) searchRoot(
+	position *model.Game, moves []model.MoveRequest, depth int,
+) (int, model.MoveRequest) {
+	hash := position.Hash()
+	moves = orderMoves(position, moves, engine.hashMove(hash))
+	alpha, bestMove := -infinity, moves[0]
+	for _, move := range moves {
+		// A rejected move was not made, so there is nothing to take back.
+		if position.Move(move) != nil {
+			continue
+		}
+		score := -engine.alphaBeta(position, depth-1, -infinity, -alpha, 1)
+		position.Undo()
+		if engine.stopped {
+			break
+		}
+		if score > alpha {
+			alpha, bestMove = score, move
+		}
+	}
+	if !engine.stopped {
+		engine.table.store(tableEntry{hash: hash, depth: depth,
+			score: scoreToTable(alpha, 0), bound: exactBound, move: bestMove,
+			hasMove: true})
+	}
+	return alpha, bestMove
+}
+
+// alphaBeta get the score of the position for the side to move, searched to
+// the depth within the window from alpha to beta, at the ply from the root
+func (engine *Engine) alphaBeta(
+	position *model.Game, depth, alpha, beta, ply int,
+) int {
+	engine.nodes++
+	if engine.timeUp() {
+		return 0
+	} else if position.GameOver() {
+		return terminalScore(position, ply)
+	} else if position.CanClaimDraw() {
+		return 0
+	} else if depth <= 0 {
+		return engine.quiescence(position, alpha, beta, ply)
+	}
+	hash := position.Hash()
+	if entry, ok := engine.table.probe(hash); ok && entry.depth >= depth {
+		score := scoreFromTable(entry.score, ply)
+		if entry.bound == exactBound ||
+			(entry.bound == lowerBound && score >= beta) ||
+			(entry.bound == upperBound && score <= alpha) {
+			return score
+		}
+	}
+	originalAlpha := alpha
+	best, bestMove := -infinity, model.MoveRequest{}
+	moves := position.LegalMoves()
+	for _, move := range orderMoves(position, moves, engine.hashMove(hash)) {
+		if position.Move(move) != nil {
+			continue
+		}
+		score := -engine.alphaBeta(position, depth-1, -beta, -alpha, ply+1)
+		position.Undo()
+		if engine.stopped {
+			return 0
+		}
+		if score > best {
+			best, bestMove = score, move
+		}
+		if score > alpha {
+			alpha = score
+		}
+		if alpha >= beta {
+			break
+		}
+	}
+	entryBound := exactBound
+	if best <= originalAlpha {
+		entryBound = upperBound
+	} else if best >= beta {
+		entryBound = lowerBound
+	}
+	engine.table.store(tableEntry{hash: hash, depth: depth,
+		score: scoreToTable(best, ply), bound: entryBound, move: bestMove,
+		hasMove: true})
+	return best
+}
+
+// quiescence search only captures and promotions until the position is
+// quiet, so that a search never stops in the middle of an exchange. The
+// side to move may stand pat on the static evaluation.
+func (engine *Engine) quiescence(
+	position *model.Game, alpha, beta, ply int,
+) int {
+	engine.nodes++
+	if engine.timeUp() {
+		return 0
+	} else if position.GameOver() {
+		return terminalScore(position, ply)
+	}
+	standPat := evaluate(position)
+	if standPat >= beta {
+		return standPat
+	} else if standPat > alpha {
+		alpha = standPat
+	}
+	board := position.GetBoard()
+	tactical := []model.MoveRequest{}
+	for _, move := range position.LegalMoves() {
+		if isCapture(board, move) || isPromotion(move) {
+			tactical = append(tactical, move)
+		}
+	}
+	for _, move := range orderMoves(position, tactical, nil) {
+		if position.Move(move) != nil {
+			continue
+		}
+		score := -engine.quiescence(position, -beta, -alpha, ply+1)
+		position.Undo()
+		if engine.stopped {
+			return 0
+		} else if score >= beta {
+			return score
+		} else if score > alpha {
+			alpha = score
+		}
+	}
+	return alpha
+}
+
+// terminalScore get the score of a finished game for the side to move,
+// preferring the shortest win and the longest loss
+func terminalScore(position *model.Game, ply int) int {
+	result := position.Result()
+	if result.Draw {
+		return 0
+	} else if result.Winner == position.Turn() {
+		return mateScore - ply
+	}
+	return -(mateScore - ply)
+}
+
+// hashMove get the stored best move for the position with the hash, if any
+func (engine *Engine) hashMove(hash uint64) *model.MoveRequest {
+	if entry, ok := engine.table.probe(hash); ok && entry.hasMove {
+		return &entry.move
+	}
+	return nil
+}
+
+// orderMoves sort the moves so that the most promising are searched first,
+// which makes cutoffs more likely: the hash move if there is one, then
+// captures of the most valuable piece by the least valuable, then promotions
+func orderMoves(
+	position *model.Game, moves []model.MoveRequest,
+	hashMove *model.MoveRequest,
+) []model.MoveRequest {
+	board := position.GetBoard()
+	scores := make([]int, len(moves))
+	for i, move := range moves {
+		switch {
+		case hashMove != nil && sameMove(move, *hashMove):
+			scores[i] = infinity
+		case isCapture(board, move):
+			mover := board.Piece(move.Position).PieceType()
+			scores[i] = 10*capturedValue(board, move) -
+				eval.PieceValue(mover)/10
+		case isPromotion(move):
+			scores[i] = eval.PieceValue(*move.PromoteTo)
+		}
+	}
+	indices := make([]int, len(moves))
+	for i := range indices {
+		indices[i] = i
+	}
+	sort.SliceStable(indices, func(a, b int) bool {
+		return scores[indices[a]] > scores[indices[b]]
+	})
+	ordered := make([]model.MoveRequest, len(moves))
+	for i, index := range indices {
+		ordered[i] = moves[index]
+	}
+	return ordered
+}
+
+// target get the square the move request moves its piece to
+func target(move model.MoveRequest) model.Position {
+	return model.Position{
+		File: uint8(int8(move.Position.File) + move.Move.X),
+		Rank: uint8(int8(move.Position.Rank) + move.Move.Y),
+	}
+}
+
+// isCapture get whether the move captures, en passant included. A Chess960
+// king castling onto its own rook is not a capture.
+func isCapture(board *model.Board, move model.MoveRequest) bool {
+	if move.IsDrop() {
+		return false
+	}
+	piece := board.Piece(move.Position)
+	captured := board.Piece(target(move))
+	if captured != nil {
+		return captured.Color() != piece.Color()
+	}
+	return piece.PieceType() == model.Pawn && move.Move.X != 0
+}
+
+// capturedValue get the value of the piece the capture takes
+func capturedValue(board *model.Board, move model.MoveRequest) int {
+	if captured := board.Piece(target(move)); captured != nil {
+		return eval.PieceValue(captured.PieceType())
+	}
+	// An en passant capture takes a pawn.
+	return eval.PieceValue(model.Pawn)
+}
+
+func isPromotion(move model.MoveRequest) bool {
+	return move.PromoteTo != nil && !move.IsDrop()
+}
+
+func sameMove(a, b model.MoveRequest) bool {
+	if a.Position != b.Position || a.Move != b.Move {
+		return false
+	} else if a.PromoteTo == nil || b.PromoteTo == nil {
+		return a.PromoteTo == b.PromoteTo
+	}
+	return *a.PromoteTo == *b.PromoteTo
+}
@@ -0,0 +1,76 @@
+package engine
+
+import (
+	"github.com/Ekotlikoff/gochess/internal/model"
+)
+
+const (
+	// exactBound is a score searched within its window
+	exactBound = bound(iota)
+	// lowerBound is a score that failed high, the position is worth at least
+	// as much
+	lowerBound
+	// upperBound is a score that failed low, the position is worth at most
+	// as much
+	upperBound
+)
+
+type (
+	// bound is how a stored score relates to the position's true score
+	bound uint8
+
+	// tableEntry is a searched position's score and best move
+	tableEntry struct {
+		hash    uint64
+		depth   int
+		score   int
+		bound   bound
+		move    model.MoveRequest
+		hasMove bool
+	}
+
+	// transpositionTable stores searched positions by Zobrist hash so that a
+	// position reached by another move order is not searched again, and so
+	// that the previous iteration's best move is searched first
+	transpositionTable []tableEntry
+)
+
+func newTranspositionTable(size int) transpositionTable {
+	return make(transpositionTable, size)
+}
+
+// probe get the entry for the position with the hash if it is stored
+func (table transpositionTable) probe(hash uint64) (tableEntry, bool) {
+	entry := table[hash%uint64(len(table))]
+	return entry, entry.hash == hash && entry.depth > 0
+}
+
+// store the entry, replacing another position's entry or a shallower search
+// of the same position
+func (table transpositionTable) store(entry tableEntry) {
+	slot := &table[entry.hash%uint64(len(table))]
+	if slot.hash != entry.hash || entry.depth >= slot.depth {
+		*slot = entry
+	}
+}
+
+// scoreToTable store a mate score as the distance to mate from the position
+// rather than from the root, so that it holds wherever the position is found
+func scoreToTable(score, ply int) int {
+	if score > mateThreshold {
+		return score + ply
+	} else if score < -mateThreshold {
+		return score - ply
+	}
+	return score
+}
+
+// scoreFromTable undo scoreToTable for the position found at the ply
+func scoreFromTable(score, ply int) int {
+	if score > mateThreshold {
+		return score - ply
+	} else if score < -mateThreshold {
+		return score + ply
+	}
+	return score
+}
@@ -0,0 +1,348 @@
+// Package eval is the static evaluation of chess positions, broken down by
+// term, which the built-in engine searches with and UIs display
+package eval
+
+import (
+	"github.com/Ekotlikoff/gochess/internal/model"
+)
+
+// pieceValues are the pieces' material values in centipawns, indexed by
+// piece type
+var pieceValues = [6]int{
+	model.Rook: 500, model.Knight: 320, model.Bishop: 330, model.Queen: 900,
+	model.King: 0, model.Pawn: 100,
+}
+
+const (
+	// endgameMaterial is the most material, besides kings and pawns, that the
+	// board may hold for the position to be an endgame, where the kings are
+	// scored with their endgame table and their safety no longer counts
+	endgameMaterial = 2600
+	// mobilityBonus is the score for each square a piece attacks
+	mobilityBonus = 4
+	// shieldBonus and farShieldBonus are the scores for each pawn one or two
+	// ranks in front of its king, on the king's file or the files beside it
+	shieldBonus    = 10
+	farShieldBonus = 5
+	// kingZonePenalty is the penalty for each attack on the king's square
+	// and the squares around it
+	kingZonePenalty = 8
+	// doubledPenalty is the penalty for each pawn behind another on its file
+	doubledPenalty = 15
+	// isolatedPenalty is the penalty for each pawn with no pawns of its color
+	// on the files beside it
+	isolatedPenalty = 15
+)
+
+// passedBonus is the score for a passed pawn, indexed by its rank counted
+// from its color's side
+var passedBonus = [8]int{0, 10, 15, 25, 40, 60, 90, 0}
+
+// Piece-square tables score each piece's square from white's side, with the
+// eighth rank on the first row. Black's squares are mirrored.
+var (
+	pawnTable = [64]int{
+		0, 0, 0, 0, 0, 0, 0, 0,
+		50, 50, 50, 50, 50, 50, 50, 50,
+		10, 10, 20, 30, 30, 20, 10, 10,
+		5, 5, 10, 25, 25, 10, 5, 5,
+		0, 0, 0, 20, 20, 0, 0, 0,
+		5, -5, -10, 0, 0, -10, -5, 5,
+		5, 10, 10, -20, -20, 10, 10, 5,
+		0, 0, 0, 0, 0, 0, 0, 0,
+	}
+	knightTable = [64]int{
+		-50, -40, -30, -30, -30, -30, -40, -50,
+		-40, -20, 0, 0, 0, 0, -20, -40,
+		-30, 0, 10, 15, 15, 10, 0, -30,
+		-30, 5, 15, 20, 20, 15, 5, -30,
+		-30, 0, 15, 20, 20, 15, 0, -30,
+		-30, 5, 10, 15, 15, 10, 5, -30,
+		-40, -20, 0, 5, 5, 0, -20, -40,
+		-50, -40, -30, -30, -30, -30, -40, -50,
+	}
+	bishopTable = [64]int{
+		-20, -10, -10, -10, -10, -10, -10, -20,
+		-10, 0, 0, 0, 0, 0, 0, -10,
+		-10, 0, 5, 10, 10, 5, 0, -10,
+		-10, 5, 5, 10, 10, 5, 5, -10,
+		-10, 0, 10, 10, 10, 10, 0, -10,
+		-10, 10, 10, 10, 10, 10, 10, -10,
+		-10, 5, 0, 0, 0, 0, 5, -10,
+		-20, -10, -10, -10, -10, -10, -10, -20,
+	}
+	rookTable = [64]int{
+		0, 0, 0, 0, 0, 0, 0, 0,
+		5, 10, 10, 10, 10, 10, 10, 5,
+		-5, 0, 0, 0, 0, 0, 0, -5,
+		-5, 0, 0, 0, 0, 0, 0, -5,
+		-5, 0, 0, 0, 0, 0, 0, -5,
+		-5, 0, 0, 0, 0, 0, 0, -5,
+		-5, 0, 0, 0, 0, 0, 0, -5,
+		0, 0, 0, 5, 5, 0, 0, 0,
+	}
+	queenTable = [64]int{
+		-20, -10, -10, -5, -5, -10, -10, -20,
+		-10, 0, 0, 0, 0, 0, 0, -10,
+		-10, 0, 5, 5, 5, 5, 0, -10,
+		-5, 0, 5, 5, 5, 5, 0, -5,
+		0, 0, 5, 5, 5, 5, 0, -5,
+		-10, 5, 5, 5, 5, 5, 0, -10,
+		-10, 0, 5, 0, 0, 0, 0, -10,
+		-20, -10, -10, -5, -5, -10, -10, -20,
+	}
+	kingTable = [64]int{
+		-30, -40, -40, -50, -50, -40, -40, -30,
+		-30, -40, -40, -50, -50, -40, -40, -30,
+		-30, -40, -40, -50, -50, -40, -40, -30,
+		-30, -40, -40, -50, -50, -40, -40, -30,
+		-20, -30, -30, -40, -40, -30, -30, -20,
+		-10, -20, -20, -20, -20, -20, -20, -10,
+		20, 20, 0, 0, 0, 0, 20, 20,
+		20, 30, 10, 0, 0, 10, 30, 20,
+	}
+	kingEndgameTable = [64]int{
+		-50, -40, -30, -20, -20, -30, -40, -50,
+		-30, -20, -10, 0, 0, -10, -20, -30,
+		-30, -10, 20, 30, 30, 20, -10, -30,
+		-30, -10, 30, 40, 40, 30, -10, -30,
+		-30, -10, 30, 40, 40, 30, -10, -30,
+		-30, -10, 20, 30, 30, 20, -10, -30,
+		-30, -30, 0, 0, 0, 0, -30, -30,
+		-50, -30, -30, -30, -30, -30, -30, -50,
+	}
+
+	pieceTables = [6]*[64]int{
+		model.Rook: &rookTable, model.Knight: &knightTable,
+		model.Bishop: &bishopTable, model.Queen: &queenTable,
+		model.King: &kingTable, model.Pawn: &pawnTable,
+	}
+)
+
+type (
+	// Score is a term's score in centipawns for each color
+	Score struct {
+		White int
+		Black int
+	}
+
+	// PawnCounts is the number of a color's pawns of each kind the pawn
+	// structure term scores
+	PawnCounts struct {
+		Doubled  int
+		Isolated int
+		Passed   int
+	}
+
+	// Evaluation is a position's static evaluation, broken down by term.
+	// Total is the sum of the terms in centipawns from White's point of view.
+	Evaluation struct {
+		Material      Score
+		Mobility      Score
+		KingSafety    Score
+		PawnStructure Score
+		PieceSquare   Score
+		WhitePawns    PawnCounts
+		BlackPawns    PawnCounts
+		Total         int
+	}
+)
+
+// For get the color's score less its opponent's
+func (score Score) For(color model.Color) int {
+	if color == model.White {
+		return score.White - score.Black
+	}
+	return score.Black - score.White
+}
+
+// add the points to the color's score
+func (score *Score) add(color model.Color, points int) {
+	if color == model.White {
+		score.White += points
+	} else {
+		score.Black += points
+	}
+}
+
+// For get the evaluation from the color's point of view
+func (evaluation Evaluation) For(color model.Color) int {
+	if color == model.White {
+		return evaluation.Total
+	}
+	return -evaluation.Total
+}
+
+// PieceValue get the piece type's material value in centipawns
+func PieceValue(pieceType model.PieceType) int {
+	return pieceValues[pieceType]
+}
+
+// Evaluate get the position's static evaluation. Pocketed pieces count as
+// material.
+func Evaluate(game *model.Game) Evaluation {
+	board := game.GetBoard()
+	evaluation := Evaluation{}
+	material := 0
+	var kings [2]*model.Piece
+	for _, file := range board {
+		for _, piece := range file {
+			if piece == nil {
+				continue
+			}
+			pieceType := piece.PieceType()
+			if pieceType == model.King {
+				kings[piece.Color()] = piece
+				continue
+			} else if pieceType != model.Pawn {
+				material += pieceValues[pieceType]
+			}
+			evaluation.Material.add(piece.Color(), pieceValues[pieceType])
+			evaluation.PieceSquare.add(piece.Color(),
+				pieceTables[pieceType][tableIndex(piece)])
+		}
+	}
+	if game.Variant() == model.Crazyhouse || game.Variant() == model.Bughouse {
+		for _, color := range []model.Color{model.Black, model.White} {
+			for pieceType, count := range game.Pocket(color) {
+				evaluation.Material.add(color,
+					int(count)*pieceValues[pieceType])
+			}
+		}
+	}
+	endgame := material <= endgameMaterial
+	kingTable := pieceTables[model.King]
+	if endgame {
+		kingTable = &kingEndgameTable
+	}
+	activity := game.PieceActivity()
+	for _, color := range []model.Color{model.Black, model.White} {
+		evaluation.Mobility.add(color, mobilityBonus*activity[color].Mobility)
+		king := kings[color]
+		if king == nil {
+			continue
+		}
+		evaluation.PieceSquare.add(color, kingTable[tableIndex(king)])
+		if !endgame {
+			enemy := model.Color(1 - color)
+			evaluation.KingSafety.add(color, pawnShield(board, king)-
+				kingZonePenalty*activity[enemy].KingZoneAttacks)
+		}
+	}
+	var points int
+	evaluation.WhitePawns, points = pawnStructure(board, model.White)
+	evaluation.PawnStructure.White = points
+	evaluation.BlackPawns, points = pawnStructure(board, model.Black)
+	evaluation.PawnStructure.Black = points
+	for _, score := range []Score{evaluation.Material, evaluation.Mobility,
+		evaluation.KingSafety, evaluation.PawnStructure,
+		evaluation.PieceSquare} {
+		evaluation.Total += score.For(model.White)
+	}
+	return evaluation
+}
+
+// pawnShield score the pawns sheltering the king, one or two ranks in front
+// of it on its file and the files beside it
+func pawnShield(board *model.Board, king *model.Piece) int {
+	forward := 1
+	if king.Color() == model.Black {
+		forward = -1
+	}
+	shield := 0
+	for file := int(king.File()) - 1; file <= int(king.File())+1; file++ {
+		if file < 0 || file > 7 {
+			continue
+		}
+		if isPawn(board, file, int(king.Rank())+forward, king.Color()) {
+			shield += shieldBonus
+		} else if isPawn(board, file, int(king.Rank())+2*forward,
+			king.Color()) {
+			shield += farShieldBonus
+		}
+	}
+	return shield
+}
+
+// pawnStructure count the color's doubled, isolated and passed pawns and
+// score them
+func pawnStructure(
+	board *model.Board, color model.Color,
+) (PawnCounts, int) {
+	var files [8]int
+	pawns := []*model.Piece{}
+	for _, file := range board {
+		for _, piece := range file {
+			if piece != nil && piece.PieceType() == model.Pawn &&
+				piece.Color() == color {
+				files[piece.File()]++
+				pawns = append(pawns, piece)
+			}
+		}
+	}
+	counts := PawnCounts{}
+	for _, count := range files {
+		if count > 1 {
+			counts.Doubled += count - 1
+		}
+	}
+	score := -doubledPenalty * counts.Doubled
+	for _, pawn := range pawns {
+		file := int(pawn.File())
+		if (file == 0 || files[file-1] == 0) &&
+			(file == 7 || files[file+1] == 0) {
+			counts.Isolated++
+			score -= isolatedPenalty
+		}
+		if isPassed(board, pawn) {
+			counts.Passed++
+			rank := int(pawn.Rank())
+			if color == model.Black {
+				rank = 7 - rank
+			}
+			score += passedBonus[rank]
+		}
+	}
+	return counts, score
+}
+
+// isPassed get whether no enemy pawn is in front of the pawn on its file or
+// the files beside it
+func isPassed(board *model.Board, pawn *model.Piece) bool {
+	forward := 1
+	if pawn.Color() == model.Black {
+		forward = -1
+	}
+	enemy := model.Color(1 - pawn.Color())
+	for file := int(pawn.File()) - 1; file <= int(pawn.File())+1; file++ {
+		if file < 0 || file > 7 {
+			continue
+		}
+		rank := int(pawn.Rank()) + forward
+		for ; rank >= 0 && rank < 8; rank += forward {
+			if isPawn(board, file, rank, enemy) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// isPawn get whether a pawn of the color is on the square
+func isPawn(board *model.Board, file, rank int, color model.Color) bool {
+	if rank < 0 || rank > 7 {
+		return false
+	}
+	piece := board[file][rank]
+	return piece != nil && piece.PieceType() == model.Pawn &&
+		piece.Color() == color
+}
+
+// tableIndex get the index of the piece's square in a piece-square table
+func tableIndex(piece *model.Piece) int {
+	rank := int(piece.Rank())
+	if piece.Color() == model.White {
+		rank = 7 - rank
+	}
+	return rank*8 + int(piece.File())
+}
@@ -0,0 +1,101 @@
+package eval
+
+import (
+	"testing"
+
+	"github.com/Ekotlikoff/gochess/internal/model"
+)
+
+func TestEvaluateStart(t *testing.T) {
+	evaluation := Evaluate(model.NewGame())
+	expected := Evaluation{
+		Material: Score{4000, 4000}, Mobility: Score{16, 16},
+		KingSafety: Score{30, 30}, PawnStructure: Score{0, 0},
+		PieceSquare: Score{evaluation.PieceSquare.White,
+			evaluation.PieceSquare.White},
+	}
+	if evaluation != expected {
+		t.Error("Expected an even start position got ", evaluation)
+	}
+}
+
+func TestEvaluateTotal(t *testing.T) {
+	game := model.NewGame()
+	game.MoveUCI("e2e4")
+	evaluation := Evaluate(game)
+	if evaluation.Mobility.For(model.White) <= 0 || evaluation.Total <= 0 ||
+		evaluation.For(model.Black) != -evaluation.Total {
+		t.Error("Expected white to be better after e4 got ", evaluation)
+	}
+	total := evaluation.Material.For(model.White) +
+		evaluation.Mobility.For(model.White) +
+		evaluation.KingSafety.For(model.White) +
+		evaluation.PawnStructure.For(model.White) +
+		evaluation.PieceSquare.For(model.White)
+	if evaluation.Total != total {
+		t.Error("Expected the total to be the terms' sum got ",
+			evaluation.Total, total)
+	}
+}
+
+func TestEvaluatePawnStructure(t *testing.T) {
+	game, _ := model.NewGameFromFEN("4k3/p7/8/1P6/8/2P5/P1P5/4K3 w - - 0 1")
+	evaluation := Evaluate(game)
+	if evaluation.WhitePawns != (PawnCounts{1, 0, 2}) ||
+		evaluation.BlackPawns != (PawnCounts{0, 1, 0}) {
+		t.Error("Expected doubled, isolated and passed pawns got ",
+			evaluation.WhitePawns, evaluation.BlackPawns)
+	}
+	// The doubled c pawns are passed on the second and third ranks, and the
+	// a7 pawn stops the a and b pawns.
+	if evaluation.PawnStructure != (Score{
+		-doubledPenalty + passedBonus[1] + passedBonus[2], -isolatedPenalty,
+	}) {
+		t.Error("Expected the pawns to be scored got ",
+			evaluation.PawnStructure)
+	}
+}
+
+func TestEvaluateKingSafety(t *testing.T) {
+	game, _ := model.NewGameFromFEN(
+		"rnb1kbnr/pppp1ppp/8/4p3/6Pq/5P2/PPPPP2P/RNBQKBNR w KQkq - 1 3")
+	evaluation := Evaluate(game)
+	// The queen attacks the king and f2, with d2, e2 and f3 left to shield
+	// the king.
+	if evaluation.KingSafety.White != 2*shieldBonus+farShieldBonus-
+		2*kingZonePenalty {
+		t.Error("Expected the queen to endanger the king got ",
+			evaluation.KingSafety)
+	}
+	game, _ = model.NewGameFromFEN("4k3/pppppppp/8/8/8/8/PPPPPPPP/4K3 w - - 0 1")
+	if evaluation := Evaluate(game); evaluation.KingSafety != (Score{}) {
+		t.Error("Expected king safety not to count in the endgame got ",
+			evaluation.KingSafety)
+	}
+}
+
+func TestEvaluatePockets(t *testing.T) {
+	game := model.NewGameVariant(model.Crazyhouse)
+	for _, move := range []string{"e2e4", "d7d5", "e4d5", "d8d5"} {
+		if err := game.MoveUCI(move); err != nil {
+			t.Fatal(err)
+		}
+	}
+	evaluation := Evaluate(game)
+	if evaluation.Material != (Score{4000, 4000}) {
+		t.Error("Expected the captured pawns to count in the pockets got ",
+			evaluation.Material)
+	}
+	if PieceValue(model.Queen) != 900 {
+		t.Error("Expected a queen to be worth 900 got ",
+			PieceValue(model.Queen))
+	}
+}
+
+func TestEvaluateNoKing(t *testing.T) {
+	evaluation := Evaluate(model.NewGameVariant(model.Horde))
+	if evaluation.KingSafety.White != 0 || evaluation.Material.White == 0 {
+		t.Error("Expected the horde to be scored without a king got ",
+			evaluation)
+	}
+}
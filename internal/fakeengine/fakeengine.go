@@ -0,0 +1,252 @@
+// Package fakeengine is a stand-in for the RustChess engine service, which
+// plays a scripted list of moves and then random legal ones and analyzes with
+// the in-process engine, for testing the matchserver without the real engine
+package fakeengine
+
+import (
+	"context"
+	"io"
+	"math/rand"
+	"time"
+
+	pb "github.com/Ekotlikoff/gochess/api"
+	"github.com/Ekotlikoff/gochess/internal/engine"
+	"github.com/Ekotlikoff/gochess/internal/model"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// Resign is the script entry for resigning in place of a move
+	Resign = "resign"
+	// Draw is the script entry for offering a draw with the next move
+	Draw = "draw"
+)
+
+// Server is a fake RustChess engine. Each game plays the script's moves in
+// UCI notation in turn, resigning or offering a draw where the script says
+// to, then random legal moves.
+type Server struct {
+	pb.UnimplementedRustChessServer
+	script      []string
+	acceptDraws bool
+	seed        int64
+}
+
+// NewServer create a fake engine that plays the script in each game, accepts
+// draw offers if told to, and picks its random moves from the seed
+func NewServer(script []string, acceptDraws bool, seed int64) *Server {
+	return &Server{script: script, acceptDraws: acceptDraws, seed: seed}
+}
+
+// fakeGame is one of the fake engine's games
+type fakeGame struct {
+	stream   pb.RustChess_GameServer
+	game     *model.Game
+	color    model.Color
+	script   []string
+	rand     *rand.Rand
+	resigned bool
+	// offerDraw is set by a Draw entry, for the draw to be offered once the
+	// next move is played.
+	offerDraw bool
+}
+
+// Game play a game, which the first message must start
+func (server *Server) Game(stream pb.RustChess_GameServer) error {
+	in, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	start := in.GetGameStart()
+	if start == nil {
+		return status.Error(codes.InvalidArgument, "expected a game start")
+	}
+	game, err := newGame(start)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	fake := fakeGame{
+		stream: stream, game: game, color: model.Color(start.PlayerColor),
+		script: server.script, rand: rand.New(rand.NewSource(server.seed)),
+	}
+	for {
+		if fake.game.Turn() == fake.color && !fake.game.GameOver() &&
+			!fake.resigned {
+			if err := fake.play(); err != nil {
+				return err
+			}
+		}
+		in, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		switch request := in.Request.(type) {
+		case *pb.GameMessage_ChessMove:
+			err := fake.game.Move(pbToMove(request.ChessMove))
+			if err != nil {
+				return status.Error(codes.InvalidArgument,
+					"illegal move: "+err.Error())
+			}
+		case *pb.GameMessage_AsyncRequest:
+			// The opponent's draw offer, which is accepted by offering back.
+			if request.AsyncRequest.Type == pb.AsyncRequest_DRAW &&
+				server.acceptDraws && !fake.resigned {
+				if err := fake.sendAsync(pb.AsyncRequest_DRAW); err != nil {
+					return err
+				}
+			}
+		case *pb.GameMessage_GameOver:
+			return nil
+		}
+	}
+}
+
+// Analyze evaluate the position with the in-process engine
+func (server *Server) Analyze(
+	ctx context.Context, request *pb.AnalysisRequest,
+) (*pb.Analysis, error) {
+	game, err := newGame(&pb.GameStart{
+		StartFen: request.Fen, Chess960: request.Chess960,
+		Variant: request.Variant,
+	})
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	for _, move := range request.Moves {
+		if err := game.MoveUCI(move); err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+	}
+	result, err := engine.NewEngine(int(request.SearchDepth),
+		time.Duration(request.MoveTimeMs)*time.Millisecond).Search(game)
+	if err != nil {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+	sign := int32(1)
+	if game.Turn() == model.Black {
+		sign = -1
+	}
+	analysis := &pb.Analysis{Depth: uint32(result.Depth)}
+	if mate := engine.MateIn(result.Score); mate != 0 {
+		analysis.Mate = sign * int32(mate)
+	} else {
+		analysis.ScoreCp = sign * int32(result.Score)
+	}
+	for _, move := range result.Line {
+		analysis.BestLine = append(analysis.BestLine, move.UCI())
+	}
+	return analysis, nil
+}
+
+// newGame create the game the message starts
+func newGame(start *pb.GameStart) (*model.Game, error) {
+	variant := model.Standard
+	if start.Variant != "" {
+		var err error
+		if variant, err = model.VariantFromName(start.Variant); err != nil {
+			return nil, err
+		}
+	}
+	if start.StartFen == "" {
+		return model.NewGameVariant(variant), nil
+	} else if start.Chess960 {
+		return model.NewGameChess960FromFEN(start.StartFen)
+	}
+	return model.NewGameVariantFromFEN(variant, start.StartFen)
+}
+
+// play the engine's turn, from the script while it lasts
+func (fake *fakeGame) play() error {
+	for len(fake.script) > 0 {
+		entry := fake.script[0]
+		fake.script = fake.script[1:]
+		switch entry {
+		case Resign:
+			fake.resigned = true
+			return fake.sendAsync(pb.AsyncRequest_RESIGN)
+		case Draw:
+			fake.offerDraw = true
+		default:
+			move, err := fake.game.ParseUCI(entry)
+			if err != nil {
+				return status.Error(codes.FailedPrecondition,
+					"illegal scripted move: "+err.Error())
+			}
+			return fake.move(move)
+		}
+	}
+	moves := fake.game.LegalMoves()
+	if len(moves) == 0 {
+		fake.resigned = true
+		return fake.sendAsync(pb.AsyncRequest_RESIGN)
+	}
+	return fake.move(moves[fake.rand.Intn(len(moves))])
+}
+
+// move play the move, offering a draw with it if the script said to
+func (fake *fakeGame) move(move model.MoveRequest) error {
+	if err := fake.game.Move(move); err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+	err := fake.stream.Send(&pb.GameMessage{
+		Request: &pb.GameMessage_ChessMove{ChessMove: moveToPB(move)},
+	})
+	if err != nil || !fake.offerDraw {
+		return err
+	}
+	fake.offerDraw = false
+	return fake.sendAsync(pb.AsyncRequest_DRAW)
+}
+
+func (fake *fakeGame) sendAsync(requestType pb.AsyncRequest_Type) error {
+	return fake.stream.Send(&pb.GameMessage{
+		Request: &pb.GameMessage_AsyncRequest{
+			AsyncRequest: &pb.AsyncRequest{Type: requestType},
+		},
+	})
+}
+
+func moveToPB(move model.MoveRequest) *pb.ChessMove {
+	chessMove := &pb.ChessMove{
+		OriginalPosition: &pb.Position{
+			File: uint32(move.Position.File), Rank: uint32(move.Position.Rank),
+		},
+		NewPosition: &pb.Position{
+			File: uint32(int8(move.Position.File) + move.Move.X),
+			Rank: uint32(int8(move.Position.Rank) + move.Move.Y),
+		},
+		Drop: move.IsDrop(),
+	}
+	if move.PromoteTo != nil {
+		chessMove.PromoteTo = pb.ChessMove_PieceType(*move.PromoteTo + 1)
+	}
+	return chessMove
+}
+
+func pbToMove(msg *pb.ChessMove) model.MoveRequest {
+	var promoteTo *model.PieceType
+	if msg.PromoteTo != pb.ChessMove_NO_PIECE {
+		pieceType := model.PieceType(msg.PromoteTo - 1)
+		promoteTo = &pieceType
+	}
+	if msg.Drop && promoteTo != nil {
+		return model.NewDropRequest(*promoteTo, model.Position{
+			File: uint8(msg.NewPosition.File),
+			Rank: uint8(msg.NewPosition.Rank),
+		})
+	}
+	return model.MoveRequest{
+		Position: model.Position{
+			File: uint8(msg.OriginalPosition.File),
+			Rank: uint8(msg.OriginalPosition.Rank),
+		},
+		Move: model.Move{
+			X: int8(msg.NewPosition.File - msg.OriginalPosition.File),
+			Y: int8(msg.NewPosition.Rank - msg.OriginalPosition.Rank),
+		},
+		PromoteTo: promoteTo,
+	}
+}
@@ -0,0 +1,498 @@
+package model
+
+import (
+	"math/bits"
+	"sync"
+)
+
+type (
+	// bitboard is a set of squares, with bit rank*8+file set for each square
+	bitboard uint64
+
+	// bitboards is the board's pieces as one bitboard per color and piece
+	// type, which backs move generation and check detection
+	bitboards struct {
+		pieces   [2][6]bitboard
+		occupied [2]bitboard
+		all      bitboard
+	}
+
+	// magic finds a slider's attacks for any occupancy with one multiply
+	magic struct {
+		mask    bitboard
+		number  uint64
+		shift   uint8
+		attacks []bitboard
+	}
+)
+
+var (
+	rookDirections   = [4][2]int{{0, 1}, {0, -1}, {1, 0}, {-1, 0}}
+	bishopDirections = [4][2]int{{1, 1}, {1, -1}, {-1, 1}, {-1, -1}}
+	knightOffsets    = [8][2]int{
+		{1, 2}, {1, -2}, {-1, 2}, {-1, -2}, {2, 1}, {2, -1}, {-2, 1}, {-2, -1},
+	}
+
+	knightAttacks [64]bitboard
+	kingAttacks   [64]bitboard
+	pawnAttacks   [2][64]bitboard
+	rookMagics    [64]magic
+	bishopMagics  [64]magic
+	attackTables  sync.Once
+)
+
+// Magic numbers found by a seeded random search, one per square. Each maps
+// every blocker subset of the square's mask to a distinct table index.
+var (
+	rookMagicNumbers = [64]uint64{
+		0x1080004008801020, 0x0840092002C03000, 0x1900200010400900,
+		0x0880100008000480, 0x4200100420080200, 0x8100020100080400,
+		0x0200040110886200, 0x0200008040220411, 0x0404800084400220,
+		0x0000401000402000, 0x0086001081220440, 0x0408800800100280,
+		0x000A001201040820, 0x8848800200840080, 0x4001000100040200,
+		0x0442000102105084, 0x9080010020804100, 0x0040404000201009,
+		0x0000808010002009, 0x2200090021D00100, 0x0008008008040080,
+		0x0004004002010040, 0x0011040008015042, 0x00000A0001768104,
+		0x0000800080204009, 0x2010004140002001, 0x9800200280100080,
+		0x1000100080080080, 0x0050500500080100, 0x0000020080040080,
+		0x0C10010400420810, 0x1040008200005104, 0x01808240088004A0,
+		0x0882804004802000, 0x0880402001001100, 0x2000210409001000,
+		0x2000480131001500, 0x0000800400800200, 0x000002380C001003,
+		0x4600084882000431, 0x0080002000504000, 0x0300500020004002,
+		0x0040408200220011, 0x0010040008004040, 0x0000080004008080,
+		0x0010040002008080, 0x2012004881020004, 0x8300842444820011,
+		0x0088403882010200, 0x0820400080210100, 0x0110910040A00300,
+		0x0801100280080480, 0x0242009008200600, 0x1002000489500200,
+		0x0040800200010080, 0x0091800041000080, 0x0000209300488001,
+		0x04C1002414824001, 0x020020000B001041, 0x7000100004200901,
+		0x8002002004100802, 0x30010002084C0007, 0x0888221800813004,
+		0x4000002840840112,
+	}
+	bishopMagicNumbers = [64]uint64{
+		0x20C0090901061081, 0x0024040094030104, 0x8210810200290200,
+		0x0011040484620000, 0x0081104002221000, 0x0009012011001350,
+		0x0081010802400380, 0x0000420210010408, 0x0008105002280050,
+		0x0001028484040044, 0x2A00880810408804, 0x7020022282000100,
+		0x0084040420100A50, 0x000401010840E000, 0x2020020210420888,
+		0x0008084202012010, 0x2010400810018800, 0x0445122008020840,
+		0x0804100808002008, 0x0008002104110100, 0x0061005820080800,
+		0x2001000200820100, 0x480C210084010800, 0x3004442500480420,
+		0x1010102240048100, 0x00182009084220A3, 0x8803090A10004205,
+		0x0208080040202020, 0x000C044084010040, 0x00A1010002004106,
+		0x6008210020640202, 0x1600902112860801, 0x00042008C1220200,
+		0x010C042002440140, 0x5022080200040820, 0x0402004042940100,
+		0x0860108400008020, 0x000C080022021000, 0x0264080652822100,
+		0x4005031221010401, 0x0004502410008400, 0x000500B010A20400,
+		0x0415094050080800, 0x080000201800A104, 0x4022A80304000110,
+		0x4012140802028020, 0x40200104010100A0, 0x12810806008B0C41,
+		0x0020441008080000, 0x2002120084045420, 0x0704020062080002,
+		0x0000001084040001, 0x0322200891240200, 0xF040200210024800,
+		0x0140824832008042, 0x000210020A004602, 0x0083042805141020,
+		0x002C12009A011000, 0x0041A00044140400, 0x00004004020A0202,
+		0x0000140010020210, 0x2864160811012200, 0x2060080841082A17,
+		0xA010041108003100,
+	}
+)
+
+func square(pos Position) int {
+	return int(pos.Rank)*8 + int(pos.File)
+}
+
+func squarePosition(sq int) Position {
+	return Position{uint8(sq % 8), uint8(sq / 8)}
+}
+
+// popSquare remove and return the lowest square in the bitboard
+func (b *bitboard) popSquare() int {
+	sq := bits.TrailingZeros64(uint64(*b))
+	*b &= *b - 1
+	return sq
+}
+
+// initAttackTables precompute the leaper attacks and the sliders' magic
+// attack tables
+func initAttackTables() {
+	attackTables.Do(func() {
+		for sq := 0; sq < 64; sq++ {
+			file, rank := sq%8, sq/8
+			for _, offset := range knightOffsets {
+				knightAttacks[sq] |= squareAt(file+offset[0], rank+offset[1])
+			}
+			for _, direction := range append(rookDirections[:],
+				bishopDirections[:]...) {
+				kingAttacks[sq] |=
+					squareAt(file+direction[0], rank+direction[1])
+			}
+			pawnAttacks[White][sq] =
+				squareAt(file-1, rank+1) | squareAt(file+1, rank+1)
+			pawnAttacks[Black][sq] =
+				squareAt(file-1, rank-1) | squareAt(file+1, rank-1)
+		}
+		// TestMagicNumbers checks that no number causes a collision.
+		for sq := 0; sq < 64; sq++ {
+			rookMagics[sq], _ =
+				newMagic(sq, rookDirections, rookMagicNumbers[sq])
+			bishopMagics[sq], _ =
+				newMagic(sq, bishopDirections, bishopMagicNumbers[sq])
+		}
+	})
+}
+
+func squareAt(file, rank int) bitboard {
+	if file < 0 || file > 7 || rank < 0 || rank > 7 {
+		return 0
+	}
+	return 1 << uint(rank*8+file)
+}
+
+// slidingAttacks walk each direction from the square until blocked
+func slidingAttacks(sq int, occupied bitboard, directions [4][2]int) bitboard {
+	attacks := bitboard(0)
+	for _, direction := range directions {
+		file, rank := sq%8+direction[0], sq/8+direction[1]
+		for ; squareAt(file, rank) != 0; file, rank =
+			file+direction[0], rank+direction[1] {
+			attacks |= squareAt(file, rank)
+			if occupied&squareAt(file, rank) != 0 {
+				break
+			}
+		}
+	}
+	return attacks
+}
+
+// relevantOccupancy get the squares whose occupancy can block a slider,
+// leaving out the edge squares at the end of each ray
+func relevantOccupancy(sq int, directions [4][2]int) bitboard {
+	mask := bitboard(0)
+	for _, direction := range directions {
+		file, rank := sq%8+direction[0], sq/8+direction[1]
+		for squareAt(file+direction[0], rank+direction[1]) != 0 {
+			mask |= squareAt(file, rank)
+			file, rank = file+direction[0], rank+direction[1]
+		}
+	}
+	return mask
+}
+
+// newMagic build the square's attack table, returning false if two blocker
+// subsets with different attacks collide on the same index
+func newMagic(sq int, directions [4][2]int, number uint64) (magic, bool) {
+	mask := relevantOccupancy(sq, directions)
+	shift := uint8(64 - bits.OnesCount64(uint64(mask)))
+	m := magic{mask, number, shift, make([]bitboard, 1<<(64-shift))}
+	used := make([]bool, len(m.attacks))
+	ok := true
+	// Enumerate every subset of the mask with the Carry-Rippler trick.
+	for subset := bitboard(0); ; subset = (subset - mask) & mask {
+		index := (uint64(subset) * number) >> shift
+		attacks := slidingAttacks(sq, subset, directions)
+		if used[index] && m.attacks[index] != attacks {
+			ok = false
+		}
+		used[index] = true
+		m.attacks[index] = attacks
+		if subset == mask {
+			return m, ok
+		}
+	}
+}
+
+func (m *magic) attacksFor(occupied bitboard) bitboard {
+	return m.attacks[(uint64(occupied&m.mask)*m.number)>>m.shift]
+}
+
+func newBitboards(board *Board) bitboards {
+	initAttackTables()
+	var b bitboards
+	for file := range board {
+		for rank, piece := range board[file] {
+			if piece != nil {
+				sq := bitboard(1) << uint(rank*8+file)
+				b.pieces[piece.color][piece.pieceType] |= sq
+				b.occupied[piece.color] |= sq
+			}
+		}
+	}
+	b.all = b.occupied[Black] | b.occupied[White]
+	return b
+}
+
+// attacks get the squares attacked by a piece on the square
+func (b *bitboards) attacks(pieceType PieceType, color Color, sq int) bitboard {
+	switch pieceType {
+	case Rook:
+		return rookMagics[sq].attacksFor(b.all)
+	case Bishop:
+		return bishopMagics[sq].attacksFor(b.all)
+	case Queen:
+		return rookMagics[sq].attacksFor(b.all) |
+			bishopMagics[sq].attacksFor(b.all)
+	case Knight:
+		return knightAttacks[sq]
+	case King:
+		return kingAttacks[sq]
+	default:
+		return pawnAttacks[color][sq]
+	}
+}
+
+// isAttacked get whether any piece of the color attacks the square
+func (b *bitboards) isAttacked(sq int, by Color) bool {
+	pieces := &b.pieces[by]
+	return pawnAttacks[getOppositeColor(by)][sq]&pieces[Pawn] != 0 ||
+		knightAttacks[sq]&pieces[Knight] != 0 ||
+		kingAttacks[sq]&pieces[King] != 0 ||
+		bishopMagics[sq].attacksFor(b.all)&(pieces[Bishop]|pieces[Queen]) != 0 ||
+		rookMagics[sq].attacksFor(b.all)&(pieces[Rook]|pieces[Queen]) != 0
+}
+
+// move the piece on the bitboards, removing whatever is on the captured
+// square, which differs from the destination only for en passant
+func (b *bitboards) move(pieceType PieceType, color Color, from, to,
+	captured int) {
+	fromTo := bitboard(1)<<uint(from) | bitboard(1)<<uint(to)
+	capturedSquare := bitboard(1) << uint(captured)
+	enemy := getOppositeColor(color)
+	for enemyType := range b.pieces[enemy] {
+		b.pieces[enemy][enemyType] &^= capturedSquare
+	}
+	b.occupied[enemy] &^= capturedSquare
+	b.pieces[color][pieceType] ^= fromTo
+	b.occupied[color] ^= fromTo
+	b.all = b.occupied[Black] | b.occupied[White]
+}
+
+// leavesKingSafe get whether moving the piece would not leave its own king
+// in check
+func (b bitboards) leavesKingSafe(pieceType PieceType, color Color, from, to,
+	captured int) bool {
+	b.move(pieceType, color, from, to, captured)
+	king := b.pieces[color][King]
+	if king == 0 {
+		return true
+	}
+	return !b.isAttacked(king.popSquare(), getOppositeColor(color))
+}
+
+// leavesKingSafeAtomic get whether the move is legal in Atomic, where a
+// capture explodes the capturing piece and every piece but a pawn next to
+// the capture square. The move may not explode its own king, but is legal
+// whenever it explodes the enemy king, and kings next to each other cannot
+// check one another.
+func (b bitboards) leavesKingSafeAtomic(pieceType PieceType, color Color,
+	from, to, captured int) bool {
+	enemy := getOppositeColor(color)
+	isCapture := b.occupied[enemy]&(bitboard(1)<<uint(captured)) != 0
+	if isCapture && pieceType == King {
+		return false
+	}
+	b.move(pieceType, color, from, to, captured)
+	if isCapture {
+		pawns := b.pieces[Black][Pawn] | b.pieces[White][Pawn]
+		blast := kingAttacks[to]&^pawns | bitboard(1)<<uint(to)
+		for c := range b.pieces {
+			for pt := range b.pieces[c] {
+				b.pieces[c][pt] &^= blast
+			}
+			b.occupied[c] &^= blast
+		}
+		b.all = b.occupied[Black] | b.occupied[White]
+	}
+	king := b.pieces[color][King]
+	if king == 0 {
+		return false
+	} else if b.pieces[enemy][King] == 0 {
+		return true
+	}
+	kingSquare := king.popSquare()
+	return kingAttacks[kingSquare]&b.pieces[enemy][King] != 0 ||
+		!b.isAttacked(kingSquare, enemy)
+}
+
+// enPassantSquare get the square a pawn could capture onto en passant, or -1
+func (game *Game) enPassantSquare() int {
+	mover := game.previousMover
+	if mover == nil || mover.pieceType != Pawn || mover.color == game.turn ||
+		(game.previousMove.Y != 2 && game.previousMove.Y != -2) {
+		return -1
+	}
+	return square(Position{mover.position.File,
+		uint8(int8(mover.position.Rank) - game.previousMove.Y/2)})
+}
+
+// generateLegalMoves get every legal move for the side to move from the
+// bitboards, with one move request per promotion option
+func (game *Game) generateLegalMoves() []MoveRequest {
+	b := newBitboards(game.board)
+	moveRequests := game.generateMoves(&b, b.leavesKingSafe)
+	return append(moveRequests, game.castleMoves(&b)...)
+}
+
+// generateMoves get the side to move's moves other than castles that the
+// filter allows, or every such move if it is nil
+func (game *Game) generateMoves(b *bitboards, filter func(
+	pieceType PieceType, color Color, from, to, captured int) bool,
+) []MoveRequest {
+	us := game.turn
+	enPassant := game.enPassantSquare()
+	forward := 8
+	if us == Black {
+		forward = -8
+	}
+	moveRequests := make([]MoveRequest, 0, 48)
+	for pieceType := Rook; pieceType <= Pawn; pieceType++ {
+		for pieces := b.pieces[us][pieceType]; pieces != 0; {
+			from := pieces.popSquare()
+			targets := b.attacks(pieceType, us, from) &^ b.occupied[us]
+			if pieceType == Pawn {
+				targets &= b.occupied[getOppositeColor(us)]
+				if enPassant >= 0 &&
+					pawnAttacks[us][from]&(bitboard(1)<<uint(enPassant)) != 0 {
+					targets |= bitboard(1) << uint(enPassant)
+				}
+				targets |= game.pawnPushes(b, from, forward)
+			}
+			piece := game.board.Piece(squarePosition(from))
+			for targets != 0 {
+				to := targets.popSquare()
+				captured := to
+				if pieceType == Pawn && to == enPassant {
+					captured = to - forward
+				}
+				if filter == nil || filter(pieceType, us, from, to, captured) {
+					toPosition := squarePosition(to)
+					moveRequests = append(moveRequests, piece.moveRequests(
+						Move{int8(toPosition.File) - int8(piece.position.File),
+							int8(toPosition.Rank) - int8(piece.position.Rank)})...)
+				}
+			}
+		}
+	}
+	return moveRequests
+}
+
+func (game *Game) pawnPushes(b *bitboards, from, forward int) bitboard {
+	single := from + forward
+	if single < 0 || single > 63 || b.all&(bitboard(1)<<uint(single)) != 0 {
+		return 0
+	}
+	pushes := bitboard(1) << uint(single)
+	double := single + forward
+	if game.board.Piece(squarePosition(from)).movesTaken == 0 &&
+		double >= 0 && double <= 63 &&
+		b.all&(bitboard(1)<<uint(double)) == 0 {
+		pushes |= bitboard(1) << uint(double)
+	}
+	return pushes
+}
+
+func (game *Game) castleMoves(b *bitboards) []MoveRequest {
+	king, _ := game.getKings()
+	if king == nil {
+		return nil
+	}
+	left, right := king.castlingRooks(game.board)
+	if left == nil && right == nil {
+		return nil
+	}
+	enemy := getOppositeColor(king.color)
+	rank, kingFile := int(king.position.Rank), int(king.position.File)
+	moveRequests := []MoveRequest{}
+	// The king lands on the c or g file and the rook next to it. Apart from
+	// the two of them every square either passes over must be empty, and the
+	// king may not start on, pass through or land on an attacked square.
+	for _, side := range []struct {
+		rook           *Piece
+		kingTo, rookTo int
+	}{{left, 2, 3}, {right, 6, 5}} {
+		if side.rook == nil {
+			continue
+		}
+		rookFile := int(side.rook.position.File)
+		without := *b
+		without.all &^= squareAt(kingFile, rank) | squareAt(rookFile, rank)
+		canCastle := true
+		for _, span := range [2][2]int{
+			{kingFile, side.kingTo}, {rookFile, side.rookTo},
+		} {
+			for file := span[0]; canCastle; file += sign(span[1] - file) {
+				canCastle = without.all&squareAt(file, rank) == 0
+				if file == span[1] {
+					break
+				}
+			}
+		}
+		for file := kingFile; canCastle; file += sign(side.kingTo - file) {
+			canCastle = !without.isAttacked(rank*8+file, enemy)
+			if file == side.kingTo {
+				break
+			}
+		}
+		if !canCastle {
+			continue
+		}
+		// Chess960 castles are the king moving onto its rook, since the king
+		// may otherwise move one file or none.
+		to := side.kingTo
+		if game.chess960 {
+			to = rookFile
+		}
+		moveRequests = append(moveRequests, MoveRequest{
+			king.position, Move{int8(to - kingFile), 0}, nil})
+	}
+	return moveRequests
+}
+
+// PieceActivity is how freely a color's pieces move and how hard they press
+// on the enemy king
+type PieceActivity struct {
+	// Mobility is the number of squares the color's knights, bishops, rooks
+	// and queens attack that its own pieces are not on.
+	Mobility int
+	// KingZoneAttacks is the number of attacks by the color's pieces, besides
+	// its king, on the enemy king's square and the squares around it.
+	KingZoneAttacks int
+}
+
+// PieceActivity get each color's piece activity, indexed by color
+func (game *Game) PieceActivity() [2]PieceActivity {
+	game.mutex.RLock()
+	defer game.mutex.RUnlock()
+	b := newBitboards(game.board)
+	var activity [2]PieceActivity
+	for _, color := range []Color{Black, White} {
+		kingZone := bitboard(0)
+		enemyKings := b.pieces[getOppositeColor(color)][King]
+		for enemyKings != 0 {
+			sq := enemyKings.popSquare()
+			kingZone |= kingAttacks[sq] | bitboard(1)<<uint(sq)
+		}
+		for pieceType := range b.pieces[color] {
+			if PieceType(pieceType) == King {
+				continue
+			}
+			pieces := b.pieces[color][pieceType]
+			for pieces != 0 {
+				attacks := b.attacks(PieceType(pieceType), color,
+					pieces.popSquare())
+				activity[color].KingZoneAttacks +=
+					bits.OnesCount64(uint64(attacks & kingZone))
+				if PieceType(pieceType) != Pawn {
+					activity[color].Mobility +=
+						bits.OnesCount64(uint64(attacks &^ b.occupied[color]))
+				}
+			}
+		}
+	}
+	return activity
+}
+
+func sign(x int) int {
+	if x < 0 {
+		return -1
+	} else if x > 0 {
+		return 1
+	}
+	return 0
+}
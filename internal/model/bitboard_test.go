@@ -0,0 +1,148 @@
+package model
+
+import (
+	"sort"
+	"testing"
+)
+
+// pieceLegalMoves get the legal moves with the Piece API's move generator
+func pieceLegalMoves(game *Game) []MoveRequest {
+	king, _ := game.getKings()
+	moveRequests := []MoveRequest{}
+	for _, file := range game.board {
+		for _, piece := range file {
+			if piece == nil || piece.color != game.turn {
+				continue
+			}
+			for _, move := range piece.ValidMoves(game.board,
+				game.previousMove, game.previousMover, false, king) {
+				moveRequests =
+					append(moveRequests, piece.moveRequests(move)...)
+			}
+		}
+	}
+	return moveRequests
+}
+
+func pieceLegalMovesPerft(game *Game, depth int) uint64 {
+	moveRequests := pieceLegalMoves(game)
+	if depth == 1 {
+		return uint64(len(moveRequests))
+	}
+	nodes := uint64(0)
+	for _, moveRequest := range moveRequests {
+		game.makeMove(game.board.Piece(moveRequest.Position), moveRequest,
+			false)
+		nodes += pieceLegalMovesPerft(game, depth-1)
+		game.undo()
+	}
+	return nodes
+}
+
+func uciMoves(moveRequests []MoveRequest) []string {
+	out := []string{}
+	for _, moveRequest := range moveRequests {
+		out = append(out, moveRequest.UCI())
+	}
+	sort.Strings(out)
+	return out
+}
+
+func TestBitboardMatchesPieceMoves(t *testing.T) {
+	for _, position := range perftPositions {
+		game, _ := NewGameFromFEN(position.fen)
+		expected := uciMoves(pieceLegalMoves(game))
+		actual := uciMoves(game.legalMoves())
+		if len(expected) != len(actual) {
+			t.Error("Expected ", expected, " got ", actual)
+			continue
+		}
+		for i := range expected {
+			if expected[i] != actual[i] {
+				t.Error("Expected ", expected, " got ", actual)
+				break
+			}
+		}
+	}
+}
+
+func TestMagicNumbers(t *testing.T) {
+	for sq := 0; sq < 64; sq++ {
+		if _, ok := newMagic(sq, rookDirections, rookMagicNumbers[sq]); !ok {
+			t.Error("Expected a valid rook magic number for ", sq)
+		}
+		if _, ok := newMagic(sq, bishopDirections,
+			bishopMagicNumbers[sq]); !ok {
+			t.Error("Expected a valid bishop magic number for ", sq)
+		}
+	}
+}
+
+func TestMagicAttacks(t *testing.T) {
+	initAttackTables()
+	occupied := squareAt(3, 5) | squareAt(6, 3) | squareAt(1, 1) | squareAt(3, 0)
+	for sq := 0; sq < 64; sq++ {
+		if rookMagics[sq].attacksFor(occupied) !=
+			slidingAttacks(sq, occupied, rookDirections) ||
+			bishopMagics[sq].attacksFor(occupied) !=
+				slidingAttacks(sq, occupied, bishopDirections) {
+			t.Error("Expected magic attacks to match sliding attacks on ", sq)
+		}
+	}
+}
+
+func TestPieceActivity(t *testing.T) {
+	activity := NewGame().PieceActivity()
+	if activity[White] != (PieceActivity{4, 0}) ||
+		activity[Black] != (PieceActivity{4, 0}) {
+		t.Error("Expected only the knights to move at the start got ",
+			activity)
+	}
+	game, _ := NewGameFromFEN("4k3/8/8/8/8/8/8/3RK3 w - - 0 1")
+	activity = game.PieceActivity()
+	if activity[White] != (PieceActivity{10, 2}) ||
+		activity[Black] != (PieceActivity{0, 0}) {
+		t.Error("Expected the rook to attack d7 and d8 got ", activity)
+	}
+}
+
+func BenchmarkLegalMovesBitboard(b *testing.B) {
+	game, _ := NewGameFromFEN(perftPositions[1].fen)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		game.legalMoves()
+	}
+}
+
+func BenchmarkLegalMovesPiece(b *testing.B) {
+	game, _ := NewGameFromFEN(perftPositions[1].fen)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		pieceLegalMoves(game)
+	}
+}
+
+func BenchmarkPerftBitboard(b *testing.B) {
+	game, _ := NewGameFromFEN(perftPositions[1].fen)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		game.perft(2)
+	}
+}
+
+func BenchmarkPerftPiece(b *testing.B) {
+	game, _ := NewGameFromFEN(perftPositions[1].fen)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		pieceLegalMovesPerft(game, 2)
+	}
+}
+
+func BenchmarkMove(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		game := NewGame()
+		for _, uci := range []string{"e2e4", "e7e5", "g1f3", "b8c6"} {
+			game.MoveUCI(uci)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package model
+
+import "fmt"
+
+// chess960KnightPlacements are the knights' places among the five squares
+// left once the bishops and queen are placed, by the Scharnagl numbering
+var chess960KnightPlacements = [10][2]int{
+	{0, 1}, {0, 2}, {0, 3}, {0, 4}, {1, 2}, {1, 3}, {1, 4}, {2, 3}, {2, 4},
+	{3, 4},
+}
+
+// NewGameChess960 create a new Chess960 game from the start position with
+// the index, from 0 to 959 by the Scharnagl numbering where 518 is the
+// standard start position
+func NewGameChess960(index int) (*Game, error) {
+	backLine, err := chess960BackLine(index)
+	if err != nil {
+		return nil, err
+	}
+	board := newFullBoard()
+	createBackLine(&board, backLine)
+	game := createGame(board)
+	game.chess960 = true
+	game.startFEN = game.fen()
+	return game, nil
+}
+
+// NewGameChess960FromFEN create a new Chess960 game from a position in
+// Forsyth-Edwards Notation, with castling rights in X-FEN or Shredder-FEN
+func NewGameChess960FromFEN(fen string) (*Game, error) {
+	game, err := NewGameFromFEN(fen)
+	if err != nil {
+		return nil, err
+	}
+	game.chess960 = true
+	game.startFEN = game.fen()
+	return game, nil
+}
+
+// Chess960FEN get the Chess960 start position with the index in
+// Forsyth-Edwards Notation
+func Chess960FEN(index int) (string, error) {
+	game, err := NewGameChess960(index)
+	if err != nil {
+		return "", err
+	}
+	return game.StartFEN(), nil
+}
+
+// Chess960 get whether the game follows Chess960 castling rules, where a
+// castle is requested as the king moving onto its rook
+func (game *Game) Chess960() bool {
+	game.mutex.RLock()
+	defer game.mutex.RUnlock()
+	return game.chess960
+}
+
+// chess960BackLine place the bishops on opposite colors, then the queen and
+// knights on the empty squares, then the king between the two rooks
+func chess960BackLine(index int) ([8]PieceType, error) {
+	var backLine [8]PieceType
+	if index < 0 || index >= 960 {
+		return backLine, fmt.Errorf("chess960: invalid start position %d",
+			index)
+	}
+	placed := [8]bool{}
+	place := func(file int, pieceType PieceType) {
+		backLine[file] = pieceType
+		placed[file] = true
+	}
+	// emptyFile get the file of the nth empty square
+	emptyFile := func(n int) int {
+		for file := range placed {
+			if !placed[file] {
+				if n == 0 {
+					return file
+				}
+				n--
+			}
+		}
+		return -1
+	}
+	place(index%4*2+1, Bishop)
+	index /= 4
+	place(index%4*2, Bishop)
+	index /= 4
+	place(emptyFile(index%6), Queen)
+	index /= 6
+	knights := chess960KnightPlacements[index]
+	// Place the second knight first so the first's index is unchanged.
+	place(emptyFile(knights[1]), Knight)
+	place(emptyFile(knights[0]), Knight)
+	place(emptyFile(0), Rook)
+	place(emptyFile(0), King)
+	place(emptyFile(0), Rook)
+	return backLine, nil
+}
@@ -0,0 +1,138 @@
+package model
+
+import (
+	"testing"
+)
+
+// Known node counts from the Chess960 perft suite at
+// https://www.chessprogramming.org/Chess960_Perft_Results
+var chess960PerftPositions = []struct {
+	fen   string
+	nodes []uint64
+}{
+	{"bqnb1rkr/pp3ppp/3ppn2/2p5/5P2/P2P4/NPP1P1PP/BQ1BNRKR w HFhf - 2 9",
+		[]uint64{21, 528, 12189, 326672}},
+	{"2nnrbkr/p1qppppp/8/1ppb4/6PP/3PP3/PPP2P2/BQNNRBKR w HEhe - 1 9",
+		[]uint64{21, 807, 18002, 667366}},
+	{"b1q1rrkb/pppppppp/3nn3/8/P7/1PPP4/4PPPP/BQNNRKRB w GE - 1 9",
+		[]uint64{20, 479, 10471, 273318}},
+	{"qbbnnrkr/2pp2pp/p7/1p2pp2/8/P3PP2/1PPP1KPP/QBBNNR1R w hf - 0 9",
+		[]uint64{22, 593, 13440, 382958}},
+	{"1nbbnrkr/p1p1ppp1/3p4/1p3P1p/3Pq2P/8/PPP1P1P1/QNBBNRKR w HFhf - 0 9",
+		[]uint64{28, 1120, 31058, 1171749}},
+	{"qnbnr1kr/ppp1b1pp/4p3/3p1p2/8/2NPP3/PPP1BPPP/QNB1R1KR w HEhe - 1 9",
+		[]uint64{29, 899, 26578, 824055}},
+}
+
+func TestChess960StartPositions(t *testing.T) {
+	expected := map[int]string{
+		0:   "bbqnnrkr/pppppppp/8/8/8/8/PPPPPPPP/BBQNNRKR w KQkq - 0 1",
+		518: StartingFEN,
+		959: "rkrnnqbb/pppppppp/8/8/8/8/PPPPPPPP/RKRNNQBB w KQkq - 0 1",
+	}
+	seen := map[string]bool{}
+	for index := 0; index < 960; index++ {
+		fen, err := Chess960FEN(index)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected[index] != "" && fen != expected[index] {
+			t.Error("Expected ", expected[index], " got ", fen)
+		}
+		if seen[fen] {
+			t.Error("Expected distinct start positions got ", fen, " twice")
+		}
+		seen[fen] = true
+	}
+	for _, index := range []int{-1, 960} {
+		if _, err := NewGameChess960(index); err == nil {
+			t.Error("Expected an error for start position ", index)
+		}
+	}
+}
+
+func TestChess960Perft(t *testing.T) {
+	for _, position := range chess960PerftPositions {
+		game, err := NewGameChess960FromFEN(position.fen)
+		if err != nil {
+			t.Fatal(position.fen, err)
+		}
+		for i, expected := range position.nodes {
+			if testing.Short() && expected > 100000 {
+				break
+			}
+			if nodes := game.Perft(i + 1); nodes != expected {
+				t.Error("Expected ", position.fen, " perft(", i+1, ") = ",
+					expected, " got ", nodes)
+			}
+		}
+	}
+}
+
+func TestChess960CastleSwap(t *testing.T) {
+	game, _ := NewGameChess960FromFEN("4k3/8/8/8/8/8/8/5KR1 w G - 0 1")
+	moveRequest, err := game.ParseSAN("O-O")
+	if err != nil || moveRequest.UCI() != "f1g1" {
+		t.Fatal("Expected the king to move onto its rook got ",
+			moveRequest.UCI(), err)
+	}
+	if san, _ := game.SAN(moveRequest); san != "O-O" {
+		t.Error("Expected O-O got ", san)
+	}
+	game.Move(moveRequest)
+	if game.FEN() != "4k3/8/8/8/8/8/8/5RK1 b - - 1 1" {
+		t.Error("Expected the king and rook to swap got ", game.FEN())
+	}
+	game.Undo()
+	if game.FEN() != "4k3/8/8/8/8/8/8/5KR1 w K - 0 1" {
+		t.Error("Expected the castle to be undone got ", game.FEN())
+	}
+}
+
+func TestChess960CastleThroughRookAttack(t *testing.T) {
+	// Once the b1 rook leaves, the a1 rook attacks the king on c1.
+	game, _ := NewGameChess960FromFEN("4k3/8/8/8/8/8/8/rRK5 w B - 0 1")
+	if _, err := game.ParseSAN("O-O-O"); err == nil {
+		t.Error("Expected castling into check to be illegal")
+	}
+}
+
+func TestChess960FEN(t *testing.T) {
+	fens := []struct {
+		fen, xfen, shredder string
+	}{
+		{"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1",
+			"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1",
+			"r3k2r/8/8/8/8/8/8/R3K2R w HAha - 0 1"},
+		{"4k3/8/8/8/8/8/8/1RK2R1R w F - 0 1",
+			"4k3/8/8/8/8/8/8/1RK2R1R w F - 0 1",
+			"4k3/8/8/8/8/8/8/1RK2R1R w F - 0 1"},
+		{"rk4r1/8/8/8/8/8/8/RK4R1 w GAga - 0 1",
+			"rk4r1/8/8/8/8/8/8/RK4R1 w KQkq - 0 1",
+			"rk4r1/8/8/8/8/8/8/RK4R1 w GAga - 0 1"},
+	}
+	for _, fen := range fens {
+		game, err := NewGameChess960FromFEN(fen.fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if game.FEN() != fen.xfen || game.ShredderFEN() != fen.shredder {
+			t.Error("Expected ", fen.xfen, " and ", fen.shredder, " got ",
+				game.FEN(), " and ", game.ShredderFEN())
+		}
+	}
+}
+
+func TestChess960Detection(t *testing.T) {
+	standard, _ := NewGameFromFEN(StartingFEN)
+	shuffled, _ := NewGameFromFEN(chess960PerftPositions[2].fen)
+	if standard.Chess960() || !shuffled.Chess960() {
+		t.Error("Expected only the shuffled position to be Chess960")
+	}
+	shredder, err := NewGameFromFEN(
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w HAha - 0 1")
+	if err != nil || shredder.Chess960() || shredder.FEN() != StartingFEN {
+		t.Error("Expected the standard start in Shredder-FEN not to be "+
+			"Chess960 got ", err)
+	}
+}
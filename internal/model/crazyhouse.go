@@ -0,0 +1,226 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// dropPieceTypes are the piece types a pocket may hold, in the order FEN
+// and Pocket list them
+var dropPieceTypes = []PieceType{Queen, Rook, Bishop, Knight, Pawn}
+
+// NewDropRequest create a move request that drops a pocketed piece of the
+// type onto the empty square at the position
+func NewDropRequest(pieceType PieceType, position Position) MoveRequest {
+	return MoveRequest{position, Move{}, &pieceType}
+}
+
+// IsDrop get whether the move request drops a pocketed piece rather than
+// moving one on the board
+func (mr MoveRequest) IsDrop() bool {
+	return mr.Move == Move{} && mr.PromoteTo != nil
+}
+
+// Pocket get the color's pocketed pieces by type, which it may drop in
+// Crazyhouse or Bughouse
+func (game *Game) Pocket(color Color) map[PieceType]uint8 {
+	game.mutex.RLock()
+	defer game.mutex.RUnlock()
+	pocket := map[PieceType]uint8{}
+	for _, pieceType := range dropPieceTypes {
+		if count := game.pockets[color][pieceType]; count > 0 {
+			pocket[pieceType] = count
+		}
+	}
+	return pocket
+}
+
+// AddToPocket give the color a piece to drop, as a Bughouse partner's capture
+// does
+func (game *Game) AddToPocket(color Color, pieceType PieceType) error {
+	game.mutex.Lock()
+	defer game.mutex.Unlock()
+	if !game.variant.drops() {
+		return errors.New(game.variant.Name() + " has no pockets")
+	} else if pieceType == King {
+		return errors.New("a king cannot be pocketed")
+	}
+	game.hash ^= game.zobristState()
+	game.pockets[color][pieceType]++
+	game.hash ^= game.zobristState()
+	return nil
+}
+
+// LastCapture get the type and color of the piece the last move captured, as
+// it would be pocketed, so that it can be passed to a Bughouse partner
+func (game *Game) LastCapture() (pieceType PieceType, color Color, ok bool) {
+	game.mutex.RLock()
+	defer game.mutex.RUnlock()
+	if len(game.undoHistory) == 0 {
+		return 0, 0, false
+	}
+	captured := game.undoHistory[len(game.undoHistory)-1].capturedPiece
+	if captured == nil {
+		return 0, 0, false
+	}
+	return captured.pocketType(), captured.color, true
+}
+
+// pocketType get the type the piece is pocketed as once captured, which is a
+// pawn for a promoted piece
+func (piece *Piece) pocketType() PieceType {
+	if piece.promoted {
+		return Pawn
+	}
+	return piece.pieceType
+}
+
+// droppedPiece create the piece a drop places for the side to move. It cannot
+// castle, and as a pawn only moves two squares from its starting rank.
+func (game *Game) droppedPiece(moveRequest MoveRequest) *Piece {
+	piece := NewPiece(*moveRequest.PromoteTo, moveRequest.Position, game.turn)
+	startRank := uint8(1)
+	if game.turn == Black {
+		startRank = 6
+	}
+	if piece.pieceType != Pawn || piece.position.Rank != startRank {
+		piece.movesTaken = 1
+	}
+	return piece
+}
+
+// dropMoves get the side to move's drops onto empty squares, which must block
+// a check. Pawns cannot be dropped on a back rank.
+func (game *Game) dropMoves(b *bitboards) []MoveRequest {
+	us := game.turn
+	targets := ^b.all
+	if king, _ := game.getKings(); king != nil {
+		kingSquare := square(king.position)
+		enemy := getOppositeColor(us)
+		if b.isAttacked(kingSquare, enemy) {
+			blocks := bitboard(0)
+			for empty := targets; empty != 0; {
+				sq := bitboard(1) << uint(empty.popSquare())
+				blocked := *b
+				blocked.all |= sq
+				if !blocked.isAttacked(kingSquare, enemy) {
+					blocks |= sq
+				}
+			}
+			targets = blocks
+		}
+	}
+	moveRequests := []MoveRequest{}
+	for _, pieceType := range dropPieceTypes {
+		if game.pockets[us][pieceType] == 0 {
+			continue
+		}
+		squares := targets
+		if pieceType == Pawn {
+			squares &^= bitboard(0xFF) | bitboard(0xFF)<<56
+		}
+		for squares != 0 {
+			moveRequests = append(moveRequests,
+				NewDropRequest(pieceType, squarePosition(squares.popSquare())))
+		}
+	}
+	return moveRequests
+}
+
+// dropLetter get the uppercase letter of a dropped piece, for example "N" in
+// "N@f3"
+func dropLetter(pieceType PieceType) string {
+	return strings.ToUpper(string(fenPieceLetters[pieceType]))
+}
+
+// parseDrop get the legal drop of the piece with the uppercase letter, or a
+// pawn if there is none, onto the square
+func (game *Game) parseDrop(notation, letter, square string) (
+	MoveRequest, error,
+) {
+	pieceType, ok := Pawn, letter == "" || letter == "P"
+	for _, dropType := range dropPieceTypes {
+		if dropLetter(dropType) == letter {
+			pieceType, ok = dropType, true
+		}
+	}
+	position, err := parseAlgebraic(square)
+	if !ok || err != nil {
+		return MoveRequest{}, fmt.Errorf("invalid move %q", notation)
+	}
+	moveRequest := NewDropRequest(pieceType, position)
+	if !game.isLegal(moveRequest) {
+		return MoveRequest{}, fmt.Errorf("illegal move %q", notation)
+	}
+	return moveRequest, nil
+}
+
+func (crazyhouse) Name() string {
+	return "Crazyhouse"
+}
+
+func (crazyhouse) StartFEN() string {
+	return "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR[] w KQkq - 0 1"
+}
+
+func (crazyhouse) legalMoves(game *Game) []MoveRequest {
+	b := newBitboards(game.board)
+	moveRequests := game.generateMoves(&b, b.leavesKingSafe)
+	moveRequests = append(moveRequests, game.castleMoves(&b)...)
+	return append(moveRequests, game.dropMoves(&b)...)
+}
+
+// afterMove pocket the captured piece for the captor
+func (crazyhouse) afterMove(game *Game, record *moveRecord) {
+	captured := record.capturedPiece
+	if captured == nil {
+		return
+	}
+	game.hash ^= game.zobristState()
+	game.pockets[record.piece.color][captured.pocketType()]++
+	game.hash ^= game.zobristState()
+	record.pocketed = true
+}
+
+// Captured pieces stay in play, so only two lone kings with empty pockets
+// cannot win.
+func (crazyhouse) insufficientMaterial(game *Game) bool {
+	return !game.hasMaterial(Black) && !game.hasMaterial(White)
+}
+
+func (crazyhouse) canWin(game *Game, color Color) bool {
+	return game.hasMaterial(color)
+}
+
+func (crazyhouse) drops() bool {
+	return true
+}
+
+// hasMaterial get whether the color has a piece besides its king on the
+// board or in its pocket
+func (game *Game) hasMaterial(color Color) bool {
+	for _, count := range game.pockets[color] {
+		if count > 0 {
+			return true
+		}
+	}
+	return !game.OnlyKing(color)
+}
+
+func (bughouse) Name() string {
+	return "Bughouse"
+}
+
+// afterMove leave the captured piece for the match to pass to the captor's
+// partner
+func (bughouse) afterMove(game *Game, record *moveRecord) {}
+
+// A partner may pass pieces at any time, so either side can always win.
+func (bughouse) insufficientMaterial(game *Game) bool {
+	return false
+}
+
+func (bughouse) canWin(game *Game, color Color) bool {
+	return true
+}
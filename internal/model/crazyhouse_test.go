@@ -0,0 +1,130 @@
+package model
+
+import (
+	"testing"
+)
+
+func TestCrazyhouseDrop(t *testing.T) {
+	game := NewGameVariant(Crazyhouse)
+	for _, san := range []string{"e4", "d5", "exd5", "Qxd5"} {
+		if err := game.MoveSAN(san); err != nil {
+			t.Fatal(san, err)
+		}
+	}
+	fen := "rnb1kbnr/ppp1pppp/8/3q4/8/8/PPPP1PPP/RNBQKBNR[Pp] w KQkq - 0 3"
+	if game.FEN() != fen || game.Pocket(White)[Pawn] != 1 {
+		t.Error("Expected each side to pocket a pawn got ", game.FEN())
+	}
+	if _, err := game.ParseSAN("P@d8"); err == nil {
+		t.Error("Expected a pawn drop on the back rank to be illegal")
+	}
+	hash := game.Hash()
+	if err := game.MoveSAN("P@e4"); err != nil {
+		t.Fatal(err)
+	}
+	if game.Pocket(White)[Pawn] != 0 || game.whitePieces[Pawn] != 8 ||
+		game.Hash() != game.zobristHash() {
+		t.Error("Expected the pawn to leave the pocket got ", game.FEN())
+	}
+	game.Undo()
+	if game.FEN() != fen || game.Hash() != hash ||
+		game.whitePieces[Pawn] != 7 {
+		t.Error("Expected the drop to be undone got ", game.FEN())
+	}
+	if moveRequest, err := game.ParseUCI("P@e4"); err != nil ||
+		!moveRequest.IsDrop() || moveRequest.UCI() != "P@e4" {
+		t.Error("Expected a UCI drop got ", moveRequest, err)
+	}
+}
+
+func TestCrazyhouseDropBlocksCheck(t *testing.T) {
+	game, err := NewGameVariantFromFEN(Crazyhouse,
+		"4k3/8/8/8/8/8/8/r3K3[N] w - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := game.ParseSAN("N@c3"); err == nil {
+		t.Error("Expected a drop that leaves the king in check to be illegal")
+	}
+	drops := 0
+	for _, moveRequest := range game.legalMoves() {
+		if moveRequest.IsDrop() {
+			drops++
+		}
+	}
+	if drops != 3 {
+		t.Error("Expected three blocking drops got ", drops)
+	}
+}
+
+func TestCrazyhouseDroppedPawn(t *testing.T) {
+	game, _ := NewGameVariantFromFEN(Crazyhouse,
+		"4k3/8/8/8/8/8/8/4K3[Pp] w - - 0 1")
+	for _, san := range []string{"P@a2", "P@h7", "a4", "h5"} {
+		if err := game.MoveSAN(san); err != nil {
+			t.Error("Expected a dropped pawn to move two squares got ", err)
+		}
+	}
+	game, _ = NewGameVariantFromFEN(Crazyhouse,
+		"4k3/8/8/8/8/8/8/4K3[Pp] w - - 0 1")
+	game.MoveSAN("P@a3")
+	game.MoveSAN("Kd7")
+	if err := game.MoveSAN("a5"); err == nil {
+		t.Error("Expected a pawn dropped past its start rank to move one square")
+	}
+}
+
+func TestCrazyhousePromotedPieceReverts(t *testing.T) {
+	game, err := NewGameVariantFromFEN(Crazyhouse,
+		"r3k3/1P6/1n6/8/8/8/8/4K3[] w q - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	game.MoveSAN("bxa8=Q+")
+	if game.FEN() != "Q~3k3/8/1n6/8/8/8/8/4K3[R] b - - 0 1" {
+		t.Error("Expected a promoted queen got ", game.FEN())
+	}
+	game.MoveSAN("Nxa8")
+	if pocket := game.Pocket(Black); pocket[Pawn] != 1 || pocket[Queen] != 0 {
+		t.Error("Expected the promoted queen to be pocketed as a pawn got ",
+			game.FEN())
+	}
+	reread, err := NewGameVariantFromFEN(Crazyhouse,
+		"Q~3k3/8/1n6/8/8/8/8/4K3[R] b - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	reread.MoveSAN("Nxa8")
+	if reread.FEN() != game.FEN() {
+		t.Error("Expected ", game.FEN(), " got ", reread.FEN())
+	}
+	if _, err := NewGameFromFEN(
+		"4k3/8/8/8/8/8/8/4K3[Q] w - - 0 1"); err == nil {
+		t.Error("Expected an error for pockets in standard chess")
+	}
+}
+
+func TestBughousePockets(t *testing.T) {
+	game := NewGameVariant(Bughouse)
+	for _, san := range []string{"e4", "d5", "exd5"} {
+		game.MoveSAN(san)
+	}
+	pieceType, color, ok := game.LastCapture()
+	if !ok || pieceType != Pawn || color != Black ||
+		len(game.Pocket(White)) != 0 {
+		t.Error("Expected a captured black pawn left for the partner got ",
+			pieceType, color, ok)
+	}
+	if err := game.AddToPocket(Black, Knight); err != nil {
+		t.Fatal(err)
+	}
+	if game.Pocket(Black)[Knight] != 1 || game.Hash() != game.zobristHash() {
+		t.Error("Expected a pocketed knight got ", game.FEN())
+	}
+	if err := game.MoveSAN("N@f3+"); err != nil {
+		t.Error("Expected the partner's knight to be dropped got ", err)
+	}
+	if err := NewGame().AddToPocket(White, Pawn); err == nil {
+		t.Error("Expected an error for pockets in standard chess")
+	}
+}
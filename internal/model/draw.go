@@ -0,0 +1,68 @@
+package model
+
+import "errors"
+
+// DrawRules decides which draws end the game by themselves and which must be
+// claimed by a player
+type DrawRules uint8
+
+const (
+	// AutomaticDraws end the game at threefold repetition and after fifty
+	// moves without a capture or pawn move
+	AutomaticDraws = DrawRules(iota)
+	// FIDEDraws make threefold repetition and the fifty-move rule claimable,
+	// ending the game by themselves only at fivefold repetition and after
+	// seventy-five moves
+	FIDEDraws
+)
+
+// SetDrawRules set which draws end the game by themselves
+func (game *Game) SetDrawRules(drawRules DrawRules) {
+	game.mutex.Lock()
+	defer game.mutex.Unlock()
+	game.drawRules = drawRules
+}
+
+// DrawRules get which draws end the game by themselves
+func (game *Game) DrawRules() DrawRules {
+	game.mutex.RLock()
+	defer game.mutex.RUnlock()
+	return game.drawRules
+}
+
+// CanClaimDraw get whether a player may claim a draw in the current position,
+// either because it has occurred three times or because fifty moves have
+// passed without a capture or pawn move
+func (game *Game) CanClaimDraw() bool {
+	game.mutex.RLock()
+	defer game.mutex.RUnlock()
+	return game.claimableDraw() != NoTermination
+}
+
+// ClaimDraw end the game in a draw if the current position allows a claim
+func (game *Game) ClaimDraw() error {
+	game.mutex.Lock()
+	defer game.mutex.Unlock()
+	if game.gameOver {
+		return errors.New("the game is over")
+	}
+	termination := game.claimableDraw()
+	if termination == NoTermination {
+		return errors.New("no draw can be claimed in this position")
+	}
+	game.gameOver = true
+	game.result = GameResult{Draw: true, Termination: termination}
+	game.resultSet = true
+	return nil
+}
+
+func (game *Game) claimableDraw() Termination {
+	if game.gameOver {
+		return NoTermination
+	} else if game.positionHistory[game.hash] > 2 {
+		return ThreefoldRepetition
+	} else if game.turnsSinceCaptureOrPawnMove >= 100 {
+		return FiftyMoveRule
+	}
+	return NoTermination
+}
@@ -0,0 +1,97 @@
+package model
+
+import (
+	"testing"
+)
+
+var knightShuffle = []string{"Nf3", "Nf6", "Ng1", "Ng8"}
+
+func TestClaimThreefoldRepetition(t *testing.T) {
+	game := NewGame()
+	game.SetDrawRules(FIDEDraws)
+	for i := 0; i < 2; i++ {
+		for _, san := range knightShuffle {
+			if game.CanClaimDraw() {
+				t.Error("Expected no claim after ", san)
+			}
+			game.MoveSAN(san)
+		}
+	}
+	if game.GameOver() || !game.CanClaimDraw() {
+		t.Error("Expected a claimable draw got ", game.Result())
+	}
+	if err := game.ClaimDraw(); err != nil {
+		t.Error("Expected to claim a draw got ", err)
+	}
+	result := game.Result()
+	if !game.GameOver() || !result.Draw ||
+		result.Termination != ThreefoldRepetition {
+		t.Error("Expected ", ThreefoldRepetition, " got ", result.Termination)
+	}
+	if err := game.ClaimDraw(); err == nil {
+		t.Error("Expected an error claiming a draw once the game is over")
+	}
+}
+
+func TestFivefoldRepetition(t *testing.T) {
+	game := NewGame()
+	game.SetDrawRules(FIDEDraws)
+	for i := 0; i < 4; i++ {
+		for _, san := range knightShuffle {
+			game.MoveSAN(san)
+		}
+		if i < 3 && game.GameOver() {
+			t.Error("Draw too early")
+		}
+	}
+	result := game.Result()
+	if !game.GameOver() || !result.Draw ||
+		result.Termination != FivefoldRepetition {
+		t.Error("Expected ", FivefoldRepetition, " got ", result.Termination)
+	}
+}
+
+func TestClaimFiftyMoveRule(t *testing.T) {
+	game, _ := NewGameFromFEN("4k3/8/8/8/8/8/8/R3K3 w - - 99 100")
+	game.SetDrawRules(FIDEDraws)
+	if game.CanClaimDraw() || game.ClaimDraw() == nil {
+		t.Error("Expected no claim after 99 half-moves")
+	}
+	game.MoveSAN("Ra2")
+	if game.GameOver() || !game.CanClaimDraw() {
+		t.Error("Expected a claimable draw got ", game.Result())
+	}
+	game.MoveSAN("Kd7")
+	if err := game.ClaimDraw(); err != nil {
+		t.Error("Expected to claim a draw got ", err)
+	}
+	if result := game.Result(); result.Termination != FiftyMoveRule {
+		t.Error("Expected ", FiftyMoveRule, " got ", result.Termination)
+	}
+}
+
+func TestSeventyFiveMoveRuleFIDE(t *testing.T) {
+	game, _ := NewGameFromFEN("4k3/8/8/8/8/8/8/R3K3 w - - 148 100")
+	game.SetDrawRules(FIDEDraws)
+	game.MoveSAN("Ra2")
+	if game.GameOver() {
+		t.Error("Draw too early")
+	}
+	game.MoveSAN("Kd7")
+	if result := game.Result(); !game.GameOver() ||
+		result.Termination != SeventyFiveMoveRule {
+		t.Error("Expected ", SeventyFiveMoveRule, " got ", result.Termination)
+	}
+}
+
+func TestAutomaticDrawsCannotBeClaimed(t *testing.T) {
+	game := NewGame()
+	for i := 0; i < 2; i++ {
+		for _, san := range knightShuffle {
+			game.MoveSAN(san)
+		}
+	}
+	if !game.GameOver() || game.CanClaimDraw() || game.ClaimDraw() == nil {
+		t.Error("Expected an automatic draw got ", game.Result())
+	}
+}
@@ -0,0 +1,438 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// StartingFEN is the standard starting position in Forsyth-Edwards Notation
+const StartingFEN = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
+
+var fenPieceLetters = map[PieceType]byte{
+	Rook: 'r', Knight: 'n', Bishop: 'b', Queen: 'q', King: 'k', Pawn: 'p',
+}
+
+// NewGameFromFEN create a new game from a position in Forsyth-Edwards
+// Notation. The halfmove clock and fullmove number may be omitted.
+func NewGameFromFEN(fen string) (*Game, error) {
+	return NewGameVariantFromFEN(Standard, fen)
+}
+
+// NewGameVariantFromFEN create a new game of the variant from a position in
+// Forsyth-Edwards Notation. Three-check positions may give the checks each
+// side has left before the halfmove clock, for example "3+3". Crazyhouse
+// positions may follow the placement with the pockets, for example "[Qp]",
+// and mark promoted pieces with a "~".
+func NewGameVariantFromFEN(variant Variant, fen string) (*Game, error) {
+	fields := strings.Fields(fen)
+	checks := ""
+	if variant == ThreeCheck && (len(fields) == 5 || len(fields) == 7) {
+		checks = fields[4]
+		fields = append(fields[:4:4], fields[5:]...)
+	}
+	if len(fields) != 4 && len(fields) != 6 {
+		return nil, fmt.Errorf("fen: expected 6 fields got %d", len(fields))
+	}
+	placement, pocket, hasPocket := splitFENPocket(fields[0])
+	if hasPocket && !variant.drops() {
+		return nil, fmt.Errorf("fen: %s has no pockets", variant.Name())
+	}
+	board, err := parseFENBoard(placement, variant.drops())
+	if err != nil {
+		return nil, err
+	}
+	game := newGameFromBoard(board)
+	game.variant = variant
+	if err := game.setFENPocket(pocket); err != nil {
+		return nil, err
+	}
+	if err := variant.setUp(game); err != nil {
+		return nil, err
+	}
+	switch fields[1] {
+	case "w":
+		game.turn = White
+	case "b":
+		game.turn = Black
+	default:
+		return nil, fmt.Errorf("fen: invalid side to move %q", fields[1])
+	}
+	if err := game.setFENCastlingRights(fields[2]); err != nil {
+		return nil, err
+	}
+	if err := game.setFENEnPassant(fields[3]); err != nil {
+		return nil, err
+	}
+	if len(fields) == 6 {
+		halfMoves, err := strconv.ParseUint(fields[4], 10, 16)
+		if err != nil {
+			return nil, fmt.Errorf("fen: invalid halfmove clock %q", fields[4])
+		}
+		fullMoves, err := strconv.ParseUint(fields[5], 10, 16)
+		if err != nil || fullMoves == 0 {
+			return nil, fmt.Errorf("fen: invalid fullmove number %q", fields[5])
+		}
+		game.turnsSinceCaptureOrPawnMove = uint16(halfMoves)
+		game.fullMoveNumber = uint16(fullMoves)
+	}
+	if checks != "" {
+		if err := game.setFENChecks(checks); err != nil {
+			return nil, err
+		}
+	}
+	game.turn = getOppositeColor(game.turn)
+	enemyInCheck := game.isInCheck()
+	game.turn = getOppositeColor(game.turn)
+	if enemyInCheck {
+		return nil, errors.New("fen: the side not to move is in check")
+	}
+	game.hash = game.zobristHash()
+	game.updatePositionHistory()
+	game.startFEN = game.fen()
+	return game, nil
+}
+
+// parseFENBoard parse the placement, which may mark promoted pieces with a "~"
+// if the variant has drops
+func parseFENBoard(placement string, drops bool) (Board, error) {
+	var board Board
+	ranks := strings.Split(placement, "/")
+	if len(ranks) != 8 {
+		return board, fmt.Errorf("fen: expected 8 ranks got %d", len(ranks))
+	}
+	for i, rankString := range ranks {
+		rank := uint8(7 - i)
+		file := uint8(0)
+		for _, r := range rankString {
+			if r >= '1' && r <= '8' {
+				file += uint8(r - '0')
+				continue
+			} else if r == '~' {
+				// The piece before was promoted.
+				if !drops || file == 0 || file > 8 ||
+					board[file-1][rank] == nil {
+					return board, fmt.Errorf("fen: invalid piece %q", r)
+				}
+				board[file-1][rank].promoted = true
+				continue
+			}
+			pieceType, color, ok := fenPiece(r)
+			if !ok {
+				return board, fmt.Errorf("fen: invalid piece %q", r)
+			} else if file >= 8 {
+				return board, fmt.Errorf("fen: rank %d is too long", rank+1)
+			}
+			piece := NewPiece(pieceType, NewPosition(file, rank), color)
+			startRank := uint8(1)
+			if color == Black {
+				startRank = 6
+			}
+			if pieceType == Pawn && rank != startRank {
+				// A pawn off its starting rank has lost its double step.
+				piece.movesTaken = 1
+			}
+			board[file][rank] = piece
+			file++
+		}
+		if file != 8 {
+			return board, fmt.Errorf("fen: rank %d has %d files", rank+1, file)
+		}
+	}
+	return board, nil
+}
+
+// splitFENPocket split the pockets, for example "[Qp]", off the end of the
+// placement
+func splitFENPocket(placement string) (string, string, bool) {
+	start := strings.IndexByte(placement, '[')
+	if start < 0 || !strings.HasSuffix(placement, "]") {
+		return placement, "", false
+	}
+	return placement[:start], placement[start+1 : len(placement)-1], true
+}
+
+func (game *Game) setFENPocket(pocket string) error {
+	for _, r := range pocket {
+		pieceType, color, ok := fenPiece(r)
+		if !ok || pieceType == King {
+			return fmt.Errorf("fen: invalid pocket %q", pocket)
+		}
+		game.pockets[color][pieceType]++
+	}
+	return nil
+}
+
+func fenPiece(r rune) (PieceType, Color, bool) {
+	color := White
+	lower := r
+	if r >= 'a' && r <= 'z' {
+		color = Black
+	} else {
+		lower = r - 'A' + 'a'
+	}
+	for pieceType, letter := range fenPieceLetters {
+		if rune(letter) == lower {
+			return pieceType, color, true
+		}
+	}
+	return 0, color, false
+}
+
+// setFENCastlingRights marks kings and rooks as moved unless the castling
+// field grants them rights, since rights are derived from movesTaken. Besides
+// KQkq it accepts the file letters of X-FEN and Shredder-FEN, and marks the
+// game as Chess960 if the rights need a king or rook off its standard square.
+func (game *Game) setFENCastlingRights(castling string) error {
+	rights := map[Position]bool{}
+	if castling != "-" {
+		for _, r := range castling {
+			king := game.whiteKing
+			if r >= 'a' && r <= 'z' {
+				king = game.blackKing
+				r = r - 'a' + 'A'
+			}
+			if king == nil {
+				return fmt.Errorf("fen: castling rights %q do not match the "+
+					"board", castling)
+			}
+			var rook *Piece
+			switch {
+			case r == 'K':
+				rook = game.outermostRook(king, 1)
+			case r == 'Q':
+				rook = game.outermostRook(king, -1)
+			case r >= 'A' && r <= 'H':
+				rook = game.board[r-'A'][king.Rank()]
+			default:
+				return fmt.Errorf("fen: invalid castling rights %q", castling)
+			}
+			backRank := uint8(0)
+			if king.color == Black {
+				backRank = 7
+			}
+			if king.Rank() != backRank || rook == nil ||
+				rook.pieceType != Rook || rook.color != king.color ||
+				rook.Rank() != king.Rank() || rook.File() == king.File() {
+				return fmt.Errorf("fen: castling rights %q do not match the "+
+					"board", castling)
+			}
+			if king.File() != 4 || (rook.File() != 0 && rook.File() != 7) {
+				game.chess960 = true
+			}
+			rights[king.position] = true
+			rights[rook.position] = true
+		}
+	}
+	for _, file := range game.board {
+		for _, piece := range file {
+			if piece != nil && !rights[piece.position] &&
+				(piece.pieceType == King || piece.pieceType == Rook) {
+				piece.movesTaken = 1
+			}
+		}
+	}
+	return nil
+}
+
+// setFENChecks set the checks each side has given in Three-check from the
+// checks each side has left
+func (game *Game) setFENChecks(checks string) error {
+	var white, black uint8
+	_, err := fmt.Sscanf(checks, "%d+%d", &white, &black)
+	if err != nil || white > 3 || black > 3 ||
+		checks != fmt.Sprintf("%d+%d", white, black) {
+		return fmt.Errorf("fen: invalid remaining checks %q", checks)
+	}
+	game.checks[White] = 3 - white
+	game.checks[Black] = 3 - black
+	return nil
+}
+
+// outermostRook get the king's rook nearest the edge of the board in the
+// direction, which is the one K and Q castling rights refer to
+func (game *Game) outermostRook(king *Piece, direction int) *Piece {
+	file := 0
+	if direction > 0 {
+		file = 7
+	}
+	for ; file != int(king.File()); file -= direction {
+		piece := game.board[file][king.Rank()]
+		if piece != nil && piece.pieceType == Rook && piece.color == king.color {
+			return piece
+		}
+	}
+	return nil
+}
+
+func (game *Game) setFENEnPassant(enPassant string) error {
+	if enPassant == "-" {
+		return nil
+	}
+	target, err := parseAlgebraic(enPassant)
+	if err != nil {
+		return err
+	}
+	// The target square sits behind a pawn of the side that just moved.
+	yDirection := int8(-1)
+	expectedRank := uint8(5)
+	if game.turn == Black {
+		yDirection = 1
+		expectedRank = 2
+	}
+	if target.Rank != expectedRank {
+		return fmt.Errorf("fen: invalid en passant square %q", enPassant)
+	}
+	pawn := game.board[target.File][uint8(int8(target.Rank)+yDirection)]
+	origin := game.board[target.File][uint8(int8(target.Rank)-yDirection)]
+	if pawn == nil || pawn.pieceType != Pawn || pawn.color == game.turn ||
+		game.board[target.File][target.Rank] != nil || origin != nil {
+		return fmt.Errorf("fen: invalid en passant square %q", enPassant)
+	}
+	game.previousMover = pawn
+	game.previousMove = Move{0, 2 * yDirection}
+	return nil
+}
+
+// FEN get the game's position in Forsyth-Edwards Notation, using X-FEN
+// castling rights for Chess960 games
+func (game *Game) FEN() string {
+	game.mutex.RLock()
+	defer game.mutex.RUnlock()
+	return game.fen()
+}
+
+// ShredderFEN get the game's position in Shredder-FEN, which names castling
+// rights by the rook's file, for example "HAha"
+func (game *Game) ShredderFEN() string {
+	game.mutex.RLock()
+	defer game.mutex.RUnlock()
+	return game.formatFEN(true)
+}
+
+func (game *Game) fen() string {
+	return game.formatFEN(false)
+}
+
+func (game *Game) formatFEN(shredder bool) string {
+	var sb strings.Builder
+	for rank := 7; rank >= 0; rank-- {
+		empty := 0
+		for file := 0; file < 8; file++ {
+			piece := game.board[file][rank]
+			if piece == nil {
+				empty++
+				continue
+			}
+			if empty > 0 {
+				sb.WriteString(strconv.Itoa(empty))
+				empty = 0
+			}
+			letter := fenPieceLetters[piece.pieceType]
+			if piece.color == White {
+				letter = letter - 'a' + 'A'
+			}
+			sb.WriteByte(letter)
+			if piece.promoted && game.variant.drops() {
+				sb.WriteByte('~')
+			}
+		}
+		if empty > 0 {
+			sb.WriteString(strconv.Itoa(empty))
+		}
+		if rank > 0 {
+			sb.WriteByte('/')
+		}
+	}
+	if game.variant.drops() {
+		sb.WriteString("[" + game.fenPocket() + "]")
+	}
+	if game.turn == White {
+		sb.WriteString(" w ")
+	} else {
+		sb.WriteString(" b ")
+	}
+	sb.WriteString(game.fenCastlingRights(shredder))
+	sb.WriteByte(' ')
+	sb.WriteString(game.fenEnPassant())
+	if game.variant == ThreeCheck {
+		sb.WriteString(fmt.Sprintf(" %d+%d",
+			3-game.checks[White], 3-game.checks[Black]))
+	}
+	sb.WriteString(" " + strconv.Itoa(int(game.turnsSinceCaptureOrPawnMove)))
+	sb.WriteString(" " + strconv.Itoa(int(game.fullMoveNumber)))
+	return sb.String()
+}
+
+func (game *Game) fenPocket() string {
+	out := ""
+	for _, color := range []Color{White, Black} {
+		for _, pieceType := range dropPieceTypes {
+			letter := string(fenPieceLetters[pieceType])
+			if color == White {
+				letter = dropLetter(pieceType)
+			}
+			out += strings.Repeat(letter, int(game.pockets[color][pieceType]))
+		}
+	}
+	return out
+}
+
+func (game *Game) fenCastlingRights(shredder bool) string {
+	out := ""
+	for _, king := range [2]*Piece{game.whiteKing, game.blackKing} {
+		if game.onBoard(king) == nil {
+			continue
+		}
+		left, right := king.castlingRooks(game.board)
+		for _, side := range []struct {
+			rook      *Piece
+			letter    byte
+			direction int
+		}{{right, 'K', 1}, {left, 'Q', -1}} {
+			if side.rook == nil {
+				continue
+			}
+			letter := side.letter
+			// X-FEN only names the file when an outer rook is in the way.
+			if shredder || game.outermostRook(king, side.direction) != side.rook {
+				letter = 'A' + side.rook.File()
+			}
+			if king.color == Black {
+				letter = letter - 'A' + 'a'
+			}
+			out += string(letter)
+		}
+	}
+	if out == "" {
+		return "-"
+	}
+	return out
+}
+
+func (game *Game) fenEnPassant() string {
+	mover := game.previousMover
+	if mover == nil || mover.pieceType != Pawn ||
+		(game.previousMove.Y != 2 && game.previousMove.Y != -2) {
+		return "-"
+	}
+	target := Position{
+		mover.position.File,
+		uint8(int8(mover.position.Rank) - game.previousMove.Y/2),
+	}
+	return target.algebraic()
+}
+
+// algebraic get the position's square name, for example "e4"
+func (pos Position) algebraic() string {
+	return string([]byte{'a' + pos.File, '1' + pos.Rank})
+}
+
+func parseAlgebraic(square string) (Position, error) {
+	if len(square) != 2 || square[0] < 'a' || square[0] > 'h' ||
+		square[1] < '1' || square[1] > '8' {
+		return Position{}, fmt.Errorf("invalid square %q", square)
+	}
+	return Position{square[0] - 'a', square[1] - '1'}, nil
+}
@@ -0,0 +1,179 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestFENStartingPosition(t *testing.T) {
+	game := NewGame()
+	if game.FEN() != StartingFEN {
+		t.Error("Expected starting FEN got ", game.FEN())
+	}
+	game, err := NewGameFromFEN(StartingFEN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if debug {
+		fmt.Println(game.board)
+	}
+	if game.turn != White || game.blackKing != game.board[4][7] ||
+		game.whiteKing != game.board[4][0] {
+		t.Error("Expected starting position got ", game.FEN())
+	}
+	err = game.Move(MoveRequest{Position{4, 1}, Move{0, 2}, nil})
+	if err != nil {
+		t.Error("Expected pawn double step to be valid", err)
+	}
+}
+
+func TestFENAfterMoves(t *testing.T) {
+	game := NewGame()
+	game.Move(MoveRequest{Position{4, 1}, Move{0, 2}, nil})
+	expected := "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1"
+	if game.FEN() != expected {
+		t.Error("Expected ", expected, " got ", game.FEN())
+	}
+	game.Move(MoveRequest{Position{6, 7}, Move{-1, -2}, nil})
+	game.Move(MoveRequest{Position{4, 0}, Move{0, 1}, nil})
+	expected = "rnbqkb1r/pppppppp/5n2/8/4P3/8/PPPPKPPP/RNBQ1BNR b kq - 2 2"
+	if game.FEN() != expected {
+		t.Error("Expected ", expected, " got ", game.FEN())
+	}
+}
+
+func TestFENRoundTrip(t *testing.T) {
+	fens := []string{
+		"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+		"8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1",
+		"r3k2r/Pppp1ppp/1b3nbN/nP6/BBP1P3/q4N2/Pp1P2PP/R2Q1RK1 w kq - 0 1",
+		"rnbq1k1r/pp1Pbppp/2p5/8/2B5/8/PPP1NnPP/RNBQK2R w KQ - 1 8",
+		"rnbqkbnr/ppp1p1pp/8/3pPp2/8/8/PPPP1PPP/RNBQKBNR w Kq f6 0 3",
+		"4k3/8/8/8/8/8/8/R3K3 b Q - 57 120",
+		"4k3/8/8/8/8/8/8/R3K3 b Q - 300 250",
+	}
+	for _, fen := range fens {
+		game, err := NewGameFromFEN(fen)
+		if err != nil {
+			t.Error("Expected valid fen ", fen, " got ", err)
+			continue
+		}
+		if game.FEN() != fen {
+			t.Error("Expected ", fen, " got ", game.FEN())
+		}
+	}
+}
+
+func TestFENShortForm(t *testing.T) {
+	game, err := NewGameFromFEN("8/8/8/8/8/8/8/K1k5 b - -")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if game.FEN() != "8/8/8/8/8/8/8/K1k5 b - - 0 1" {
+		t.Error("Expected default clocks got ", game.FEN())
+	}
+}
+
+func TestFENEnPassant(t *testing.T) {
+	game, err := NewGameFromFEN(
+		"rnbqkbnr/ppp1p1pp/8/3pPp2/8/8/PPPP1PPP/RNBQKBNR w KQkq f6 0 3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = game.Move(MoveRequest{Position{4, 4}, Move{1, 1}, nil})
+	if err != nil || game.board[5][4] != nil {
+		t.Error("Expected en passant capture", err)
+	}
+	game, _ = NewGameFromFEN(
+		"rnbqkbnr/ppp1p1pp/8/3pPp2/8/8/PPPP1PPP/RNBQKBNR w KQkq - 0 3")
+	err = game.Move(MoveRequest{Position{4, 4}, Move{1, 1}, nil})
+	if err == nil {
+		t.Error("Expected en passant to be unavailable")
+	}
+}
+
+func TestFENCastlingRights(t *testing.T) {
+	game, err := NewGameFromFEN("r3k2r/8/8/8/8/8/8/R3K2R w Kq - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = game.Move(MoveRequest{Position{4, 0}, Move{-2, 0}, nil})
+	if err == nil {
+		t.Error("Expected queenside castle to be unavailable")
+	}
+	err = game.Move(MoveRequest{Position{4, 0}, Move{2, 0}, nil})
+	if err != nil || game.board[5][0] == nil ||
+		game.board[5][0].pieceType != Rook {
+		t.Error("Expected kingside castle", err)
+	}
+	if game.FEN() != "r3k2r/8/8/8/8/8/8/R4RK1 b q - 1 1" {
+		t.Error("Expected castled position got ", game.FEN())
+	}
+}
+
+func TestFENHalfmoveClockDraw(t *testing.T) {
+	game, err := NewGameFromFEN("4k3/8/8/8/8/8/8/R3K3 w - - 99 80")
+	if err != nil {
+		t.Fatal(err)
+	}
+	game.Move(MoveRequest{Position{0, 0}, Move{0, 1}, nil})
+	if !game.GameOver() || !game.Result().Draw {
+		t.Error("Expected draw by the fifty move rule")
+	}
+	// Clocks past the seventy-five move rule come from other sources.
+	game, err = NewGameFromFEN("4k3/8/8/8/8/8/8/R3K3 w - - 256 200")
+	if err != nil {
+		t.Fatal(err)
+	}
+	game.Move(MoveRequest{Position{0, 0}, Move{0, 1}, nil})
+	if game.Result().Termination != SeventyFiveMoveRule ||
+		!strings.HasSuffix(game.FEN(), " 257 200") {
+		t.Error("Expected a draw by the seventy-five move rule got ", err)
+	}
+}
+
+func TestFENInvalid(t *testing.T) {
+	fens := []string{
+		"",
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP w KQkq - 0 1",
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR x KQkq - 0 1",
+		"rnbqkbnr/pppppppp/9/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNX w KQkq - 0 1",
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 0",
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - x 1",
+		// Missing kings
+		"rnbq1bnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQ - 0 1",
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNK w kq - 0 1",
+		// The side not to move is in check
+		"4k3/8/8/8/8/8/8/4R1K1 w - - 0 1",
+		// Pawns on back ranks
+		"P3k3/8/8/8/8/8/8/4K3 w - - 0 1",
+		"4k3/8/8/8/8/8/8/3pK3 w - - 0 1",
+		// Castling rights without the pieces
+		"4k3/8/8/8/8/8/8/4K3 w K - 0 1",
+		"r3k2r/8/8/8/8/8/8/R3K2R w KQkqx - 0 1",
+		// En passant without a pawn to capture
+		"4k3/8/8/8/8/8/8/4K3 w - e6 0 1",
+		"4k3/8/8/4p3/8/8/8/4K3 w - e3 0 1",
+		// Promoted pieces outside of variants with drops
+		"Q~3k3/8/8/8/8/8/8/4K3 w - - 0 1",
+	}
+	for _, fen := range fens {
+		if _, err := NewGameFromFEN(fen); err == nil {
+			t.Error("Expected invalid fen error for ", fen)
+		}
+	}
+	// Promoted markers past the end of a rank
+	for _, fen := range []string{
+		"55~/8/8/8/8/8/8/8 w - - 0 1",
+		"4k3/8/8/8/8/8/8/4K3Q~ w - - 0 1",
+		"~4k3/8/8/8/8/8/8/4K3 w - - 0 1",
+	} {
+		for _, variant := range []Variant{Standard, Crazyhouse} {
+			if _, err := NewGameVariantFromFEN(variant, fen); err == nil {
+				t.Error("Expected invalid fen error for ", fen)
+			}
+		}
+	}
+}
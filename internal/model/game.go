@@ -14,24 +14,38 @@ type (
 		turn                        Color
 		gameOver                    bool
 		result                      GameResult
+		resultSet                   bool
 		previousMove                Move
 		previousMover               *Piece
 		blackKing                   *Piece
 		whiteKing                   *Piece
 		whitePieces                 map[PieceType]uint8
 		blackPieces                 map[PieceType]uint8
-		positionHistory             map[string]uint8
-		turnsSinceCaptureOrPawnMove uint8
+		positionHistory             map[uint64]uint8
+		hash                        uint64
+		turnsSinceCaptureOrPawnMove uint16
+		fullMoveNumber              uint16
+		drawRules                   DrawRules
+		chess960                    bool
+		variant                     Variant
+		checks                      [2]uint8
+		pockets                     [2][6]uint8
+		startFEN                    string
+		moveHistory                 []MoveRequest
+		undoHistory                 []moveRecord
 		mutex                       sync.RWMutex
 	}
 
 	// GameResult is a struct representing the result of a game
 	GameResult struct {
-		Winner Color
-		Draw   bool
+		Winner      Color
+		Draw        bool
+		Termination Termination
 	}
 
-	// MoveRequest is a move request that can be applied to a game
+	// MoveRequest is a move request that can be applied to a game. A drop
+	// has no Move, and PromoteTo is the type of pocketed piece it places on
+	// Position.
 	MoveRequest struct {
 		Position  Position
 		Move      Move
@@ -43,38 +57,263 @@ type (
 func (game *Game) Move(moveRequest MoveRequest) error {
 	game.mutex.Lock()
 	defer game.mutex.Unlock()
+	return game.move(moveRequest)
+}
+
+func (game *Game) move(moveRequest MoveRequest) error {
 	piece := game.board[moveRequest.Position.File][moveRequest.Position.Rank]
+	if moveRequest.IsDrop() {
+		// The dropped piece is only placed on the board by the move.
+		piece = &Piece{pieceType: *moveRequest.PromoteTo, color: game.turn}
+	}
 	err := game.isMoveRequestValid(piece)
 	if err != nil {
 		return err
 	}
-	king, enemyKing := game.getKings()
-	capturedPiece, err := piece.takeMove(game.board, moveRequest.Move,
-		game.previousMove, game.previousMover, king, moveRequest.PromoteTo)
-	if err != nil {
-		return err
+	if !game.isLegal(moveRequest) {
+		return errors.New("piece attempted invalid move")
 	}
-	game.handleCapturedPiece(piece, capturedPiece)
-	drawByRepetion, err := game.updatePositionHistory()
-	if err != nil {
-		return err
+	drawByRepetion := game.makeMove(piece, moveRequest, true)
+	possibleEnemyMoves := game.legalMoves()
+	result := game.variant.termination(game, possibleEnemyMoves)
+	if result.Termination == NoTermination {
+		result = game.standardTermination(
+			piece.color, drawByRepetion, possibleEnemyMoves)
 	}
-	drawByFiftyMoveRule := game.turnsSinceCaptureOrPawnMove >= 100
-	possibleEnemyMoves := AllMoves(game.board, getOppositeColor(piece.color),
-		moveRequest.Move, piece, false, enemyKing)
-	if len(possibleEnemyMoves) == 0 &&
-		enemyKing.isThreatened(game.board, moveRequest.Move, piece) {
+	if result.Termination != NoTermination {
 		game.gameOver = true
-		game.result.Winner = game.turn
-	} else if len(possibleEnemyMoves) == 0 || drawByRepetion ||
-		drawByFiftyMoveRule || game.isDrawByInsufficientMaterial() {
-		game.gameOver = true
-		game.result.Draw = true
+		game.result = result
+	}
+	return nil
+}
+
+// standardTermination get the result if the position after the mover's move
+// ends the game by the standard rules
+func (game *Game) standardTermination(
+	mover Color, drawByRepetion bool, possibleEnemyMoves []MoveRequest,
+) GameResult {
+	automaticDraws := game.drawRules == AutomaticDraws
+	termination := NoTermination
+	if len(possibleEnemyMoves) == 0 && game.isInCheck() {
+		termination = Checkmate
+	} else if len(possibleEnemyMoves) == 0 {
+		termination = Stalemate
+	} else if game.positionHistory[game.hash] > 4 {
+		termination = FivefoldRepetition
+	} else if drawByRepetion && automaticDraws {
+		termination = ThreefoldRepetition
+	} else if game.turnsSinceCaptureOrPawnMove >= 150 {
+		termination = SeventyFiveMoveRule
+	} else if game.turnsSinceCaptureOrPawnMove >= 100 && automaticDraws {
+		termination = FiftyMoveRule
+	} else if game.variant.insufficientMaterial(game) {
+		termination = InsufficientMaterial
+	}
+	if termination == Checkmate {
+		return GameResult{Winner: mover, Termination: termination}
+	}
+	return GameResult{
+		Draw: termination != NoTermination, Termination: termination,
+	}
+}
+
+// makeMove apply a valid move without checking whether it ends the game,
+// tracking the position for draw by repetition if requested. A drop's piece
+// is created here.
+func (game *Game) makeMove(
+	piece *Piece, moveRequest MoveRequest, trackRepetition bool,
+) (drawByRepetion bool) {
+	drop := moveRequest.IsDrop()
+	if drop {
+		piece = game.droppedPiece(moveRequest)
+	}
+	record := game.newMoveRecord(piece, moveRequest.Move)
+	hash := game.hash ^ game.zobristState()
+	if drop {
+		record.dropped = true
+		game.pockets[piece.color][piece.pieceType]--
+		game.board[piece.position.File][piece.position.Rank] = piece
+		game.pieceCounts(piece.color)[piece.pieceType]++
+	} else {
+		hash ^= zobristPiece(piece.color, piece.pieceType, piece.position)
+		_, capturedPiece, _, _ := piece.takeMoveUnsafe(game.board,
+			moveRequest.Move, game.previousMove, game.previousMover,
+			moveRequest.PromoteTo)
+		piece.movesTaken++
+		if capturedPiece != nil {
+			hash ^= zobristPiece(capturedPiece.color, capturedPiece.pieceType,
+				capturedPiece.position)
+		}
+		if rook := record.castledRook; rook != nil {
+			hash ^= zobristPiece(rook.color, Rook, record.castledRookPosition) ^
+				zobristPiece(rook.color, Rook, rook.position)
+		}
+		record.capturedPiece = capturedPiece
+	}
+	hash ^= zobristPiece(piece.color, piece.pieceType, piece.position)
+	game.handleCapturedPiece(piece, record.capturedPiece)
+	if piece.pieceType != record.pieceType {
+		game.pieceCounts(piece.color)[record.pieceType]--
+		game.pieceCounts(piece.color)[piece.pieceType]++
+		piece.promoted = true
+	}
+	if game.turnsSinceCaptureOrPawnMove != 0 {
+		// The history was kept so undoing only needs to forget this position.
+		record.positionHistory = nil
 	}
 	game.previousMove = moveRequest.Move
 	game.previousMover = piece
 	game.turn = getOppositeColor(piece.color)
-	return nil
+	if piece.color == Black {
+		game.fullMoveNumber++
+	}
+	game.hash = hash ^ game.zobristState()
+	game.variant.afterMove(game, &record)
+	if trackRepetition {
+		record.trackedRepetition = true
+		drawByRepetion = game.updatePositionHistory()
+	}
+	game.moveHistory = append(game.moveHistory, moveRequest.copy())
+	game.undoHistory = append(game.undoHistory, record)
+	return drawByRepetion
+}
+
+// LegalMoves get every legal move for the side to move, with one move request
+// per promotion option and per drop, or none once the game is over
+func (game *Game) LegalMoves() []MoveRequest {
+	game.mutex.RLock()
+	defer game.mutex.RUnlock()
+	if game.gameOver {
+		return []MoveRequest{}
+	}
+	return game.legalMoves()
+}
+
+// LegalMovesFrom get the legal moves of the piece on the position, with one
+// move request per promotion option
+func (game *Game) LegalMovesFrom(position Position) []MoveRequest {
+	moveRequests := []MoveRequest{}
+	for _, moveRequest := range game.LegalMoves() {
+		if !moveRequest.IsDrop() && moveRequest.Position == position {
+			moveRequests = append(moveRequests, moveRequest)
+		}
+	}
+	return moveRequests
+}
+
+// InCheck get whether the side to move is in check
+func (game *Game) InCheck() bool {
+	game.mutex.RLock()
+	defer game.mutex.RUnlock()
+	return game.isInCheck()
+}
+
+// IsCheckmate get whether the side to move is in check with no legal moves
+func (game *Game) IsCheckmate() bool {
+	game.mutex.RLock()
+	defer game.mutex.RUnlock()
+	return game.isInCheck() && len(game.legalMoves()) == 0
+}
+
+// IsStalemate get whether the side to move is not in check but has no legal
+// moves
+func (game *Game) IsStalemate() bool {
+	game.mutex.RLock()
+	defer game.mutex.RUnlock()
+	return !game.isInCheck() && len(game.legalMoves()) == 0
+}
+
+// legalMoves get every legal move for the side to move in the game's
+// variant, with one move request per promotion option
+func (game *Game) legalMoves() []MoveRequest {
+	return game.variant.legalMoves(game)
+}
+
+func (piece *Piece) moveRequests(move Move) []MoveRequest {
+	_, newY := addMoveToPosition(piece, move)
+	if piece.pieceType != Pawn || (newY != 0 && newY != 7) {
+		return []MoveRequest{{piece.position, move, nil}}
+	}
+	moveRequests := []MoveRequest{}
+	for _, pieceType := range []PieceType{Queen, Rook, Bishop, Knight} {
+		promoteTo := pieceType
+		moveRequests = append(moveRequests,
+			MoveRequest{piece.position, move, &promoteTo})
+	}
+	return moveRequests
+}
+
+// isInCheck get whether the side to move is in check
+func (game *Game) isInCheck() bool {
+	return game.variant.inCheck(game)
+}
+
+// isLegal get whether the move request is one of the legal moves
+func (game *Game) isLegal(moveRequest MoveRequest) bool {
+	for _, legalMove := range game.legalMoves() {
+		if legalMove.Position == moveRequest.Position &&
+			legalMove.Move == moveRequest.Move &&
+			promoteToEqual(legalMove.PromoteTo, moveRequest.PromoteTo) {
+			return true
+		}
+	}
+	return false
+}
+
+// Clone create a copy of the game that can be played on, for example by a
+// search, without changing the original. The copy's moves before the copy
+// cannot be undone.
+func (game *Game) Clone() *Game {
+	game.mutex.RLock()
+	defer game.mutex.RUnlock()
+	return game.clone()
+}
+
+// clone create a deep copy of the game that shares no pieces with the
+// original. The clone's moves before the copy cannot be undone.
+func (game *Game) clone() *Game {
+	var board Board
+	pieces := map[*Piece]*Piece{}
+	for file := range game.board {
+		for rank, piece := range game.board[file] {
+			if piece != nil {
+				pieceCopy := *piece
+				board[file][rank] = &pieceCopy
+				pieces[piece] = &pieceCopy
+			}
+		}
+	}
+	clone := &Game{
+		board: &board, turn: game.turn, gameOver: game.gameOver,
+		result: game.result, resultSet: game.resultSet,
+		previousMove:                game.previousMove,
+		previousMover:               pieces[game.previousMover],
+		blackKing:                   pieces[game.blackKing],
+		whiteKing:                   pieces[game.whiteKing],
+		whitePieces:                 make(map[PieceType]uint8),
+		blackPieces:                 make(map[PieceType]uint8),
+		positionHistory:             make(map[uint64]uint8),
+		hash:                        game.hash,
+		turnsSinceCaptureOrPawnMove: game.turnsSinceCaptureOrPawnMove,
+		fullMoveNumber:              game.fullMoveNumber,
+		drawRules:                   game.drawRules,
+		chess960:                    game.chess960,
+		variant:                     game.variant,
+		checks:                      game.checks,
+		pockets:                     game.pockets,
+		startFEN:                    game.startFEN,
+		moveHistory:                 append([]MoveRequest{}, game.moveHistory...),
+	}
+	for pieceType, count := range game.whitePieces {
+		clone.whitePieces[pieceType] = count
+	}
+	for pieceType, count := range game.blackPieces {
+		clone.blackPieces[pieceType] = count
+	}
+	for position, count := range game.positionHistory {
+		clone.positionHistory[position] = count
+	}
+	return clone
 }
 
 func (game *Game) handleCapturedPiece(piece *Piece, capturedPiece *Piece) {
@@ -86,12 +325,34 @@ func (game *Game) handleCapturedPiece(piece *Piece, capturedPiece *Piece) {
 		if capturedPiece == nil {
 			return
 		}
-		if capturedPiece.color == Black {
-			game.blackPieces[capturedPiece.pieceType]--
-		} else {
-			game.whitePieces[capturedPiece.pieceType]--
-		}
+		game.pieceCounts(capturedPiece.color)[capturedPiece.pieceType]--
+	}
+}
+
+// pieceCounts get the color's count of pieces by type
+func (game *Game) pieceCounts(color Color) map[PieceType]uint8 {
+	if color == Black {
+		return game.blackPieces
+	}
+	return game.whitePieces
+}
+
+// pieceCount get how many pieces the color has left
+func (game *Game) pieceCount(color Color) int {
+	count := 0
+	for _, n := range game.pieceCounts(color) {
+		count += int(n)
 	}
+	return count
+}
+
+// isCapture get whether the move request captures a piece, including en
+// passant
+func (game *Game) isCapture(moveRequest MoveRequest) bool {
+	piece := game.board.Piece(moveRequest.Position)
+	newX, newY := addMoveToPosition(piece, moveRequest.Move)
+	return game.board[newX][newY] != nil ||
+		(piece.pieceType == Pawn && moveRequest.Move.X != 0)
 }
 
 func (game *Game) isDrawByInsufficientMaterial() bool {
@@ -131,27 +392,33 @@ func (game *Game) isMoveRequestValid(piece *Piece) error {
 	return nil
 }
 
+// getKings get the side to move's king and the enemy king, or nil for a
+// king that is not on the board, as in Horde or once captured in a variant
 func (game *Game) getKings() (king, enemyKing *Piece) {
-	king = game.blackKing
-	enemyKing = game.whiteKing
+	king = game.onBoard(game.blackKing)
+	enemyKing = game.onBoard(game.whiteKing)
 	if game.turn == White {
-		king = game.whiteKing
-		enemyKing = game.blackKing
+		king, enemyKing = enemyKing, king
 	}
 	return
 }
 
-func (game *Game) updatePositionHistory() (bool, error) {
-	position, err := game.MarshalBinary()
-	if err != nil {
-		return false, err
+func (game *Game) onBoard(piece *Piece) *Piece {
+	if piece == nil || game.board.Piece(piece.position) != piece {
+		return nil
 	}
-	game.positionHistory[string(position)]++
-	return game.positionHistory[string(position)] > 2, nil
+	return piece
+}
+
+// updatePositionHistory count the current position, returning whether it
+// has now occurred three times
+func (game *Game) updatePositionHistory() bool {
+	game.positionHistory[game.hash]++
+	return game.positionHistory[game.hash] > 2
 }
 
 func (game *Game) clearPositionHistory() {
-	game.positionHistory = make(map[string]uint8)
+	game.positionHistory = make(map[uint64]uint8)
 }
 
 // MarshalBinary represent the game as a byte array
@@ -205,25 +472,44 @@ func NewGameNoPawns() *Game {
 }
 
 func createGame(board Board) *Game {
+	game := newGameFromBoard(board)
+	game.turn = White
+	game.startFEN = game.fen()
+	game.hash = game.zobristHash()
+	game.updatePositionHistory()
+	return game
+}
+
+// newGameFromBoard create a game around the board, counting its pieces and
+// locating its kings. The caller is responsible for the remaining state.
+func newGameFromBoard(board Board) *Game {
 	game := Game{
-		board: &board, blackKing: board[4][7], whiteKing: board[4][0],
-		positionHistory: make(map[string]uint8),
+		board:           &board,
+		positionHistory: make(map[uint64]uint8),
 		blackPieces:     make(map[PieceType]uint8),
 		whitePieces:     make(map[PieceType]uint8),
+		fullMoveNumber:  1,
+		variant:         Standard,
 	}
 	for _, file := range board {
 		for _, piece := range file {
-			if piece != nil {
+			if piece == nil {
+				continue
+			}
+			if piece.color == Black {
+				game.blackPieces[piece.pieceType]++
+			} else {
+				game.whitePieces[piece.pieceType]++
+			}
+			if piece.pieceType == King {
 				if piece.color == Black {
-					game.blackPieces[piece.pieceType]++
+					game.blackKing = piece
 				} else {
-					game.whitePieces[piece.pieceType]++
+					game.whiteKing = piece
 				}
 			}
 		}
 	}
-	game.updatePositionHistory()
-	game.turn = White
 	return &game
 }
 
@@ -274,13 +560,33 @@ func (game *Game) GameOver() bool {
 	return game.gameOver
 }
 
-// SetGameResult set the game's result
-func (game *Game) SetGameResult(winner Color, draw bool) {
+// StartFEN get the game's starting position in Forsyth-Edwards Notation
+func (game *Game) StartFEN() string {
+	game.mutex.RLock()
+	defer game.mutex.RUnlock()
+	return game.startFEN
+}
+
+// MoveHistory get every move played in the game so far
+func (game *Game) MoveHistory() []MoveRequest {
+	game.mutex.RLock()
+	defer game.mutex.RUnlock()
+	moveHistory := make([]MoveRequest, len(game.moveHistory))
+	for i, moveRequest := range game.moveHistory {
+		moveHistory[i] = moveRequest.copy()
+	}
+	return moveHistory
+}
+
+// SetGameResult end the game with the given result
+func (game *Game) SetGameResult(
+	winner Color, draw bool, termination Termination,
+) {
 	game.mutex.Lock()
 	defer game.mutex.Unlock()
 	game.gameOver = true
-	game.result.Winner = winner
-	game.result.Draw = draw
+	game.result = GameResult{winner, draw, termination}
+	game.resultSet = true
 }
 
 // Result get the game's result
@@ -290,6 +596,19 @@ func (game *Game) Result() GameResult {
 	return game.result
 }
 
+// copy the move request so that it does not share its promotion type
+func (mr MoveRequest) copy() MoveRequest {
+	if mr.PromoteTo != nil {
+		promoteTo := *mr.PromoteTo
+		mr.PromoteTo = &promoteTo
+	}
+	return mr
+}
+
 func (mr MoveRequest) String() string {
+	if mr.IsDrop() {
+		return "Drop: " + dropLetter(*mr.PromoteTo) + ", Position: " +
+			mr.Position.String()
+	}
 	return "Position: " + mr.Position.String() + ", Move: " + mr.Move.String()
 }
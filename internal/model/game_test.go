@@ -88,7 +88,8 @@ func TestCheckMate(t *testing.T) {
 	if debug {
 		fmt.Println(game.board)
 	}
-	if game.gameOver == false || game.result.Winner != White {
+	if game.gameOver == false || game.result.Winner != White ||
+		game.result.Termination != Checkmate {
 		t.Error("Game should be over")
 	}
 }
@@ -119,7 +120,8 @@ func TestCheckMate2(t *testing.T) {
 	if debug {
 		fmt.Println(game.board)
 	}
-	if game.gameOver == false || game.result.Winner != Black {
+	if game.gameOver == false || game.result.Winner != Black ||
+		game.result.Termination != Checkmate {
 		t.Error("Game should be over")
 	}
 }
@@ -145,7 +147,8 @@ func TestStalemate(t *testing.T) {
 	if debug {
 		fmt.Println(game.board)
 	}
-	if game.gameOver == false || game.result.Draw != true {
+	if game.gameOver == false || game.result.Draw != true ||
+		game.result.Termination != Stalemate {
 		t.Error("Game should be a draw")
 	}
 }
@@ -314,7 +317,8 @@ func TestDrawByInsufficientMaterial(t *testing.T) {
 	if debug {
 		fmt.Println(game.board)
 	}
-	if !game.gameOver || !game.result.Draw {
+	if !game.gameOver || !game.result.Draw ||
+		game.result.Termination != InsufficientMaterial {
 		t.Error("Game should be a draw")
 	}
 }
@@ -351,7 +355,8 @@ func TestDrawByInsufficientMaterialOneMinor(t *testing.T) {
 	if debug {
 		fmt.Println(game.board)
 	}
-	if !game.gameOver || !game.result.Draw {
+	if !game.gameOver || !game.result.Draw ||
+		game.result.Termination != InsufficientMaterial {
 		t.Error("Game should be a draw")
 	}
 }
@@ -373,11 +378,16 @@ func TestDrawByRepetion(t *testing.T) {
 		game.Move(MoveRequest{Position{3, 7}, Move{0, -1}, nil})
 		game.Move(MoveRequest{Position{4, 1}, Move{-1, -1}, nil})
 		err := game.Move(MoveRequest{Position{3, 6}, Move{0, 1}, nil})
-		if err != nil {
+		if err != nil || game.gameOver {
 			t.Error("Draw too early")
 		}
 	}
-	if !game.gameOver || !game.result.Draw {
+	// The position after d5 allowed en passant so it differs from the one
+	// reached after the queens return, and the first repetition to occur
+	// three times is the one after Qe2.
+	game.Move(MoveRequest{Position{3, 0}, Move{1, 1}, nil})
+	if !game.gameOver || !game.result.Draw ||
+		game.result.Termination != ThreefoldRepetition {
 		t.Error("Game should be a draw")
 	}
 }
@@ -458,7 +468,70 @@ func TestDrawByFiftyMoveRule(t *testing.T) {
 	game.Move(MoveRequest{Position{1, 7}, Move{-1, -2}, nil})
 	game.Move(MoveRequest{Position{0, 2}, Move{1, -2}, nil})
 	game.Move(MoveRequest{Position{0, 5}, Move{1, 2}, nil})
-	if !game.gameOver || !game.result.Draw {
+	if !game.gameOver || !game.result.Draw ||
+		game.result.Termination != FiftyMoveRule {
 		t.Error("Game should be a draw")
 	}
 }
+
+func TestDrawBySeventyFiveMoveRule(t *testing.T) {
+	game, _ := NewGameFromFEN("4k3/8/8/8/8/8/8/R3K3 w - - 149 100")
+	game.Move(MoveRequest{Position{0, 0}, Move{0, 1}, nil})
+	result := game.Result()
+	if !game.GameOver() || !result.Draw ||
+		result.Termination != SeventyFiveMoveRule {
+		t.Error("Expected ", SeventyFiveMoveRule, " got ", result.Termination)
+	}
+}
+
+func TestLegalMoves(t *testing.T) {
+	game := NewGame()
+	if moves := game.LegalMoves(); len(moves) != 20 {
+		t.Error("Expected 20 opening moves got ", len(moves))
+	}
+	if moves := game.LegalMovesFrom(Position{6, 0}); len(moves) != 2 {
+		t.Error("Expected two knight moves got ", moves)
+	}
+	game, _ = NewGameFromFEN("4k3/1P6/8/8/8/8/8/4K3 w - - 0 1")
+	if moves := game.LegalMovesFrom(Position{1, 6}); len(moves) != 4 {
+		t.Error("Expected four promotions got ", moves)
+	}
+	if moves := game.LegalMovesFrom(Position{0, 0}); len(moves) != 0 {
+		t.Error("Expected no moves from an empty square got ", moves)
+	}
+}
+
+func TestCheckmateAndStalemate(t *testing.T) {
+	game, _ := NewGameFromFEN("7k/6Q1/6K1/8/8/8/8/8 b - - 0 1")
+	if !game.InCheck() || !game.IsCheckmate() || game.IsStalemate() {
+		t.Error("Expected checkmate got ", game.FEN())
+	}
+	game, _ = NewGameFromFEN("7k/8/6QK/8/8/8/8/8 b - - 0 1")
+	if game.InCheck() || game.IsCheckmate() || !game.IsStalemate() {
+		t.Error("Expected stalemate got ", game.FEN())
+	}
+	game, _ = NewGameFromFEN("4k3/8/8/8/8/8/8/R3K3 b - - 0 1")
+	if game.IsCheckmate() || game.IsStalemate() {
+		t.Error("Expected the game to go on got ", game.FEN())
+	}
+}
+
+func TestClone(t *testing.T) {
+	game := NewGame()
+	game.MoveUCI("e2e4")
+	clone := game.Clone()
+	if err := clone.MoveUCI("e7e5"); err != nil {
+		t.Fatal(err)
+	}
+	if game.Turn() != Black || game.Hash() == clone.Hash() ||
+		len(game.MoveHistory()) != 1 {
+		t.Error("Expected the original to be unchanged got ", game.FEN())
+	}
+	clone.Undo()
+	if clone.FEN() != game.FEN() || clone.Hash() != game.Hash() {
+		t.Error("Expected the clone to undo its own move got ", clone.FEN())
+	}
+	if clone.Undo() == nil {
+		t.Error("Expected the moves before the clone not to be undone")
+	}
+}
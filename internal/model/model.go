@@ -58,15 +58,15 @@ func newBoardNoPawns() Board {
 }
 
 func createTheBackLine(board *Board) {
-	for i := uint8(0); i < 4; i++ {
-		board[i][7] = NewPiece(PieceType(i), NewPosition(uint8(i), 7), Black)
-		board[i][0] = NewPiece(PieceType(i), NewPosition(uint8(i), 0), White)
-		pieceType := PieceType(i)
-		if i == 3 {
-			pieceType = PieceType(i + 1)
-		}
-		board[7-i][7] = NewPiece(pieceType, NewPosition(uint8(7-i), 7), Black)
-		board[7-i][0] = NewPiece(pieceType, NewPosition(uint8(7-i), 0), White)
+	createBackLine(board, [8]PieceType{
+		Rook, Knight, Bishop, Queen, King, Bishop, Knight, Rook,
+	})
+}
+
+func createBackLine(board *Board, backLine [8]PieceType) {
+	for file, pieceType := range backLine {
+		board[file][7] = NewPiece(pieceType, NewPosition(uint8(file), 7), Black)
+		board[file][0] = NewPiece(pieceType, NewPosition(uint8(file), 0), White)
 	}
 }
 
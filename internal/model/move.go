@@ -83,12 +83,13 @@ func (piece *Piece) takeMoveUnsafe(
 		(previousMove.Y == 2 || previousMove.Y == -2) &&
 		piece.Rank() == enPassantTargetY &&
 		piece.Color() != enPassantTarget.Color())
-	isCastle := piece.pieceType == King && (move.X < -1 || move.X > 1)
+	castledRook = piece.castlingRook(board, move)
 	if isEnPassant {
 		capturedPiece = board[enPassantTarget.File()][enPassantTarget.Rank()]
 		board[enPassantTarget.File()][enPassantTarget.Rank()] = nil
-	} else if isCastle {
-		castledRook, newCastledPosition = piece.handleCastle(board, move)
+	} else if castledRook != nil {
+		newPosition, newCastledPosition = piece.handleCastle(board, castledRook)
+		return newPosition, nil, newCastledPosition, castledRook
 	}
 	if board[newX][newY] != nil {
 		capturedPiece = board[newX][newY]
@@ -324,22 +325,62 @@ func (piece *Piece) Moves(
 	return positions
 }
 
+// handleCastle move the king and rook to their castled files, the king to
+// the c or g file and the rook next to it, which in Chess960 may swap them or
+// leave either in place
 func (piece *Piece) handleCastle(
-	board *Board, move Move,
-) (castledRook *Piece, newCastledPosition Position) {
-	if move.X < 0 {
-		board[3][piece.Rank()] = board[0][piece.Rank()]
-		board[0][piece.Rank()] = nil
-		castledRook = board[3][piece.Rank()]
-		newCastledPosition = Position{3, piece.Rank()}
-	} else {
-		board[5][piece.Rank()] = board[7][piece.Rank()]
-		board[7][piece.Rank()] = nil
-		castledRook = board[5][piece.Rank()]
-		newCastledPosition = Position{5, piece.Rank()}
-	}
+	board *Board, castledRook *Piece,
+) (newPosition Position, newCastledPosition Position) {
+	rank := piece.Rank()
+	newPosition, newCastledPosition = Position{6, rank}, Position{5, rank}
+	if castledRook.File() < piece.File() {
+		newPosition, newCastledPosition = Position{2, rank}, Position{3, rank}
+	}
+	board[piece.File()][rank] = nil
+	board[castledRook.File()][rank] = nil
+	board[newPosition.File][rank] = piece
+	board[newCastledPosition.File][rank] = castledRook
+	piece.position = newPosition
 	castledRook.position = newCastledPosition
-	return castledRook, newCastledPosition
+	return newPosition, newCastledPosition
+}
+
+// castlingRook get the rook a king move castles with, or nil if the move is
+// not a castle. A castle is the king moving onto its own rook, as in
+// Chess960, or two files towards it as in standard chess.
+func (piece *Piece) castlingRook(board *Board, move Move) *Piece {
+	if piece.pieceType != King || move.Y != 0 {
+		return nil
+	}
+	left, right := piece.castlingRooks(board)
+	newX, _ := addMoveToPosition(piece, move)
+	if left != nil && (left.File() == newX || move.X == -2) {
+		return left
+	} else if right != nil && (right.File() == newX || move.X == 2) {
+		return right
+	}
+	return nil
+}
+
+// castlingRooks get the unmoved rooks on either side of an unmoved king,
+// which are the ones it may castle with
+func (piece *Piece) castlingRooks(board *Board) (left, right *Piece) {
+	if piece.pieceType != King || piece.movesTaken > 0 {
+		return nil, nil
+	}
+	for file := uint8(0); file < 8; file++ {
+		rook := board[file][piece.Rank()]
+		if rook == nil || rook.pieceType != Rook ||
+			rook.color != piece.color || rook.movesTaken != 0 {
+			continue
+		}
+		if file < piece.File() && left == nil {
+			left = rook
+		} else if file > piece.File() {
+			right = rook
+		}
+	}
+	return left, right
 }
 
 func (piece *Piece) canCastle(
@@ -365,21 +406,8 @@ func (piece *Piece) canCastle(
 }
 
 func (piece *Piece) hasCastleRights(board *Board) (castleLeft, castleRight bool) {
-	if piece.pieceType != King || piece.movesTaken > 0 {
-		return false, false
-	}
-	rookPieces := [2]*Piece{board[0][piece.Rank()], board[7][piece.Rank()]}
-	castleLeft, castleRight = true, true
-	for i, rook := range rookPieces {
-		if rook == nil || rook.pieceType != Rook || rook.movesTaken != 0 {
-			if i == 0 {
-				castleLeft = false
-			} else {
-				castleRight = false
-			}
-		}
-	}
-	return castleLeft, castleRight
+	left, right := piece.castlingRooks(board)
+	return left != nil, right != nil
 }
 
 func (piece *Piece) noPiecesBlockingCastle(board *Board) (left, right bool) {
@@ -447,16 +475,6 @@ func (piece *Piece) wouldBeInCheck(
 func (piece *Piece) isThreatened(board *Board, previousMove Move,
 	previousMover *Piece,
 ) bool {
-	enemyColor := Black
-	if piece.color == Black {
-		enemyColor = White
-	}
-	threatenedPositions := AllMoves(
-		board, enemyColor, previousMove, previousMover, true, nil,
-	)
-	inCheck := false
-	if threatenedPositions[piece.position] {
-		inCheck = true
-	}
-	return inCheck
+	b := newBitboards(board)
+	return b.isAttacked(square(piece.position), getOppositeColor(piece.color))
 }
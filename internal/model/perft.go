@@ -0,0 +1,48 @@
+package model
+
+// Perft count the leaf nodes of the legal move tree to the given depth. It
+// is used to verify move generation against known node counts, so moves are
+// made without checking whether they end the game.
+func (game *Game) Perft(depth int) uint64 {
+	game.mutex.RLock()
+	clone := game.clone()
+	game.mutex.RUnlock()
+	return clone.perft(depth)
+}
+
+// Divide count the leaf nodes to the given depth below each legal move,
+// keyed by the move in long algebraic notation
+func (game *Game) Divide(depth int) map[string]uint64 {
+	game.mutex.RLock()
+	clone := game.clone()
+	game.mutex.RUnlock()
+	out := map[string]uint64{}
+	if depth < 1 {
+		return out
+	}
+	for _, moveRequest := range clone.legalMoves() {
+		clone.makeMove(clone.board.Piece(moveRequest.Position), moveRequest,
+			false)
+		out[moveRequest.UCI()] = clone.perft(depth - 1)
+		clone.undo()
+	}
+	return out
+}
+
+func (game *Game) perft(depth int) uint64 {
+	if depth < 1 {
+		return 1
+	}
+	moveRequests := game.legalMoves()
+	if depth == 1 {
+		return uint64(len(moveRequests))
+	}
+	nodes := uint64(0)
+	for _, moveRequest := range moveRequests {
+		game.makeMove(game.board.Piece(moveRequest.Position), moveRequest,
+			false)
+		nodes += game.perft(depth - 1)
+		game.undo()
+	}
+	return nodes
+}
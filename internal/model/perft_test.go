@@ -0,0 +1,65 @@
+package model
+
+import (
+	"testing"
+)
+
+// Known node counts from https://www.chessprogramming.org/Perft_Results
+var perftPositions = []struct {
+	name  string
+	fen   string
+	nodes []uint64
+}{
+	{"start position", StartingFEN,
+		[]uint64{20, 400, 8902, 197281}},
+	{"kiwipete",
+		"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+		[]uint64{48, 2039, 97862, 4085603}},
+	{"position 3", "8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1",
+		[]uint64{14, 191, 2812, 43238, 674624}},
+	{"position 4",
+		"r3k2r/Pppp1ppp/1b3nbN/nP6/BBP1P3/q4N2/Pp1P2PP/R2Q1RK1 w kq - 0 1",
+		[]uint64{6, 264, 9467, 422333}},
+	{"position 5",
+		"rnbq1k1r/pp1Pbppp/2p5/8/2B5/8/PPP1NnPP/RNBQK2R w KQ - 1 8",
+		[]uint64{44, 1486, 62379, 2103487}},
+	{"position 6", "r4rk1/1pp1qppp/p1np1n2/2b1p1B1/2B1P1b1/P1NP1N2/" +
+		"1PP1QPPP/R4RK1 w - - 0 10",
+		[]uint64{46, 2079, 89890, 3894594}},
+}
+
+func TestPerft(t *testing.T) {
+	for _, position := range perftPositions {
+		game, err := NewGameFromFEN(position.fen)
+		if err != nil {
+			t.Fatal(position.name, err)
+		}
+		for i, expected := range position.nodes {
+			depth := i + 1
+			if testing.Short() && expected > 100000 {
+				break
+			}
+			if nodes := game.Perft(depth); nodes != expected {
+				t.Error("Expected ", position.name, " perft(", depth, ") = ",
+					expected, " got ", nodes)
+			}
+		}
+		if game.FEN() != position.fen {
+			t.Error("Expected perft to leave the game unchanged got ",
+				game.FEN())
+		}
+	}
+}
+
+func TestDivide(t *testing.T) {
+	game, _ := NewGameFromFEN(perftPositions[1].fen)
+	divide := game.Divide(2)
+	total := uint64(0)
+	for _, nodes := range divide {
+		total += nodes
+	}
+	if len(divide) != 48 || total != 2039 || divide["e1g1"] != 43 ||
+		divide["e5f7"] != 44 {
+		t.Error("Expected kiwipete divide got ", divide)
+	}
+}
@@ -12,6 +12,9 @@ type (
 		position   Position
 		color      Color
 		movesTaken uint16
+		// promoted is whether the piece was a pawn, which it reverts to when
+		// captured into a Crazyhouse pocket.
+		promoted bool
 	}
 
 	// PieceType the various piece types
@@ -20,7 +23,7 @@ type (
 
 // NewPiece return a new piece
 func NewPiece(pieceType PieceType, position Position, color Color) *Piece {
-	return &Piece{pieceType, position, color, uint16(0)}
+	return &Piece{pieceType, position, color, uint16(0), false}
 }
 
 const (
@@ -0,0 +1,203 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	sanPieceLetters = map[PieceType]string{
+		Rook: "R", Knight: "N", Bishop: "B", Queen: "Q", King: "K",
+	}
+
+	sanRegexp = regexp.MustCompile(
+		`^([KQRBN])?([a-h])?([1-8])?(x)?([a-h][1-8])(=?([QRBNK]))?$`)
+	sanDropRegexp = regexp.MustCompile(`^([QRBNP])?@([a-h][1-8])$`)
+)
+
+// SAN get the move request in Standard Algebraic Notation, for example "Nxe5+"
+// or the drop "N@f3". The move request must be legal in the game's current
+// position.
+func (game *Game) SAN(moveRequest MoveRequest) (string, error) {
+	game.mutex.RLock()
+	// Work on a copy since checking for legality temporarily moves pieces.
+	clone := game.clone()
+	game.mutex.RUnlock()
+	return clone.san(moveRequest)
+}
+
+func (game *Game) san(moveRequest MoveRequest) (string, error) {
+	var out string
+	var err error
+	if moveRequest.IsDrop() {
+		out, err = game.sanDrop(moveRequest)
+	} else {
+		out, err = game.sanMove(moveRequest)
+	}
+	if err != nil {
+		return "", err
+	}
+	if err := game.move(moveRequest); err != nil {
+		return "", err
+	}
+	if game.gameOver && game.result.Termination == Checkmate {
+		out += "#"
+	} else if game.isInCheck() {
+		out += "+"
+	}
+	return out, nil
+}
+
+// sanDrop get a drop's notation without its check suffix
+func (game *Game) sanDrop(moveRequest MoveRequest) (string, error) {
+	if !game.isLegal(moveRequest) {
+		return "", errors.New("illegal move " + moveRequest.String())
+	}
+	return dropLetter(*moveRequest.PromoteTo) + "@" +
+		moveRequest.Position.algebraic(), nil
+}
+
+// sanMove get a move's notation without its check suffix
+func (game *Game) sanMove(moveRequest MoveRequest) (string, error) {
+	piece := game.board.Piece(moveRequest.Position)
+	if err := game.isMoveRequestValid(piece); err != nil {
+		return "", err
+	}
+	newX, newY := addMoveToPosition(piece, moveRequest.Move)
+	destination := Position{newX, newY}
+	found := false
+	ambiguousFile, ambiguousRank, ambiguous := false, false, false
+	for _, legalMove := range game.legalMoves() {
+		if legalMove.IsDrop() {
+			continue
+		}
+		legalX, legalY := addMoveToPosition(
+			game.board.Piece(legalMove.Position), legalMove.Move)
+		if legalX != newX || legalY != newY {
+			continue
+		} else if legalMove.Position == moveRequest.Position {
+			found = found || promoteToEqual(legalMove.PromoteTo,
+				moveRequest.PromoteTo)
+		} else if game.board.Piece(legalMove.Position).pieceType ==
+			piece.pieceType {
+			ambiguous = true
+			ambiguousFile = ambiguousFile ||
+				legalMove.Position.File == moveRequest.Position.File
+			ambiguousRank = ambiguousRank ||
+				legalMove.Position.Rank == moveRequest.Position.Rank
+		}
+	}
+	if !found {
+		return "", errors.New("illegal move " + moveRequest.String())
+	}
+	isCapture := game.isCapture(moveRequest)
+	castlingRook := piece.castlingRook(game.board, moveRequest.Move)
+	out := ""
+	switch {
+	case castlingRook != nil && castlingRook.File() > piece.File():
+		out = "O-O"
+	case castlingRook != nil:
+		out = "O-O-O"
+	case piece.pieceType == Pawn:
+		if isCapture {
+			out = piece.position.algebraic()[:1] + "x"
+		}
+		out += destination.algebraic()
+		if moveRequest.PromoteTo != nil {
+			out += "=" + sanPieceLetters[*moveRequest.PromoteTo]
+		}
+	default:
+		out = sanPieceLetters[piece.pieceType]
+		if ambiguous && (!ambiguousFile || ambiguousRank) {
+			out += piece.position.algebraic()[:1]
+		}
+		if ambiguousFile {
+			out += piece.position.algebraic()[1:]
+		}
+		if isCapture {
+			out += "x"
+		}
+		out += destination.algebraic()
+	}
+	return out, nil
+}
+
+// ParseSAN get the move request for a move in Standard Algebraic Notation,
+// for example "exd8=Q+" or the drop "N@f3". The move must be legal in the
+// game's current position.
+func (game *Game) ParseSAN(san string) (MoveRequest, error) {
+	game.mutex.RLock()
+	clone := game.clone()
+	game.mutex.RUnlock()
+	return clone.parseSAN(san)
+}
+
+func (game *Game) parseSAN(san string) (MoveRequest, error) {
+	notation := strings.TrimRight(strings.TrimSpace(san), "+#!?")
+	if matches := sanDropRegexp.FindStringSubmatch(notation); matches != nil {
+		return game.parseDrop(san, matches[1], matches[2])
+	}
+	castle := 0
+	switch notation {
+	case "O-O", "0-0":
+		castle = 1
+	case "O-O-O", "0-0-0":
+		castle = -1
+	}
+	var matches []string
+	if castle == 0 {
+		matches = sanRegexp.FindStringSubmatch(notation)
+		if matches == nil {
+			return MoveRequest{}, fmt.Errorf("invalid move %q", san)
+		}
+	}
+	candidates := []MoveRequest{}
+	for _, legalMove := range game.legalMoves() {
+		if legalMove.IsDrop() {
+			continue
+		}
+		piece := game.board.Piece(legalMove.Position)
+		castlingRook := piece.castlingRook(game.board, legalMove.Move)
+		isCastle := castlingRook != nil
+		if castle != 0 {
+			if isCastle &&
+				sign(int(castlingRook.File())-int(piece.File())) == castle {
+				candidates = append(candidates, legalMove)
+			}
+		} else if !isCastle && sanMatches(piece, legalMove, matches) {
+			candidates = append(candidates, legalMove)
+		}
+	}
+	if len(candidates) == 0 {
+		return MoveRequest{}, fmt.Errorf("illegal move %q", san)
+	} else if len(candidates) > 1 {
+		return MoveRequest{}, fmt.Errorf("ambiguous move %q", san)
+	}
+	return candidates[0], nil
+}
+
+func sanMatches(piece *Piece, moveRequest MoveRequest, matches []string) bool {
+	pieceLetter, file, rank := matches[1], matches[2], matches[3]
+	destination, promoteTo := matches[5], matches[7]
+	if sanPieceLetters[piece.pieceType] != pieceLetter {
+		return false
+	}
+	square := piece.position.algebraic()
+	if (file != "" && file != square[:1]) || (rank != "" && rank != square[1:]) {
+		return false
+	}
+	newX, newY := addMoveToPosition(piece, moveRequest.Move)
+	if (Position{newX, newY}).algebraic() != destination {
+		return false
+	}
+	if moveRequest.PromoteTo == nil {
+		return promoteTo == ""
+	}
+	return sanPieceLetters[*moveRequest.PromoteTo] == promoteTo
+}
+
+func promoteToEqual(a, b *PieceType) bool {
+	return (a == nil && b == nil) || (a != nil && b != nil && *a == *b)
+}
@@ -0,0 +1,85 @@
+package model
+
+import (
+	"testing"
+)
+
+func TestSAN(t *testing.T) {
+	game, _ := NewGameFromFEN(
+		"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1")
+	tests := map[string]MoveRequest{
+		"O-O":   {Position{4, 0}, Move{2, 0}, nil},
+		"O-O-O": {Position{4, 0}, Move{-2, 0}, nil},
+		"Nxf7":  {Position{4, 4}, Move{1, 2}, nil},
+		"Nb5":   {Position{2, 2}, Move{-1, 2}, nil},
+		"Qxf6":  {Position{5, 2}, Move{0, 3}, nil},
+		"dxe6":  {Position{3, 4}, Move{1, 1}, nil},
+		"gxh3":  {Position{6, 1}, Move{1, 1}, nil},
+		"Bxa6":  {Position{4, 1}, Move{-4, 4}, nil},
+	}
+	for expected, moveRequest := range tests {
+		san, err := game.SAN(moveRequest)
+		if err != nil || san != expected {
+			t.Error("Expected ", expected, " got ", san, err)
+		}
+		parsed, err := game.ParseSAN(expected)
+		if err != nil || parsed.Position != moveRequest.Position ||
+			parsed.Move != moveRequest.Move {
+			t.Error("Expected to parse ", expected, " got ", parsed, err)
+		}
+	}
+}
+
+func TestSANDisambiguation(t *testing.T) {
+	game, _ := NewGameFromFEN("4k3/8/8/8/8/8/8/R4RK1 w - - 0 1")
+	san, _ := game.SAN(MoveRequest{Position{0, 0}, Move{3, 0}, nil})
+	if san != "Rad1" {
+		t.Error("Expected Rad1 got ", san)
+	}
+	game, _ = NewGameFromFEN("R7/8/8/4k3/8/8/8/R3K3 w - - 0 1")
+	san, _ = game.SAN(MoveRequest{Position{0, 0}, Move{0, 3}, nil})
+	if san != "R1a4" {
+		t.Error("Expected R1a4 got ", san)
+	}
+	if _, err := game.ParseSAN("Ra4"); err == nil {
+		t.Error("Expected Ra4 to be ambiguous")
+	}
+}
+
+func TestSANCheckAndPromotion(t *testing.T) {
+	game, _ := NewGameFromFEN("4k3/1P6/8/8/8/8/8/4K3 w - - 0 1")
+	queen, knight := Queen, Knight
+	san, _ := game.SAN(MoveRequest{Position{1, 6}, Move{0, 1}, &queen})
+	if san != "b8=Q+" {
+		t.Error("Expected b8=Q+ got ", san)
+	}
+	san, _ = game.SAN(MoveRequest{Position{1, 6}, Move{0, 1}, &knight})
+	if san != "b8=N" {
+		t.Error("Expected b8=N got ", san)
+	}
+	game = NewGame()
+	for _, san := range []string{"f3", "e5", "g4"} {
+		if err := game.MoveSAN(san); err != nil {
+			t.Fatal(err)
+		}
+	}
+	san, _ = game.SAN(MoveRequest{Position{3, 7}, Move{4, -4}, nil})
+	if san != "Qh4#" {
+		t.Error("Expected Qh4# got ", san)
+	}
+	if err := game.MoveSAN("Qh4#"); err != nil || !game.GameOver() {
+		t.Error("Expected checkmate got ", err)
+	}
+}
+
+func TestSANInvalid(t *testing.T) {
+	game := NewGame()
+	for _, san := range []string{"", "e5", "Ke2", "Nf4", "Zz9", "O-O"} {
+		if err := game.MoveSAN(san); err == nil {
+			t.Error("Expected invalid move ", san)
+		}
+	}
+	if len(game.MoveHistory()) != 0 {
+		t.Error("Expected no moves got ", game.MoveHistory())
+	}
+}
@@ -0,0 +1,61 @@
+package model
+
+// Termination is the reason a game ended
+type Termination uint8
+
+const (
+	// NoTermination the game has not ended
+	NoTermination = Termination(iota)
+	// Checkmate the side to move is in check and has no legal move
+	Checkmate
+	// Stalemate the side to move is not in check and has no legal move
+	Stalemate
+	// ThreefoldRepetition the same position occurred three times
+	ThreefoldRepetition
+	// FivefoldRepetition the same position occurred five times
+	FivefoldRepetition
+	// FiftyMoveRule fifty moves passed without a capture or pawn move
+	FiftyMoveRule
+	// SeventyFiveMoveRule seventy-five moves passed without a capture or pawn
+	// move
+	SeventyFiveMoveRule
+	// InsufficientMaterial neither side has enough material to checkmate
+	InsufficientMaterial
+	// Timeout a player ran out of time
+	Timeout
+	// TimeoutVsInsufficientMaterial a player ran out of time but their
+	// opponent does not have enough material to checkmate
+	TimeoutVsInsufficientMaterial
+	// Resignation a player resigned
+	Resignation
+	// Agreement both players agreed to a draw
+	Agreement
+	// Abandonment a player left the game
+	Abandonment
+	// Aborted the game was called off before it got going and has no result
+	Aborted
+	// KingInTheCenter a king reached the center in King of the Hill
+	KingInTheCenter
+	// ThreeChecks a king was checked for the third time in Three-check
+	ThreeChecks
+	// KingExploded a king was caught in an explosion in Atomic
+	KingExploded
+	// NoPiecesLeft a player has no pieces left, which wins Antichess and
+	// loses Horde
+	NoPiecesLeft
+)
+
+var terminationNames = [...]string{
+	"none", "checkmate", "stalemate", "threefold repetition",
+	"fivefold repetition", "fifty-move rule", "seventy-five-move rule",
+	"insufficient material", "timeout", "timeout vs insufficient material",
+	"resignation", "agreement", "abandonment", "abort", "king in the center",
+	"three checks", "king exploded", "no pieces left",
+}
+
+func (termination Termination) String() string {
+	if int(termination) < len(terminationNames) {
+		return terminationNames[termination]
+	}
+	return "unknown"
+}
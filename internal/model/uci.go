@@ -0,0 +1,100 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+)
+
+var uciPromotionLetters = map[PieceType]string{
+	Rook: "r", Knight: "n", Bishop: "b", Queen: "q", King: "k",
+}
+
+// UCI get the move request in long algebraic notation as used by the
+// Universal Chess Interface, for example "e7e8q" or the drop "N@f3"
+func (mr MoveRequest) UCI() string {
+	if mr.IsDrop() {
+		return dropLetter(*mr.PromoteTo) + "@" + mr.Position.algebraic()
+	}
+	newX := int8(mr.Position.File) + mr.Move.X
+	newY := int8(mr.Position.Rank) + mr.Move.Y
+	out := mr.Position.algebraic() +
+		Position{uint8(newX), uint8(newY)}.algebraic()
+	if mr.PromoteTo != nil {
+		out += uciPromotionLetters[*mr.PromoteTo]
+	}
+	return out
+}
+
+// ParseUCI get the move request for a move in long algebraic notation, for
+// example "e7e8q" or the drop "N@f3". The move must be legal in the game's
+// current position.
+func (game *Game) ParseUCI(uci string) (MoveRequest, error) {
+	game.mutex.RLock()
+	clone := game.clone()
+	game.mutex.RUnlock()
+	return clone.parseUCI(uci)
+}
+
+func (game *Game) parseUCI(uci string) (MoveRequest, error) {
+	notation := strings.ToLower(strings.TrimSpace(uci))
+	if len(notation) == 4 && notation[1] == '@' {
+		return game.parseDrop(uci, strings.ToUpper(notation[:1]), notation[2:])
+	}
+	if len(notation) != 4 && len(notation) != 5 {
+		return MoveRequest{}, fmt.Errorf("invalid move %q", uci)
+	}
+	from, err := parseAlgebraic(notation[:2])
+	if err != nil {
+		return MoveRequest{}, fmt.Errorf("invalid move %q", uci)
+	}
+	to, err := parseAlgebraic(notation[2:4])
+	if err != nil {
+		return MoveRequest{}, fmt.Errorf("invalid move %q", uci)
+	}
+	moveRequest := MoveRequest{from, Move{
+		int8(to.File) - int8(from.File), int8(to.Rank) - int8(from.Rank),
+	}, nil}
+	if len(notation) == 5 {
+		promoteTo, ok := uciPromotion(notation[4:])
+		if !ok {
+			return MoveRequest{}, fmt.Errorf("invalid move %q", uci)
+		}
+		moveRequest.PromoteTo = &promoteTo
+	}
+	if !game.isLegal(moveRequest) {
+		return MoveRequest{}, fmt.Errorf("illegal move %q", uci)
+	}
+	return moveRequest, nil
+}
+
+func uciPromotion(letter string) (PieceType, bool) {
+	for pieceType, promotionLetter := range uciPromotionLetters {
+		if promotionLetter == letter {
+			return pieceType, true
+		}
+	}
+	return 0, false
+}
+
+// MoveSAN make a move given in Standard Algebraic Notation, for example
+// "Nxe5+"
+func (game *Game) MoveSAN(san string) error {
+	game.mutex.Lock()
+	defer game.mutex.Unlock()
+	moveRequest, err := game.clone().parseSAN(san)
+	if err != nil {
+		return err
+	}
+	return game.move(moveRequest)
+}
+
+// MoveUCI make a move given in long algebraic notation, for example "e7e8q"
+func (game *Game) MoveUCI(uci string) error {
+	game.mutex.Lock()
+	defer game.mutex.Unlock()
+	moveRequest, err := game.clone().parseUCI(uci)
+	if err != nil {
+		return err
+	}
+	return game.move(moveRequest)
+}
@@ -0,0 +1,44 @@
+package model
+
+import (
+	"testing"
+)
+
+func TestUCI(t *testing.T) {
+	queen := Queen
+	tests := map[string]MoveRequest{
+		"e2e4":  {Position{4, 1}, Move{0, 2}, nil},
+		"e1g1":  {Position{4, 0}, Move{2, 0}, nil},
+		"b7b8q": {Position{1, 6}, Move{0, 1}, &queen},
+	}
+	for expected, moveRequest := range tests {
+		if moveRequest.UCI() != expected {
+			t.Error("Expected ", expected, " got ", moveRequest.UCI())
+		}
+	}
+}
+
+func TestParseUCI(t *testing.T) {
+	game, _ := NewGameFromFEN("4k3/1P6/8/8/8/8/8/R3K3 w Q - 0 1")
+	moveRequest, err := game.ParseUCI("b7b8n")
+	if err != nil || moveRequest.Position != (Position{1, 6}) ||
+		moveRequest.Move != (Move{0, 1}) || *moveRequest.PromoteTo != Knight {
+		t.Error("Expected knight promotion got ", moveRequest, err)
+	}
+	if err := game.MoveUCI("e1c1"); err != nil {
+		t.Error("Expected castle to be valid ", err)
+	}
+	if game.FEN() != "4k3/1P6/8/8/8/8/8/2KR4 b - - 1 1" {
+		t.Error("Expected queenside castle got ", game.FEN())
+	}
+}
+
+func TestParseUCIInvalid(t *testing.T) {
+	game, _ := NewGameFromFEN("4k3/1P6/8/8/8/8/8/4K3 w - - 0 1")
+	for _, uci := range []string{"", "b7", "b7b8", "b7b8k", "i1a1", "e8d8",
+		"e1e3", "b7b8qq"} {
+		if err := game.MoveUCI(uci); err == nil {
+			t.Error("Expected invalid move ", uci)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+)
+
+// moveRecord holds the state a move destroys so that it can be undone
+type moveRecord struct {
+	piece         *Piece
+	piecePosition Position
+	pieceType     PieceType
+	promoted      bool
+	capturedPiece *Piece
+	// dropped is whether the piece was dropped from a pocket, and pocketed
+	// whether the captured piece went to the captor's pocket.
+	dropped  bool
+	pocketed bool
+	// exploded are the pieces an Atomic capture removed, which may include
+	// the piece that moved.
+	exploded            []*Piece
+	castledRook         *Piece
+	castledRookPosition Position
+	previousMove        Move
+	previousMover       *Piece
+	// positionHistory is the history the move cleared, if it was a capture
+	// or pawn move.
+	positionHistory             map[uint64]uint8
+	trackedRepetition           bool
+	hash                        uint64
+	turnsSinceCaptureOrPawnMove uint16
+	fullMoveNumber              uint16
+	checks                      [2]uint8
+	gameOver                    bool
+	result                      GameResult
+}
+
+func (game *Game) newMoveRecord(piece *Piece, move Move) moveRecord {
+	record := moveRecord{
+		piece:                       piece,
+		piecePosition:               piece.position,
+		pieceType:                   piece.pieceType,
+		promoted:                    piece.promoted,
+		previousMove:                game.previousMove,
+		previousMover:               game.previousMover,
+		positionHistory:             game.positionHistory,
+		hash:                        game.hash,
+		turnsSinceCaptureOrPawnMove: game.turnsSinceCaptureOrPawnMove,
+		fullMoveNumber:              game.fullMoveNumber,
+		checks:                      game.checks,
+		gameOver:                    game.gameOver,
+		result:                      game.result,
+	}
+	if rook := piece.castlingRook(game.board, move); rook != nil {
+		record.castledRook = rook
+		record.castledRookPosition = rook.position
+	}
+	return record
+}
+
+// Undo take back the last move
+func (game *Game) Undo() error {
+	return game.UndoN(1)
+}
+
+// UndoN take back the last n moves, leaving the game unchanged if there are
+// fewer than n moves to take back. Only a result the board decided is taken
+// back with its move, one set by SetGameResult or ClaimDraw cannot be undone.
+func (game *Game) UndoN(n int) error {
+	game.mutex.Lock()
+	defer game.mutex.Unlock()
+	if n < 0 || n > len(game.undoHistory) {
+		return fmt.Errorf("cannot undo %d moves, only %d have been made",
+			n, len(game.undoHistory))
+	} else if n > 0 && game.resultSet {
+		return errors.New("cannot undo moves past the game's set result")
+	}
+	for i := 0; i < n; i++ {
+		game.undo()
+	}
+	return nil
+}
+
+func (game *Game) undo() {
+	record := game.undoHistory[len(game.undoHistory)-1]
+	if record.positionHistory != nil {
+		game.positionHistory = record.positionHistory
+	} else if record.trackedRepetition {
+		game.positionHistory[game.hash]--
+		if game.positionHistory[game.hash] == 0 {
+			delete(game.positionHistory, game.hash)
+		}
+	}
+	game.hash = record.hash
+	piece := record.piece
+	for _, exploded := range record.exploded {
+		game.pieceCounts(exploded.color)[exploded.pieceType]++
+	}
+	rook := record.castledRook
+	game.board[piece.position.File][piece.position.Rank] = nil
+	if record.dropped {
+		game.pieceCounts(piece.color)[piece.pieceType]--
+		game.pockets[piece.color][piece.pieceType]++
+	} else {
+		// Clear both squares first since a Chess960 castle may swap the king
+		// and rook.
+		if rook != nil {
+			game.board[rook.position.File][rook.position.Rank] = nil
+		}
+		piece.position = record.piecePosition
+		if piece.pieceType != record.pieceType {
+			game.pieceCounts(piece.color)[piece.pieceType]--
+			game.pieceCounts(piece.color)[record.pieceType]++
+		}
+		piece.pieceType = record.pieceType
+		piece.promoted = record.promoted
+		piece.movesTaken--
+		game.board[piece.position.File][piece.position.Rank] = piece
+		if rook != nil {
+			rook.position = record.castledRookPosition
+			game.board[rook.position.File][rook.position.Rank] = rook
+		}
+	}
+	if captured := record.capturedPiece; captured != nil {
+		game.board[captured.position.File][captured.position.Rank] = captured
+		game.pieceCounts(captured.color)[captured.pieceType]++
+		if record.pocketed {
+			game.pockets[piece.color][captured.pocketType()]--
+		}
+	}
+	for _, exploded := range record.exploded {
+		if exploded != piece {
+			game.board[exploded.position.File][exploded.position.Rank] =
+				exploded
+		}
+	}
+	game.previousMove = record.previousMove
+	game.previousMover = record.previousMover
+	game.turnsSinceCaptureOrPawnMove = record.turnsSinceCaptureOrPawnMove
+	game.fullMoveNumber = record.fullMoveNumber
+	game.checks = record.checks
+	game.gameOver = record.gameOver
+	game.result = record.result
+	game.turn = piece.color
+	game.undoHistory = game.undoHistory[:len(game.undoHistory)-1]
+	game.moveHistory = game.moveHistory[:len(game.moveHistory)-1]
+}
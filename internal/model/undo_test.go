@@ -0,0 +1,133 @@
+package model
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+var undoGames = []string{
+	// Anderssen's Immortal Game: castling and checkmate.
+	"e4 e5 f4 exf4 Bc4 Qh4+ Kf1 b5 Bxb5 Nf6 Nf3 Qh6 d3 Nh5 Nh4 Qg5 Nf5 c6 " +
+		"g4 Nf6 Rg1 cxb5 h4 Qg6 h5 Qg5 Qf3 Ng8 Bxf4 Qf6 Nc3 Bc5 Nd5 Qxb2 " +
+		"Bd6 Bxg1 e5 Qxa1+ Ke2 Na6 Nxg7+ Kd8 Qf6+ Nxf6 Be7#",
+	// En passant, promotions, underpromotion and castling with captures.
+	"e4 d5 e5 f5 exf6 Nc6 fxg7 Nf6 gxh8=Q Ne4 Nf3 d4 Bc4 d3 O-O dxc2 " +
+		"Qxh7 cxb1=N Rxb1 Bf5 Qh5+ Kd7 d4 Qe8 Qxe8+ Kxe8 Bb5",
+	// Queenside castling and draw by repetition.
+	"d4 d5 Nc3 Nc6 Bf4 Bf5 Qd2 Qd7 O-O-O O-O-O Kb1 Kb8 Ka1 Ka8 Kb1 Kb8 " +
+		"Ka1 Ka8 Kb1 Kb8",
+}
+
+func undoSnapshot(t *testing.T, game *Game) string {
+	position, err := game.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fmt.Sprint(position, game.FEN(), game.blackPieces,
+		game.whitePieces, game.positionHistory, game.gameOver, game.result,
+		len(game.MoveHistory()))
+}
+
+func TestUndoRoundTrip(t *testing.T) {
+	for _, moves := range undoGames {
+		game := NewGame()
+		snapshots := []string{undoSnapshot(t, game)}
+		for _, san := range strings.Fields(moves) {
+			if err := game.MoveSAN(san); err != nil {
+				t.Fatal(san, err)
+			}
+			snapshots = append(snapshots, undoSnapshot(t, game))
+			// Undo and redo every move to catch state that leaks forward.
+			before, _ := game.MarshalBinary()
+			lastMove := game.MoveHistory()[len(game.MoveHistory())-1]
+			if err := game.Undo(); err != nil {
+				t.Fatal(err)
+			}
+			if undoSnapshot(t, game) != snapshots[len(snapshots)-2] {
+				t.Error("Expected ", snapshots[len(snapshots)-2], " got ",
+					undoSnapshot(t, game))
+			}
+			game.Move(lastMove)
+			after, _ := game.MarshalBinary()
+			if !bytes.Equal(before, after) {
+				t.Error("Expected to redo ", san)
+			}
+		}
+		for i := len(snapshots) - 2; i >= 0; i-- {
+			if err := game.Undo(); err != nil {
+				t.Fatal(err)
+			}
+			if undoSnapshot(t, game) != snapshots[i] {
+				t.Error("Expected ", snapshots[i], " got ",
+					undoSnapshot(t, game))
+			}
+		}
+		if game.FEN() != StartingFEN {
+			t.Error("Expected starting position got ", game.FEN())
+		}
+	}
+}
+
+func TestUndoN(t *testing.T) {
+	game := NewGame()
+	for _, san := range []string{"e4", "e5", "Nf3", "Nc6"} {
+		game.MoveSAN(san)
+	}
+	if err := game.UndoN(5); err == nil || len(game.MoveHistory()) != 4 {
+		t.Error("Expected undoing too many moves to fail")
+	}
+	if err := game.UndoN(3); err != nil {
+		t.Error(err)
+	}
+	expected := "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1"
+	if game.FEN() != expected {
+		t.Error("Expected ", expected, " got ", game.FEN())
+	}
+	if err := game.MoveSAN("c5"); err != nil {
+		t.Error(err)
+	}
+	game.UndoN(2)
+	if err := game.Undo(); err == nil {
+		t.Error("Expected no moves to undo")
+	}
+}
+
+func TestUndoGameOver(t *testing.T) {
+	game := NewGame()
+	for _, san := range []string{"f3", "e5", "g4", "Qh4#"} {
+		game.MoveSAN(san)
+	}
+	if !game.GameOver() {
+		t.Fatal("Expected checkmate")
+	}
+	game.Undo()
+	if game.GameOver() || game.Turn() != Black {
+		t.Error("Expected the game to resume got ", game.FEN())
+	}
+	if err := game.MoveSAN("Nc6"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestUndoSetResult(t *testing.T) {
+	resigned := NewGame()
+	claimed := NewGame()
+	claimed.SetDrawRules(FIDEDraws)
+	for _, san := range []string{"Nf3", "Nf6", "Ng1", "Ng8", "Nf3", "Nf6",
+		"Ng1", "Ng8"} {
+		resigned.MoveSAN(san)
+		claimed.MoveSAN(san)
+	}
+	resigned.SetGameResult(White, false, Resignation)
+	if err := claimed.ClaimDraw(); err != nil {
+		t.Fatal(err)
+	}
+	for _, game := range []*Game{resigned, claimed, claimed.Clone()} {
+		if err := game.Undo(); err == nil || !game.GameOver() ||
+			len(game.MoveHistory()) != 8 {
+			t.Error("Expected the set result not to be undone got ", err)
+		}
+	}
+}
@@ -0,0 +1,411 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+type (
+	// Variant is a set of rules played in place of standard chess. Its hooks
+	// run with the game locked, so they use the game's unexported state.
+	Variant interface {
+		// Name get the variant's name as used in a PGN Variant tag
+		Name() string
+		// StartFEN get the variant's start position in Forsyth-Edwards
+		// Notation
+		StartFEN() string
+		// setUp check that a new game's pieces are valid in the variant,
+		// adjusting any piece state that FEN cannot express
+		setUp(game *Game) error
+		// legalMoves get the legal moves for the side to move, usually by
+		// filtering the moves its pieces could make
+		legalMoves(game *Game) []MoveRequest
+		// inCheck get whether the side to move is in check
+		inCheck(game *Game) bool
+		// afterMove apply the variant's side effects of a move, keeping what
+		// it changes in the record so that the move can be undone
+		afterMove(game *Game, record *moveRecord)
+		// termination get the result if the position after a move ends the
+		// game by the variant's own rules, the standard endings are checked
+		// when it has no termination
+		termination(game *Game, legalMoves []MoveRequest) GameResult
+		// insufficientMaterial get whether neither side can win
+		insufficientMaterial(game *Game) bool
+		// canWin get whether the color has the material to win, which decides
+		// whether the opponent running out of time loses or draws
+		canWin(game *Game, color Color) bool
+		// drops get whether captured pieces go to pockets to be dropped back
+		// on the board, which FEN then writes after the placement
+		drops() bool
+	}
+
+	standard      struct{}
+	kingOfTheHill struct{ standard }
+	threeCheck    struct{ standard }
+	antichess     struct{ standard }
+	atomic        struct{ standard }
+	horde         struct{ standard }
+	crazyhouse    struct{ standard }
+	bughouse      struct{ crazyhouse }
+)
+
+var (
+	// Standard is standard chess
+	Standard Variant = standard{}
+	// KingOfTheHill is won by checkmate or by bringing the king to one of
+	// the four center squares
+	KingOfTheHill Variant = kingOfTheHill{}
+	// ThreeCheck is won by checkmate or by checking the enemy king three
+	// times
+	ThreeCheck Variant = threeCheck{}
+	// Antichess is won by losing every piece or being stalemated. Captures
+	// are compulsory and the king is an ordinary piece that pawns may
+	// promote to.
+	Antichess Variant = antichess{}
+	// Atomic captures explode, removing the capturing piece and every piece
+	// but a pawn next to the capture square. It is won by checkmate or by
+	// exploding the enemy king.
+	Atomic Variant = atomic{}
+	// Horde pits white's horde of pawns and no king against black's standard
+	// army. Black wins by capturing the whole horde.
+	Horde Variant = horde{}
+	// Crazyhouse pockets each captured piece for the captor, who may drop it
+	// back on an empty square instead of moving. Promoted pieces revert to
+	// pawns when captured.
+	Crazyhouse Variant = crazyhouse{}
+	// Bughouse is Crazyhouse played by teams of two on two boards, where a
+	// capture goes to the captor's partner on the other board. The game only
+	// tracks drops, the captures are passed to the partner's game with
+	// AddToPocket.
+	Bughouse Variant = bughouse{}
+
+	// Variants are every supported variant
+	Variants = []Variant{
+		Standard, KingOfTheHill, ThreeCheck, Antichess, Atomic, Horde,
+		Crazyhouse, Bughouse,
+	}
+
+	kingOfTheHillCenter = []Position{{3, 3}, {3, 4}, {4, 3}, {4, 4}}
+)
+
+// VariantFromName get the variant with the name, ignoring case
+func VariantFromName(name string) (Variant, error) {
+	for _, variant := range Variants {
+		if strings.EqualFold(variant.Name(), name) {
+			return variant, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown variant %q", name)
+}
+
+// NewGameVariant create a new game of the variant from its start position
+func NewGameVariant(variant Variant) *Game {
+	// Every variant's start position is valid so this cannot fail.
+	game, _ := NewGameVariantFromFEN(variant, variant.StartFEN())
+	return game
+}
+
+// CanWin get whether the color has the material to win in the game's
+// variant, so that the opponent running out of time loses rather than draws
+func (game *Game) CanWin(color Color) bool {
+	game.mutex.RLock()
+	defer game.mutex.RUnlock()
+	return game.variant.canWin(game, color)
+}
+
+// Variant get the game's variant
+func (game *Game) Variant() Variant {
+	game.mutex.RLock()
+	defer game.mutex.RUnlock()
+	return game.variant
+}
+
+func (standard) Name() string {
+	return "Standard"
+}
+
+func (standard) StartFEN() string {
+	return StartingFEN
+}
+
+func (standard) setUp(game *Game) error {
+	if err := noBackRankPawns(game, Black, White); err != nil {
+		return err
+	}
+	if game.blackPieces[King] != 1 || game.whitePieces[King] != 1 {
+		return errors.New("fen: each side must have exactly one king")
+	}
+	return nil
+}
+
+func noBackRankPawns(game *Game, colors ...Color) error {
+	for _, color := range colors {
+		for file := range game.board {
+			for _, rank := range []uint8{0, 7} {
+				piece := game.board[file][rank]
+				if piece != nil && piece.pieceType == Pawn &&
+					piece.color == color {
+					return errors.New("fen: pawns cannot be on a back rank")
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (standard) legalMoves(game *Game) []MoveRequest {
+	return game.generateLegalMoves()
+}
+
+func (standard) inCheck(game *Game) bool {
+	king, _ := game.getKings()
+	if king == nil {
+		return false
+	}
+	b := newBitboards(game.board)
+	return b.isAttacked(square(king.position), getOppositeColor(king.color))
+}
+
+func (standard) afterMove(game *Game, record *moveRecord) {}
+
+func (standard) termination(game *Game, legalMoves []MoveRequest) GameResult {
+	return GameResult{}
+}
+
+func (standard) insufficientMaterial(game *Game) bool {
+	return game.isDrawByInsufficientMaterial()
+}
+
+func (standard) canWin(game *Game, color Color) bool {
+	return !game.OnlyKing(color)
+}
+
+func (standard) drops() bool {
+	return false
+}
+
+func (kingOfTheHill) Name() string {
+	return "King of the Hill"
+}
+
+func (kingOfTheHill) termination(
+	game *Game, legalMoves []MoveRequest,
+) GameResult {
+	_, king := game.getKings()
+	for _, center := range kingOfTheHillCenter {
+		if king != nil && king.position == center {
+			return GameResult{
+				Winner: king.color, Termination: KingInTheCenter,
+			}
+		}
+	}
+	return GameResult{}
+}
+
+// A lone king can still win by reaching the center.
+func (kingOfTheHill) insufficientMaterial(game *Game) bool {
+	return false
+}
+
+func (kingOfTheHill) canWin(game *Game, color Color) bool {
+	return true
+}
+
+func (threeCheck) Name() string {
+	return "Three-check"
+}
+
+func (threeCheck) afterMove(game *Game, record *moveRecord) {
+	mover := record.piece.color
+	if game.checks[mover] >= 3 || !game.isInCheck() {
+		return
+	}
+	game.hash ^= game.zobristState()
+	game.checks[mover]++
+	game.hash ^= game.zobristState()
+}
+
+func (threeCheck) StartFEN() string {
+	return "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 3+3 0 1"
+}
+
+func (threeCheck) termination(
+	game *Game, legalMoves []MoveRequest,
+) GameResult {
+	mover := getOppositeColor(game.turn)
+	if game.checks[mover] >= 3 {
+		return GameResult{Winner: mover, Termination: ThreeChecks}
+	}
+	return GameResult{}
+}
+
+// Any piece can give check, so only two lone kings cannot win.
+func (threeCheck) insufficientMaterial(game *Game) bool {
+	return game.OnlyKing(Black) && game.OnlyKing(White)
+}
+
+func (antichess) Name() string {
+	return "Antichess"
+}
+
+func (antichess) StartFEN() string {
+	return "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w - - 0 1"
+}
+
+// Kings may be captured or promoted to, so any number is allowed.
+func (antichess) setUp(game *Game) error {
+	return noBackRankPawns(game, Black, White)
+}
+
+func (antichess) legalMoves(game *Game) []MoveRequest {
+	b := newBitboards(game.board)
+	moveRequests := game.generateMoves(&b, nil)
+	captures := []MoveRequest{}
+	for _, moveRequest := range moveRequests {
+		if game.isCapture(moveRequest) {
+			captures = append(captures, moveRequest)
+		}
+	}
+	if len(captures) > 0 {
+		moveRequests = captures
+	}
+	for _, moveRequest := range moveRequests {
+		if moveRequest.PromoteTo != nil && *moveRequest.PromoteTo == Queen {
+			king := King
+			moveRequests = append(moveRequests,
+				MoveRequest{moveRequest.Position, moveRequest.Move, &king})
+		}
+	}
+	return moveRequests
+}
+
+func (antichess) inCheck(game *Game) bool {
+	return false
+}
+
+// The side to move wins once it has no moves, whether or not it has pieces
+// left.
+func (antichess) termination(game *Game, legalMoves []MoveRequest) GameResult {
+	if len(legalMoves) > 0 {
+		return GameResult{}
+	}
+	termination := Stalemate
+	if game.pieceCount(game.turn) == 0 {
+		termination = NoPiecesLeft
+	}
+	return GameResult{Winner: game.turn, Termination: termination}
+}
+
+func (antichess) insufficientMaterial(game *Game) bool {
+	return false
+}
+
+// Losing pieces is the aim, so the side with any material left can win.
+func (antichess) canWin(game *Game, color Color) bool {
+	return true
+}
+
+func (atomic) Name() string {
+	return "Atomic"
+}
+
+func (atomic) legalMoves(game *Game) []MoveRequest {
+	b := newBitboards(game.board)
+	moveRequests := game.generateMoves(&b, b.leavesKingSafeAtomic)
+	return append(moveRequests, game.castleMoves(&b)...)
+}
+
+// Kings next to each other are never in check, since capturing the other
+// king would explode both.
+func (atomic) inCheck(game *Game) bool {
+	king, enemyKing := game.getKings()
+	if king == nil || enemyKing == nil {
+		return false
+	}
+	b := newBitboards(game.board)
+	return kingAttacks[square(king.position)]&
+		b.pieces[enemyKing.color][King] == 0 &&
+		b.isAttacked(square(king.position), enemyKing.color)
+}
+
+func (atomic) afterMove(game *Game, record *moveRecord) {
+	if record.capturedPiece == nil {
+		return
+	}
+	game.hash ^= game.zobristState()
+	center := record.piece.position
+	for file := int(center.File) - 1; file <= int(center.File)+1; file++ {
+		for rank := int(center.Rank) - 1; rank <= int(center.Rank)+1; rank++ {
+			if squareAt(file, rank) == 0 {
+				continue
+			}
+			piece := game.board[file][rank]
+			if piece == nil ||
+				(piece.pieceType == Pawn && piece != record.piece) {
+				continue
+			}
+			game.board[file][rank] = nil
+			game.pieceCounts(piece.color)[piece.pieceType]--
+			game.hash ^= zobristPiece(piece.color, piece.pieceType,
+				piece.position)
+			record.exploded = append(record.exploded, piece)
+		}
+	}
+	game.hash ^= game.zobristState()
+}
+
+func (atomic) termination(game *Game, legalMoves []MoveRequest) GameResult {
+	if king, _ := game.getKings(); king == nil {
+		return GameResult{
+			Winner: getOppositeColor(game.turn), Termination: KingExploded,
+		}
+	}
+	return GameResult{}
+}
+
+// A lone king cannot capture, so only two lone kings cannot win.
+func (atomic) insufficientMaterial(game *Game) bool {
+	return game.OnlyKing(Black) && game.OnlyKing(White)
+}
+
+func (horde) Name() string {
+	return "Horde"
+}
+
+func (horde) StartFEN() string {
+	return "rnbqkbnr/pppppppp/8/1PP2PP1/PPPPPPPP/PPPPPPPP/PPPPPPPP/PPPPPPPP " +
+		"w kq - 0 1"
+}
+
+// The horde's pawns may stand on the first rank, and may move two squares
+// from it as from the second.
+func (horde) setUp(game *Game) error {
+	if err := noBackRankPawns(game, Black); err != nil {
+		return err
+	}
+	for file := range game.board {
+		if piece := game.board[file][7]; piece != nil &&
+			piece.pieceType == Pawn {
+			return errors.New("fen: pawns cannot be on a back rank")
+		} else if piece := game.board[file][0]; piece != nil &&
+			piece.pieceType == Pawn {
+			piece.movesTaken = 0
+		}
+	}
+	if game.blackPieces[King] != 1 || game.whitePieces[King] != 0 {
+		return errors.New("fen: only black may have a king, and exactly one")
+	}
+	return nil
+}
+
+func (horde) termination(game *Game, legalMoves []MoveRequest) GameResult {
+	if game.pieceCount(White) == 0 {
+		return GameResult{Winner: Black, Termination: NoPiecesLeft}
+	}
+	return GameResult{}
+}
+
+func (horde) insufficientMaterial(game *Game) bool {
+	return false
+}
@@ -0,0 +1,216 @@
+package model
+
+import (
+	"testing"
+)
+
+// Known node counts for each variant's start position, as published by
+// lichess and python-chess
+var variantPerftNodes = map[Variant][]uint64{
+	KingOfTheHill: {20, 400, 8902, 197281},
+	ThreeCheck:    {20, 400, 8902, 197281},
+	Antichess:     {20, 400, 8067, 153299},
+	Atomic:        {20, 400, 8902, 197326},
+	Horde:         {8, 128, 1274, 23310},
+	Crazyhouse:    {20, 400, 8902, 197281},
+	Bughouse:      {20, 400, 8902, 197281},
+}
+
+func TestVariantStartPositions(t *testing.T) {
+	for _, variant := range Variants {
+		game := NewGameVariant(variant)
+		if game == nil {
+			t.Fatal("Expected a game of ", variant.Name())
+		}
+		if game.Variant() != variant ||
+			game.StartFEN() != variant.StartFEN() {
+			t.Error("Expected ", variant.StartFEN(), " got ", game.StartFEN())
+		}
+		for i, expected := range variantPerftNodes[variant] {
+			if testing.Short() && expected > 100000 {
+				break
+			}
+			if nodes := game.Perft(i + 1); nodes != expected {
+				t.Error("Expected ", variant.Name(), " perft(", i+1, ") = ",
+					expected, " got ", nodes)
+			}
+		}
+	}
+}
+
+func TestVariantFromName(t *testing.T) {
+	variant, err := VariantFromName("king of the hill")
+	if err != nil || variant != KingOfTheHill {
+		t.Error("Expected ", KingOfTheHill.Name(), " got ", variant, err)
+	}
+	if _, err := VariantFromName("Fog of War"); err == nil {
+		t.Error("Expected an error for an unknown variant")
+	}
+}
+
+func TestCanWin(t *testing.T) {
+	fen := "4k3/8/8/8/8/8/8/R3K3 w - - 0 1"
+	for variant, expected := range map[Variant]bool{
+		Standard: false, KingOfTheHill: true, Antichess: true, Atomic: false,
+	} {
+		game, _ := NewGameVariantFromFEN(variant, fen)
+		if !game.CanWin(White) || game.CanWin(Black) != expected {
+			t.Error("Expected ", variant.Name(), " lone king to win ", expected)
+		}
+	}
+}
+
+func TestKingOfTheHill(t *testing.T) {
+	game, _ := NewGameVariantFromFEN(KingOfTheHill,
+		"4k3/8/8/8/8/8/3K4/8 w - - 0 1")
+	game.MoveSAN("Kd3")
+	game.MoveSAN("Kd7")
+	if game.GameOver() {
+		t.Error("Expected lone kings to play on got ", game.Result())
+	}
+	game.MoveSAN("Kd4")
+	result := game.Result()
+	if !game.GameOver() || result.Draw || result.Winner != White ||
+		result.Termination != KingInTheCenter {
+		t.Error("Expected ", KingInTheCenter, " got ", result)
+	}
+}
+
+func TestThreeCheck(t *testing.T) {
+	game, err := NewGameVariantFromFEN(ThreeCheck,
+		"4k3/8/8/8/8/8/8/R3K3 w - - 2+3 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash := game.Hash()
+	game.MoveSAN("Ra8+")
+	if game.GameOver() ||
+		game.FEN() != "R3k3/8/8/8/8/8/8/4K3 b - - 1+3 1 1" {
+		t.Error("Expected one check left got ", game.FEN())
+	}
+	if game.Hash() != game.zobristHash() {
+		t.Error("Expected the incremental hash to count the check")
+	}
+	game.Undo()
+	if game.Hash() != hash ||
+		game.FEN() != "4k3/8/8/8/8/8/8/R3K3 w - - 2+3 0 1" {
+		t.Error("Expected the check to be undone got ", game.FEN())
+	}
+	for _, san := range []string{"Ra8+", "Kd7", "Ra7+"} {
+		game.MoveSAN(san)
+	}
+	result := game.Result()
+	if !game.GameOver() || result.Winner != White ||
+		result.Termination != ThreeChecks {
+		t.Error("Expected ", ThreeChecks, " got ", result)
+	}
+	if _, err := NewGameVariantFromFEN(ThreeCheck,
+		"4k3/8/8/8/8/8/8/R3K3 w - - 4+3 0 1"); err == nil {
+		t.Error("Expected an error for more than three checks left")
+	}
+}
+
+func TestAntichess(t *testing.T) {
+	game := NewGameVariant(Antichess)
+	game.MoveSAN("e3")
+	game.MoveSAN("b5")
+	moves := game.legalMoves()
+	if len(moves) != 1 || moves[0].UCI() != "f1b5" {
+		t.Error("Expected the capture to be compulsory got ", moves)
+	}
+	game, _ = NewGameVariantFromFEN(Antichess,
+		"8/8/8/8/8/8/1p6/R7 b - - 0 1")
+	if moves := game.legalMoves(); len(moves) != 5 {
+		t.Error("Expected five promotions including a king got ", moves)
+	}
+	if err := game.MoveSAN("bxa1=K"); err != nil {
+		t.Error("Expected a king promotion got ", err)
+	}
+	result := game.Result()
+	if !game.GameOver() || result.Winner != White ||
+		result.Termination != NoPiecesLeft {
+		t.Error("Expected ", NoPiecesLeft, " got ", result)
+	}
+}
+
+func TestAntichessStalemateWins(t *testing.T) {
+	game, _ := NewGameVariantFromFEN(Antichess,
+		"8/8/8/8/p7/8/P7/8 w - - 0 1")
+	game.MoveSAN("a3")
+	result := game.Result()
+	if !game.GameOver() || result.Draw || result.Winner != Black ||
+		result.Termination != Stalemate {
+		t.Error("Expected the stalemated side to win got ", result)
+	}
+}
+
+func TestAtomicExplosion(t *testing.T) {
+	fen := "4k3/8/2b5/3np3/4P3/8/8/4K3 w - - 0 1"
+	game, _ := NewGameVariantFromFEN(Atomic, fen)
+	hash := game.Hash()
+	game.MoveSAN("exd5")
+	if game.FEN() != "4k3/8/8/4p3/8/8/8/4K3 b - - 0 1" {
+		t.Error("Expected the pawn, knight and bishop to explode got ",
+			game.FEN())
+	}
+	if game.Hash() != game.zobristHash() || game.blackPieces[Bishop] != 0 ||
+		game.whitePieces[Pawn] != 0 {
+		t.Error("Expected the explosion to update the hash and piece counts")
+	}
+	game.Undo()
+	if game.FEN() != fen || game.Hash() != hash ||
+		game.blackPieces[Bishop] != 1 || game.whitePieces[Pawn] != 1 {
+		t.Error("Expected the explosion to be undone got ", game.FEN())
+	}
+}
+
+func TestAtomicKingExploded(t *testing.T) {
+	game, _ := NewGameVariantFromFEN(Atomic,
+		"4k3/4r3/8/8/8/8/8/4RK2 w - - 0 1")
+	game.MoveSAN("Rxe7")
+	result := game.Result()
+	if !game.GameOver() || result.Winner != White ||
+		result.Termination != KingExploded {
+		t.Error("Expected ", KingExploded, " got ", result)
+	}
+}
+
+func TestAtomicKingSafety(t *testing.T) {
+	game, _ := NewGameVariantFromFEN(Atomic,
+		"4k3/8/8/8/8/8/4p3/4K3 w - - 0 1")
+	if _, err := game.ParseSAN("Kxe2"); err == nil {
+		t.Error("Expected a king capture to be illegal")
+	}
+	// A capture next to its own king would explode it.
+	game, _ = NewGameVariantFromFEN(Atomic,
+		"k7/8/8/8/8/8/3pQ3/4K3 w - - 0 1")
+	if _, err := game.ParseSAN("Qxd2"); err == nil {
+		t.Error("Expected a capture exploding its own king to be illegal")
+	}
+	game, _ = NewGameVariantFromFEN(Atomic,
+		"8/8/8/8/8/4k3/4K3/4q3 w - - 0 1")
+	if game.isInCheck() {
+		t.Error("Expected kings next to each other not to be in check")
+	}
+}
+
+func TestHorde(t *testing.T) {
+	game, err := NewGameVariantFromFEN(Horde, "4k3/8/8/8/8/8/8/P7 w - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := game.MoveSAN("a3"); err != nil {
+		t.Error("Expected a pawn on the first rank to move two squares got ",
+			err)
+	}
+	game, _ = NewGameVariantFromFEN(Horde, "4k3/8/8/8/8/8/8/Pr6 b - - 0 1")
+	game.MoveSAN("Rxa1")
+	result := game.Result()
+	if !game.GameOver() || result.Winner != Black ||
+		result.Termination != NoPiecesLeft {
+		t.Error("Expected ", NoPiecesLeft, " got ", result)
+	}
+	if _, err := NewGameVariantFromFEN(Horde, StartingFEN); err == nil {
+		t.Error("Expected an error for a white king in Horde")
+	}
+}
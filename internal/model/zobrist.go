@@ -0,0 +1,120 @@
+package model
+
+// zobristKeys are the random numbers XORed together for a position's hash.
+// They are seeded so that hashes are the same every run.
+var zobristKeys struct {
+	pieces    [2][6][64]uint64
+	castling  [2][2]uint64
+	enPassant [8]uint64
+	black     uint64
+	checks    [2][4]uint64
+	pockets   [2][6]uint64
+}
+
+func init() {
+	seed := uint64(0x2545F4914F6CDD1D)
+	for color := range zobristKeys.pieces {
+		for pieceType := range zobristKeys.pieces[color] {
+			for sq := range zobristKeys.pieces[color][pieceType] {
+				zobristKeys.pieces[color][pieceType][sq] = nextRandom(&seed)
+			}
+		}
+	}
+	for color := range zobristKeys.castling {
+		zobristKeys.castling[color][0] = nextRandom(&seed)
+		zobristKeys.castling[color][1] = nextRandom(&seed)
+	}
+	for file := range zobristKeys.enPassant {
+		zobristKeys.enPassant[file] = nextRandom(&seed)
+	}
+	zobristKeys.black = nextRandom(&seed)
+	for color := range zobristKeys.checks {
+		// No checks given hashes as zero so that other variants are unchanged.
+		for checks := 1; checks < len(zobristKeys.checks[color]); checks++ {
+			zobristKeys.checks[color][checks] = nextRandom(&seed)
+		}
+	}
+	for color := range zobristKeys.pockets {
+		for pieceType := range zobristKeys.pockets[color] {
+			// Odd keys give every count of pocketed pieces its own multiple.
+			zobristKeys.pockets[color][pieceType] = nextRandom(&seed) | 1
+		}
+	}
+}
+
+// Hash get the game position's Zobrist hash. Positions with the same pieces,
+// side to move, castling rights, en passant capture, checks given in
+// Three-check and pocketed pieces in Crazyhouse share a hash.
+func (game *Game) Hash() uint64 {
+	game.mutex.RLock()
+	defer game.mutex.RUnlock()
+	return game.hash
+}
+
+func zobristPiece(color Color, pieceType PieceType, pos Position) uint64 {
+	return zobristKeys.pieces[color][pieceType][square(pos)]
+}
+
+// zobristHash compute the hash from scratch, Game.Move keeps it up to date
+// incrementally afterwards
+func (game *Game) zobristHash() uint64 {
+	hash := game.zobristState()
+	for _, file := range game.board {
+		for _, piece := range file {
+			if piece != nil {
+				hash ^= zobristPiece(piece.color, piece.pieceType,
+					piece.position)
+			}
+		}
+	}
+	return hash
+}
+
+// zobristState get the hash of everything but the piece placement
+func (game *Game) zobristState() uint64 {
+	hash := uint64(0)
+	if game.turn == Black {
+		hash ^= zobristKeys.black
+	}
+	for color, king := range [2]*Piece{game.blackKing, game.whiteKing} {
+		hash ^= zobristKeys.checks[color][game.checks[color]]
+		for pieceType, count := range game.pockets[color] {
+			hash ^= zobristKeys.pockets[color][pieceType] * uint64(count)
+		}
+		if game.onBoard(king) == nil {
+			continue
+		}
+		castleLeft, castleRight := king.hasCastleRights(game.board)
+		if castleLeft {
+			hash ^= zobristKeys.castling[color][0]
+		}
+		if castleRight {
+			hash ^= zobristKeys.castling[color][1]
+		}
+	}
+	// As in Polyglot, en passant only counts when a pawn could capture.
+	if sq := game.enPassantSquare(); sq >= 0 {
+		mover := game.previousMover
+		for _, dx := range []int8{-1, 1} {
+			file := uint8(int8(mover.position.File) + dx)
+			if file > 7 {
+				continue
+			}
+			pawn := game.board[file][mover.position.Rank]
+			if pawn != nil && pawn.pieceType == Pawn &&
+				pawn.color == game.turn {
+				hash ^= zobristKeys.enPassant[mover.position.File]
+				break
+			}
+		}
+	}
+	return hash
+}
+
+// nextRandom is a seeded xorshift generator so that keys are reproducible
+func nextRandom(seed *uint64) uint64 {
+	*seed ^= *seed >> 12
+	*seed ^= *seed << 25
+	*seed ^= *seed >> 27
+	return *seed * 2685821657736338717
+}
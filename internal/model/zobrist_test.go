@@ -0,0 +1,67 @@
+package model
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHashIncremental(t *testing.T) {
+	for _, moves := range undoGames {
+		game := NewGame()
+		for _, san := range strings.Fields(moves) {
+			game.MoveSAN(san)
+			if game.Hash() != game.zobristHash() {
+				t.Error("Expected incremental hash to match after ", san)
+			}
+		}
+		game.UndoN(len(game.MoveHistory()))
+		if game.Hash() != NewGame().Hash() {
+			t.Error("Expected undo to restore the starting hash")
+		}
+	}
+}
+
+func TestHashTransposition(t *testing.T) {
+	game1, game2 := NewGame(), NewGame()
+	for _, uci := range []string{"g1f3", "g8f6", "b1c3", "b8c6"} {
+		game1.MoveUCI(uci)
+	}
+	for _, uci := range []string{"b1c3", "b8c6", "g1f3", "g8f6"} {
+		game2.MoveUCI(uci)
+	}
+	if game1.Hash() != game2.Hash() {
+		t.Error("Expected transposed positions to share a hash")
+	}
+	fromFEN, _ := NewGameFromFEN(game1.FEN())
+	if fromFEN.Hash() != game1.Hash() {
+		t.Error("Expected a game from FEN to share the hash")
+	}
+	game1.MoveUCI("e2e4")
+	if game1.Hash() == game2.Hash() {
+		t.Error("Expected different positions to differ")
+	}
+}
+
+func TestHashCastlingAndEnPassant(t *testing.T) {
+	fens := []string{
+		"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1",
+		"r3k2r/8/8/8/8/8/8/R3K2R w Kkq - 0 1",
+		"r3k2r/8/8/8/8/8/8/R3K2R b KQkq - 0 1",
+		"4k3/8/8/3pP3/8/8/8/4K3 w - d6 0 1",
+		"4k3/8/8/3pP3/8/8/8/4K3 w - - 0 1",
+	}
+	hashes := map[uint64]string{}
+	for _, fen := range fens {
+		game, _ := NewGameFromFEN(fen)
+		if other, ok := hashes[game.Hash()]; ok {
+			t.Error("Expected ", fen, " and ", other, " to differ")
+		}
+		hashes[game.Hash()] = fen
+	}
+	// Without a pawn to capture en passant the square does not matter.
+	game1, _ := NewGameFromFEN("4k3/8/8/3p4/8/8/8/4K3 w - d6 0 1")
+	game2, _ := NewGameFromFEN("4k3/8/8/3p4/8/8/8/4K3 w - - 0 1")
+	if game1.Hash() != game2.Hash() {
+		t.Error("Expected an unusable en passant square to be ignored")
+	}
+}
@@ -0,0 +1,218 @@
+package pgn
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Ekotlikoff/gochess/internal/model"
+)
+
+const maxLineLength = 79
+
+var sevenTagRoster = []string{
+	"Event", "Site", "Date", "Round", "White", "Black", "Result",
+}
+
+type (
+	// Game is a chess game along with its PGN tag pairs and annotations
+	Game struct {
+		Tags map[string]string
+		Game *model.Game
+		// Comments maps a ply to the comment following it, where ply 0 is
+		// before the first move.
+		Comments map[int]string
+		// NAGs maps a ply to the numeric annotation glyphs following it.
+		NAGs map[int][]int
+	}
+
+	// SyntaxError is an error in a PGN file along with its location
+	SyntaxError struct {
+		Line, Column int
+		Msg          string
+	}
+)
+
+// NewGame wrap a game with the default Seven Tag Roster
+func NewGame(game *model.Game) *Game {
+	return &Game{
+		Tags: map[string]string{
+			"Event": "?", "Site": "?", "Date": "????.??.??", "Round": "?",
+			"White": "?", "Black": "?",
+		},
+		Game:     game,
+		Comments: map[int]string{},
+		NAGs:     map[int][]int{},
+	}
+}
+
+func (err *SyntaxError) Error() string {
+	return fmt.Sprintf("pgn: line %d, column %d: %s",
+		err.Line, err.Column, err.Msg)
+}
+
+// Result get the PGN result token for the game
+func Result(game *model.Game) string {
+	result := game.Result()
+	if !game.GameOver() || result.Termination == model.Aborted {
+		return "*"
+	} else if result.Draw {
+		return "1/2-1/2"
+	} else if result.Winner == model.White {
+		return "1-0"
+	}
+	return "0-1"
+}
+
+// Write the game in PGN export format
+func Write(w io.Writer, game *Game) error {
+	movetext, err := game.movetext()
+	if err != nil {
+		return err
+	}
+	tags := map[string]string{}
+	for name, value := range game.Tags {
+		tags[name] = value
+	}
+	tags["Result"] = Result(game.Game)
+	variant := game.Game.Variant()
+	if game.Game.Chess960() && !isChess960(tags["Variant"]) {
+		tags["Variant"] = "Chess960"
+	} else if variant != model.Standard {
+		tags["Variant"] = variant.Name()
+	}
+	roster := sevenTagRoster
+	startFEN := game.Game.StartFEN()
+	if startFEN != variant.StartFEN() {
+		// Games from a setup position name it right after the roster.
+		tags["SetUp"] = "1"
+		tags["FEN"] = startFEN
+		roster = append(roster[:len(roster):len(roster)], "SetUp", "FEN")
+	}
+	var buf bytes.Buffer
+	for _, name := range roster {
+		value, ok := tags[name]
+		if !ok {
+			value = "?"
+		}
+		writeTag(&buf, name, value)
+		delete(tags, name)
+	}
+	names := []string{}
+	for name := range tags {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		writeTag(&buf, name, tags[name])
+	}
+	buf.WriteString("\n")
+	writeWrapped(&buf, append(movetext, Result(game.Game)))
+	buf.WriteString("\n")
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+func (game *Game) String() string {
+	var sb strings.Builder
+	if err := Write(&sb, game); err != nil {
+		return err.Error()
+	}
+	return sb.String()
+}
+
+func writeTag(buf *bytes.Buffer, name, value string) {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `"`, `\"`)
+	buf.WriteString("[" + name + " \"" + value + "\"]\n")
+}
+
+func writeWrapped(buf *bytes.Buffer, tokens []string) {
+	lineLength := 0
+	for _, token := range tokens {
+		if lineLength > 0 && lineLength+1+len(token) > maxLineLength {
+			buf.WriteString("\n")
+			lineLength = 0
+		} else if lineLength > 0 {
+			buf.WriteString(" ")
+			lineLength++
+		}
+		buf.WriteString(token)
+		lineLength += len(token)
+	}
+}
+
+// movetext replay the game from its starting position to get each move in
+// Standard Algebraic Notation
+func (game *Game) movetext() ([]string, error) {
+	replay, err := newModelGame(game.Game.StartFEN(), game.Game.Chess960(),
+		game.Game.Variant())
+	if err != nil {
+		return nil, err
+	}
+	fields := strings.Fields(game.Game.StartFEN())
+	moveNumber, _ := strconv.Atoi(fields[len(fields)-1])
+	tokens := game.annotations(0)
+	for ply, moveRequest := range game.Game.MoveHistory() {
+		turn := replay.Turn()
+		san, err := replay.SAN(moveRequest)
+		if err != nil {
+			return nil, err
+		}
+		if turn == model.White {
+			tokens = append(tokens, strconv.Itoa(moveNumber)+".")
+		} else if ply == 0 || game.Comments[ply] != "" {
+			tokens = append(tokens, strconv.Itoa(moveNumber)+"...")
+		}
+		if turn == model.Black {
+			moveNumber++
+		}
+		tokens = append(tokens, san)
+		tokens = append(tokens, game.annotations(ply+1)...)
+		if err := replay.Move(moveRequest); err != nil {
+			return nil, err
+		}
+	}
+	return tokens, nil
+}
+
+// newModelGame create a game of the variant from its starting position,
+// which follows Chess960 castling rules if requested
+func newModelGame(
+	fen string, chess960 bool, variant model.Variant,
+) (*model.Game, error) {
+	if chess960 {
+		return model.NewGameChess960FromFEN(fen)
+	}
+	return model.NewGameVariantFromFEN(variant, fen)
+}
+
+// variantFromTag get the variant named by a Variant tag, which is standard
+// chess if the tag is missing or unknown
+func variantFromTag(tag string) model.Variant {
+	variant, err := model.VariantFromName(tag)
+	if err != nil {
+		return model.Standard
+	}
+	return variant
+}
+
+func isChess960(variant string) bool {
+	return strings.EqualFold(variant, "Chess960") ||
+		strings.EqualFold(variant, "Fischerandom")
+}
+
+func (game *Game) annotations(ply int) []string {
+	tokens := []string{}
+	for _, nag := range game.NAGs[ply] {
+		tokens = append(tokens, "$"+strconv.Itoa(nag))
+	}
+	if comment := game.Comments[ply]; comment != "" {
+		comment = strings.ReplaceAll(comment, "}", "")
+		tokens = append(tokens, strings.Fields("{"+comment+"}")...)
+	}
+	return tokens
+}
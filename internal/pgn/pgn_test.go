@@ -0,0 +1,261 @@
+package pgn
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/Ekotlikoff/gochess/internal/model"
+)
+
+const debug bool = false
+
+const multiGamePGN = `[Event "Casual Game"]
+[Site "Berlin GER"]
+[Date "1852.??.??"]
+[Round "?"]
+[White "Adolf Anderssen"]
+[Black "Jean Dufresne"]
+[Result "1-0"]
+
+1.e4 e5 2.Nf3 Nc6 3.Bc4 Bc5 4.b4 Bxb4 5.c3 Ba5 6.d4 exd4 7.O-O
+d3 8.Qb3 Qf6 9.e5 Qg6 10.Re1 Nge7 11.Ba3 b5 12.Qxb5 Rb8 13.Qa4
+Bb6 14.Nbd2 Bb7 15.Ne4 Qf5 16.Bxd3 Qh5 17.Nf6+ gxf6 18.exf6
+Rg8 19.Rad1 Qxf3 20.Rxe7+ Nxe7 21.Qxd7+ Kxd7 22.Bf5+ Ke8
+23.Bd7+ Kf8 24.Bxe7# 1-0
+
+% An escaped line that should be ignored.
+[Event "Annotated"]
+[White "A"]
+[Black "B"]
+
+{Opening comment} 1. e4 $1 c5 ; Sicilian
+2. Nf3 (2. c3 d5 (2... Nf6)) 2... d6!? 3. d4 cxd4 4. Nxd4 Nf6 5. Nc3 a6 1/2-1/2
+
+[Event "Unfinished"]
+[SetUp "1"]
+[FEN "4k3/8/8/8/8/8/4P3/4K3 b - - 0 40"]
+
+40... Kd7 41. e4 *
+`
+
+func TestReadAll(t *testing.T) {
+	games, err := ReadAll(strings.NewReader(multiGamePGN))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(games) != 3 {
+		t.Fatal("Expected 3 games got ", len(games))
+	}
+	immortal := games[0]
+	if debug {
+		fmt.Println(immortal.Game.BoardString())
+	}
+	if len(immortal.Game.MoveHistory()) != 47 || !immortal.Game.GameOver() ||
+		immortal.Game.Result().Winner != model.White ||
+		immortal.Game.Result().Termination != model.Checkmate ||
+		immortal.Tags["White"] != "Adolf Anderssen" {
+		t.Error("Expected checkmate by white got ", immortal.Game.FEN())
+	}
+	annotated := games[1]
+	if len(annotated.Game.MoveHistory()) != 10 ||
+		!annotated.Game.Result().Draw ||
+		annotated.Game.Result().Termination != model.Agreement {
+		t.Error("Expected a drawn game got ", annotated.Game.FEN())
+	}
+	if annotated.Comments[0] != "Opening comment" ||
+		annotated.Comments[2] != "Sicilian" {
+		t.Error("Expected comments got ", annotated.Comments)
+	}
+	if len(annotated.NAGs[1]) != 1 || annotated.NAGs[1][0] != 1 ||
+		len(annotated.NAGs[4]) != 1 || annotated.NAGs[4][0] != 5 {
+		t.Error("Expected NAGs got ", annotated.NAGs)
+	}
+	unfinished := games[2]
+	if unfinished.Game.GameOver() || unfinished.Tags["Result"] != "*" ||
+		unfinished.Game.FEN() != "8/3k4/8/8/4P3/8/8/4K3 b - e3 0 41" {
+		t.Error("Expected unfinished game got ", unfinished.Game.FEN())
+	}
+}
+
+func TestWrite(t *testing.T) {
+	game := NewGame(model.NewGame())
+	game.Tags["White"] = "player1"
+	game.Tags["Black"] = "player2"
+	game.Tags["Annotator"] = "gochess"
+	for _, san := range []string{"e4", "e5", "Bc4", "Nc6", "Qh5", "Nf6", "Qxf7#"} {
+		moveRequest, err := game.Game.ParseSAN(san)
+		if err != nil {
+			t.Fatal(err)
+		}
+		game.Game.Move(moveRequest)
+	}
+	expected := `[Event "?"]
+[Site "?"]
+[Date "????.??.??"]
+[Round "?"]
+[White "player1"]
+[Black "player2"]
+[Result "1-0"]
+[Annotator "gochess"]
+
+1. e4 e5 2. Bc4 Nc6 3. Qh5 Nf6 4. Qxf7# 1-0
+`
+	if game.String() != expected {
+		t.Error("Expected ", expected, " got ", game.String())
+	}
+}
+
+func TestWriteFromFEN(t *testing.T) {
+	modelGame, _ := model.NewGameFromFEN("4k3/8/8/8/8/8/4P3/4K3 b - - 0 40")
+	game := NewGame(modelGame)
+	game.Comments[1] = "Only move"
+	for _, san := range []string{"Kd7", "e4", "Ke6"} {
+		moveRequest, _ := game.Game.ParseSAN(san)
+		game.Game.Move(moveRequest)
+	}
+	output := game.String()
+	if !strings.Contains(output, "[SetUp \"1\"]") ||
+		!strings.Contains(output,
+			"[FEN \"4k3/8/8/8/8/8/4P3/4K3 b - - 0 40\"]") ||
+		!strings.Contains(output,
+			"40... Kd7 {Only move} 41. e4 Ke6 *") {
+		t.Error("Expected a game starting from a FEN got ", output)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	games, err := ReadAll(strings.NewReader(multiGamePGN))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, game := range games {
+		reread, err := ReadAll(strings.NewReader(game.String()))
+		if err != nil || len(reread) != 1 {
+			t.Error("Expected to reread the game got ", err)
+			continue
+		}
+		if reread[0].Game.FEN() != game.Game.FEN() ||
+			Result(reread[0].Game) != Result(game.Game) ||
+			reread[0].String() != game.String() {
+			t.Error("Expected the same game got ", reread[0].String())
+		}
+	}
+}
+
+func TestLongGameWrapsLines(t *testing.T) {
+	games, _ := ReadAll(strings.NewReader(multiGamePGN))
+	for _, line := range strings.Split(games[0].String(), "\n") {
+		if len(line) > maxLineLength {
+			t.Error("Expected wrapped lines got ", line)
+		}
+	}
+}
+
+func TestIllegalMove(t *testing.T) {
+	input := "[Event \"?\"]\n\n1. e4 e5\n2. Ke3 Nc6 *\n"
+	_, err := ReadAll(strings.NewReader(input))
+	syntaxError, ok := err.(*SyntaxError)
+	if !ok || syntaxError.Line != 4 || syntaxError.Column != 4 {
+		t.Error("Expected an illegal move error at 4:4 got ", err)
+	}
+}
+
+func TestContradictingResult(t *testing.T) {
+	inputs := []string{
+		"1. f3 e5 2. g4 Qh4# 1-0",
+		"1. f3 e5 2. g4 Qh4# 1/2-1/2",
+		"[FEN \"7k/8/4Q1K1/8/8/8/8/8 w - - 0 1\"]\n\n1. Qf7 1-0",
+	}
+	for _, input := range inputs {
+		_, err := ReadAll(strings.NewReader(input))
+		if syntaxError, ok := err.(*SyntaxError); !ok ||
+			syntaxError.Line != strings.Count(input, "\n")+1 ||
+			!strings.Contains(err.Error(), "contradicts") {
+			t.Error("Expected a contradicting result error for ", input,
+				" got ", err)
+		}
+	}
+	for _, input := range []string{"1. f3 e5 2. g4 Qh4# 0-1",
+		"[FEN \"7k/8/4Q1K1/8/8/8/8/8 w - - 0 1\"]\n\n1. Qf7 1/2-1/2"} {
+		games, err := ReadAll(strings.NewReader(input))
+		if err != nil || !games[0].Game.GameOver() {
+			t.Error("Expected the agreeing result for ", input, " got ", err)
+		}
+	}
+}
+
+func TestSyntaxErrors(t *testing.T) {
+	inputs := []string{
+		"[Event \"?\"\n\n1. e4 *",
+		"[Event ?]\n\n1. e4 *",
+		"[Event \"?]\n\n1. e4 *",
+		"1. e4 {unterminated *",
+		"1. e4 (1. d4 *",
+		"1. e4 & *",
+		"[FEN \"8/8/8/8/8/8/8/8 w - - 0 1\"]\n\n*",
+	}
+	for _, input := range inputs {
+		if _, err := ReadAll(strings.NewReader(input)); err == nil {
+			t.Error("Expected a syntax error for ", input)
+		}
+	}
+}
+
+func TestChess960RoundTrip(t *testing.T) {
+	modelGame, _ := model.NewGameChess960FromFEN(
+		"rk4r1/pppppppp/8/8/8/8/PPPPPPPP/RK4R1 w KQkq - 0 1")
+	for _, san := range []string{"O-O", "O-O-O"} {
+		if err := modelGame.MoveSAN(san); err != nil {
+			t.Fatal(san, err)
+		}
+	}
+	game := NewGame(modelGame)
+	if game.Tags["Variant"] != "" || !strings.Contains(game.String(),
+		"[Variant \"Chess960\"]") {
+		t.Error("Expected a Chess960 variant tag got ", game.String())
+	}
+	reread, err := ReadAll(strings.NewReader(game.String()))
+	if err != nil || len(reread) != 1 || !reread[0].Game.Chess960() ||
+		reread[0].Game.FEN() != modelGame.FEN() {
+		t.Error("Expected to reread the Chess960 game got ", err)
+	}
+}
+
+func TestVariantRoundTrip(t *testing.T) {
+	modelGame := model.NewGameVariant(model.Atomic)
+	for _, san := range []string{"e4", "d5", "exd5"} {
+		if err := modelGame.MoveSAN(san); err != nil {
+			t.Fatal(san, err)
+		}
+	}
+	game := NewGame(modelGame)
+	if !strings.Contains(game.String(), "[Variant \"Atomic\"]") ||
+		strings.Contains(game.String(), "[FEN ") {
+		t.Error("Expected an Atomic variant tag got ", game.String())
+	}
+	reread, err := ReadAll(strings.NewReader(game.String()))
+	if err != nil || len(reread) != 1 ||
+		reread[0].Game.Variant() != model.Atomic ||
+		reread[0].Game.FEN() != modelGame.FEN() {
+		t.Error("Expected to reread the Atomic game got ", err)
+	}
+}
+
+func TestDropRoundTrip(t *testing.T) {
+	modelGame := model.NewGameVariant(model.Crazyhouse)
+	for _, san := range []string{"e4", "d5", "exd5", "Qxd5", "P@e4"} {
+		if err := modelGame.MoveSAN(san); err != nil {
+			t.Fatal(san, err)
+		}
+	}
+	game := NewGame(modelGame)
+	if !strings.Contains(game.String(), "3. P@e4") {
+		t.Error("Expected the drop in the movetext got ", game.String())
+	}
+	reread, err := ReadAll(strings.NewReader(game.String()))
+	if err != nil || len(reread) != 1 ||
+		reread[0].Game.FEN() != modelGame.FEN() {
+		t.Error("Expected to reread the Crazyhouse game got ", err)
+	}
+}
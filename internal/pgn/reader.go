@@ -0,0 +1,402 @@
+package pgn
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/Ekotlikoff/gochess/internal/model"
+)
+
+const (
+	tokenSymbol = tokenKind(iota)
+	tokenString
+	tokenComment
+	tokenNAG
+	tokenPeriod
+	tokenAsterisk
+	tokenLeftBracket
+	tokenRightBracket
+	tokenLeftParen
+	tokenRightParen
+	tokenEOF
+)
+
+var suffixAnnotations = map[string]int{
+	"!": 1, "?": 2, "!!": 3, "??": 4, "!?": 5, "?!": 6,
+}
+
+type (
+	tokenKind uint8
+
+	token struct {
+		kind         tokenKind
+		text         string
+		line, column int
+	}
+
+	// Reader reads games from a PGN file
+	Reader struct {
+		r            *bufio.Reader
+		line, column int
+		lastColumn   int
+		peeked       *token
+	}
+)
+
+// NewReader create a reader of PGN games
+func NewReader(r io.Reader) *Reader {
+	return &Reader{r: bufio.NewReader(r), line: 1}
+}
+
+// ReadAll read every game in the PGN file
+func ReadAll(r io.Reader) ([]*Game, error) {
+	reader := NewReader(r)
+	games := []*Game{}
+	for {
+		game, err := reader.Read()
+		if err == io.EOF {
+			return games, nil
+		} else if err != nil {
+			return games, err
+		}
+		games = append(games, game)
+	}
+}
+
+// Read the next game, returning io.EOF when there are no games left
+func (reader *Reader) Read() (*Game, error) {
+	tok, err := reader.next()
+	if err != nil {
+		return nil, err
+	} else if tok.kind == tokenEOF {
+		return nil, io.EOF
+	}
+	tags := map[string]string{}
+	for tok.kind == tokenLeftBracket {
+		name, err := reader.expect(tokenSymbol, "tag name")
+		if err != nil {
+			return nil, err
+		}
+		value, err := reader.expect(tokenString, "tag value")
+		if err != nil {
+			return nil, err
+		}
+		if _, err := reader.expect(tokenRightBracket, "]"); err != nil {
+			return nil, err
+		}
+		tags[name.text] = value.text
+		if tok, err = reader.next(); err != nil {
+			return nil, err
+		}
+	}
+	reader.peeked = &tok
+	game, err := newGameFromTags(tags, tok)
+	if err != nil {
+		return nil, err
+	}
+	return game, reader.readMovetext(game)
+}
+
+func newGameFromTags(tags map[string]string, tok token) (*Game, error) {
+	variant := variantFromTag(tags["Variant"])
+	fen, ok := tags["FEN"]
+	if !ok {
+		fen = variant.StartFEN()
+	}
+	modelGame, err :=
+		newModelGame(fen, isChess960(tags["Variant"]), variant)
+	if err != nil {
+		return nil, &SyntaxError{tok.line, tok.column, err.Error()}
+	}
+	game := NewGame(modelGame)
+	for name, value := range tags {
+		game.Tags[name] = value
+	}
+	return game, nil
+}
+
+func (reader *Reader) readMovetext(game *Game) error {
+	ply := 0
+	for {
+		tok, err := reader.next()
+		if err != nil {
+			return err
+		}
+		switch tok.kind {
+		case tokenEOF:
+			return nil
+		case tokenLeftBracket:
+			// The next game began without a result token.
+			reader.peeked = &tok
+			return nil
+		case tokenAsterisk:
+			game.Tags["Result"] = "*"
+			return nil
+		case tokenPeriod:
+		case tokenComment:
+			game.Comments[ply] = strings.TrimSpace(
+				game.Comments[ply] + " " + tok.text)
+		case tokenNAG:
+			nag, ok := suffixAnnotations[tok.text]
+			if !ok {
+				nag, _ = strconv.Atoi(tok.text)
+			}
+			game.NAGs[ply] = append(game.NAGs[ply], nag)
+		case tokenLeftParen:
+			if err := reader.skipVariation(); err != nil {
+				return err
+			}
+		case tokenSymbol:
+			if ok, err := setResult(game, tok); err != nil || ok {
+				return err
+			} else if isMoveNumber(tok.text) {
+				continue
+			}
+			moveRequest, err := game.Game.ParseSAN(tok.text)
+			if err == nil {
+				err = game.Game.Move(moveRequest)
+			}
+			if err != nil {
+				return &SyntaxError{tok.line, tok.column, err.Error()}
+			}
+			ply++
+		default:
+			return &SyntaxError{tok.line, tok.column,
+				fmt.Sprintf("unexpected %q", tok.text)}
+		}
+	}
+}
+
+// setResult set the game's result if the token is a result, which must agree
+// with the final position if that ended the game
+func setResult(game *Game, tok token) (bool, error) {
+	winner, draw := model.White, false
+	switch tok.text {
+	case "1-0":
+	case "0-1":
+		winner = model.Black
+	case "1/2-1/2":
+		winner, draw = model.Black, true
+	default:
+		return false, nil
+	}
+	if game.Game.GameOver() {
+		result := game.Game.Result()
+		if result.Draw != draw || (!draw && result.Winner != winner) {
+			return false, &SyntaxError{tok.line, tok.column, fmt.Sprintf(
+				"result %q contradicts the final position", tok.text)}
+		}
+	} else {
+		game.Game.SetGameResult(winner, draw, termination(game, draw))
+	}
+	game.Tags["Result"] = tok.text
+	return true, nil
+}
+
+// termination guess why a game ended when its final position does not say,
+// using the optional Termination tag when present
+func termination(game *Game, draw bool) model.Termination {
+	switch game.Tags["Termination"] {
+	case "time forfeit":
+		return model.Timeout
+	case "abandoned":
+		return model.Abandonment
+	}
+	if draw {
+		return model.Agreement
+	}
+	return model.Resignation
+}
+
+func isMoveNumber(text string) bool {
+	_, err := strconv.Atoi(text)
+	return err == nil
+}
+
+func (reader *Reader) skipVariation() error {
+	depth := 1
+	for depth > 0 {
+		tok, err := reader.next()
+		if err != nil {
+			return err
+		}
+		switch tok.kind {
+		case tokenLeftParen:
+			depth++
+		case tokenRightParen:
+			depth--
+		case tokenEOF:
+			return &SyntaxError{tok.line, tok.column, "unterminated variation"}
+		}
+	}
+	return nil
+}
+
+func (reader *Reader) expect(kind tokenKind, description string) (token, error) {
+	tok, err := reader.next()
+	if err != nil {
+		return tok, err
+	} else if tok.kind != kind {
+		return tok, &SyntaxError{tok.line, tok.column,
+			fmt.Sprintf("expected %s got %q", description, tok.text)}
+	}
+	return tok, nil
+}
+
+func (reader *Reader) readRune() (rune, error) {
+	r, _, err := reader.r.ReadRune()
+	if err != nil {
+		return r, err
+	}
+	reader.lastColumn = reader.column
+	if r == '\n' {
+		reader.line++
+		reader.column = 0
+	} else {
+		reader.column++
+	}
+	return r, nil
+}
+
+func (reader *Reader) unreadRune(r rune) {
+	reader.r.UnreadRune()
+	if r == '\n' {
+		reader.line--
+	}
+	reader.column = reader.lastColumn
+}
+
+// next get the next token, skipping whitespace and escaped lines
+func (reader *Reader) next() (token, error) {
+	if reader.peeked != nil {
+		tok := *reader.peeked
+		reader.peeked = nil
+		return tok, nil
+	}
+	for {
+		r, err := reader.readRune()
+		if err == io.EOF {
+			return token{tokenEOF, "", reader.line, reader.column}, nil
+		} else if err != nil {
+			return token{}, err
+		}
+		tok := token{line: reader.line, column: reader.column,
+			text: string(r)}
+		switch {
+		case unicode.IsSpace(r):
+			continue
+		case r == '%' && reader.column == 1:
+			if err := reader.skipLine(); err != nil {
+				return tok, err
+			}
+			continue
+		case r == ';':
+			tok.kind = tokenComment
+			tok.text, err = reader.readUntil('\n', tok)
+		case r == '{':
+			tok.kind = tokenComment
+			tok.text, err = reader.readUntil('}', tok)
+		case r == '"':
+			tok.kind = tokenString
+			tok.text, err = reader.readString(tok)
+		case r == '$':
+			tok.kind = tokenNAG
+			tok.text = reader.readWhile(unicode.IsDigit)
+		case r == '!' || r == '?':
+			tok.kind = tokenNAG
+			tok.text = string(r) + reader.readWhile(func(r rune) bool {
+				return r == '!' || r == '?'
+			})
+		case r == '.':
+			tok.kind = tokenPeriod
+		case r == '*':
+			tok.kind = tokenAsterisk
+		case r == '[':
+			tok.kind = tokenLeftBracket
+		case r == ']':
+			tok.kind = tokenRightBracket
+		case r == '(':
+			tok.kind = tokenLeftParen
+		case r == ')':
+			tok.kind = tokenRightParen
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '@':
+			tok.kind = tokenSymbol
+			tok.text += reader.readWhile(isSymbolRune)
+		default:
+			return tok, &SyntaxError{tok.line, tok.column,
+				fmt.Sprintf("unexpected character %q", r)}
+		}
+		return tok, err
+	}
+}
+
+func isSymbolRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) ||
+		strings.ContainsRune("_+#=:-/@", r)
+}
+
+func (reader *Reader) readWhile(accept func(rune) bool) string {
+	var sb strings.Builder
+	for {
+		r, err := reader.readRune()
+		if err != nil {
+			return sb.String()
+		} else if !accept(r) {
+			reader.unreadRune(r)
+			return sb.String()
+		}
+		sb.WriteRune(r)
+	}
+}
+
+func (reader *Reader) readUntil(end rune, start token) (string, error) {
+	var sb strings.Builder
+	for {
+		r, err := reader.readRune()
+		if err == io.EOF && end == '\n' {
+			return sb.String(), nil
+		} else if err == io.EOF {
+			return "", &SyntaxError{start.line, start.column,
+				"unterminated comment"}
+		} else if err != nil {
+			return "", err
+		} else if r == end {
+			return sb.String(), nil
+		}
+		sb.WriteRune(r)
+	}
+}
+
+func (reader *Reader) readString(start token) (string, error) {
+	var sb strings.Builder
+	escaped := false
+	for {
+		r, err := reader.readRune()
+		if err == io.EOF {
+			return "", &SyntaxError{start.line, start.column,
+				"unterminated string"}
+		} else if err != nil {
+			return "", err
+		}
+		switch {
+		case escaped:
+			sb.WriteRune(r)
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '"':
+			return sb.String(), nil
+		default:
+			sb.WriteRune(r)
+		}
+	}
+}
+
+func (reader *Reader) skipLine() error {
+	_, err := reader.readUntil('\n', token{})
+	return err
+}
@@ -7,8 +7,10 @@ import (
 	"log"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/Ekotlikoff/gochess/internal/model"
+	"github.com/Ekotlikoff/gochess/internal/pgn"
 	matchserver "github.com/Ekotlikoff/gochess/internal/server/backend/match"
 	gateway "github.com/Ekotlikoff/gochess/internal/server/frontend"
 )
@@ -21,6 +23,8 @@ func Serve(
 	mux.Handle("/http/match", makeSearchForMatchHandler(matchServer))
 	mux.Handle("/http/sync", makeSyncHandler())
 	mux.Handle("/http/async", makeAsyncHandler())
+	mux.Handle("/http/moves", makeLegalMovesHandler())
+	mux.Handle("/http/analysis", makeAnalysisHandler(matchServer))
 	log.Println("HTTP server listening on port", port, "...")
 	http.ListenAndServe(":"+strconv.Itoa(port), mux)
 }
@@ -37,23 +41,44 @@ func makeSearchForMatchHandler(
 		player := gateway.GetSession(w, r)
 		if player == nil {
 			return
+		} else if r.Method == "DELETE" {
+			if !matchServer.CancelSeek(player) {
+				// Return HTTP 409 if the player is not waiting for a match.
+				w.WriteHeader(http.StatusConflict)
+				return
+			}
+			player.SetSearchingForMatch(false)
+			return
 		} else if !player.GetSearchingForMatch() {
+			query := r.URL.Query()
+			seek, err := matchserver.NewSeek(
+				query.Get("timecontrol"), query.Get("variant"))
+			if err == nil && query.Get("rated") != "" {
+				seek.Rated, err = strconv.ParseBool(query.Get("rated"))
+			}
+			if err != nil {
+				log.Println("Invalid seek", err)
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
 			player.Reset()
 			player.SetSearchingForMatch(true)
-			matchServer.MatchPlayer(player)
+			if botLevel := query.Get("bot"); botLevel == "" {
+				matchServer.MatchPlayer(player, seek)
+			} else if err := matchServer.MatchPlayerWithBot(
+				player, botLevel, seek); err != nil {
+				log.Println("Failed to match with a bot", err)
+				player.SetSearchingForMatch(false)
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
 		}
 		ctx, cancel :=
 			context.WithTimeout(context.Background(), matchserver.PollingDefaultTimeout)
 		defer cancel()
 		if player.HasMatchStarted(ctx) {
 			player.SetSearchingForMatch(false)
-			matchResponse :=
-				matchserver.MatchedResponse{
-					Color:        player.Color(),
-					OpponentName: player.MatchedOpponentName(),
-					MaxTimeMs:    player.MatchMaxTimeMs(),
-				}
-			json.NewEncoder(w).Encode(matchResponse)
+			json.NewEncoder(w).Encode(player.MatchedResponse())
 		} else {
 			// Return HTTP 202 until match starts.
 			w.WriteHeader(http.StatusAccepted)
@@ -85,11 +110,12 @@ func makeSyncHandler() http.Handler {
 				w.WriteHeader(http.StatusBadRequest)
 				return
 			}
-			success := player.MakeMove(moveRequest)
-			if !success {
+			response := player.MakeMoveResponse(moveRequest)
+			if !response.MoveSuccess {
 				w.WriteHeader(http.StatusBadRequest)
 				return
 			}
+			json.NewEncoder(w).Encode(response)
 		}
 	}
 	return http.HandlerFunc(handler)
@@ -130,3 +156,96 @@ func makeAsyncHandler() http.Handler {
 	}
 	return http.HandlerFunc(handler)
 }
+
+// makeLegalMovesHandler answer a query for the legal moves in the player's
+// match, limited to the piece on the square given by the file and rank query
+// parameters if they are set
+func makeLegalMovesHandler() http.Handler {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		player := gateway.GetSession(w, r)
+		if player == nil {
+			return
+		}
+		var from *model.Position
+		query := r.URL.Query()
+		if query.Get("file") != "" || query.Get("rank") != "" {
+			file, fileErr := strconv.ParseUint(query.Get("file"), 10, 8)
+			rank, rankErr := strconv.ParseUint(query.Get("rank"), 10, 8)
+			if fileErr != nil || rankErr != nil || file > 7 || rank > 7 {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			from = &model.Position{File: uint8(file), Rank: uint8(rank)}
+		}
+		legalMoves, err := player.LegalMoves(from)
+		if err != nil {
+			// Return HTTP 404 if the player is not in a match.
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(legalMoves)
+	}
+	return http.HandlerFunc(handler)
+}
+
+// makeAnalysisHandler answer a request to analyze the player's game in
+// progress or last finished game, or on POST the first game of the PGN body.
+// The depth and movetime query parameters, in plies and milliseconds, limit
+// the search of each position and may not be above the analysis maximums.
+func makeAnalysisHandler(
+	matchServer *matchserver.MatchingServer,
+) http.Handler {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		player := gateway.GetSession(w, r)
+		if player == nil {
+			return
+		}
+		query := r.URL.Query()
+		depth, moveTimeMs := 0, 0
+		var depthErr, moveTimeErr error
+		if query.Get("depth") != "" {
+			depth, depthErr = strconv.Atoi(query.Get("depth"))
+		}
+		if query.Get("movetime") != "" {
+			moveTimeMs, moveTimeErr = strconv.Atoi(query.Get("movetime"))
+		}
+		if depthErr != nil || moveTimeErr != nil || depth < 0 ||
+			moveTimeMs < 0 || depth > matchserver.AnalysisMaxDepth ||
+			moveTimeMs > int(matchserver.AnalysisMaxMoveTime.Milliseconds()) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		var game *model.Game
+		switch r.Method {
+		case "GET":
+			var err error
+			if game, err = player.ReviewGame(); err != nil {
+				// Return HTTP 404 if the player has no game to review.
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+		case "POST":
+			games, err := pgn.ReadAll(r.Body)
+			if err != nil || len(games) == 0 {
+				log.Println("Failed to parse PGN body ", err)
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			game = games[0].Game
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		analysis, err := matchServer.AnalyzeGame(r.Context(), game, depth,
+			time.Duration(moveTimeMs)*time.Millisecond)
+		if err != nil {
+			log.Println("Failed to analyze the game ", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(analysis)
+	}
+	return http.HandlerFunc(handler)
+}
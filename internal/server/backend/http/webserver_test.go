@@ -11,6 +11,7 @@ import (
 	"net/url"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/Ekotlikoff/gochess/internal/model"
 	matchserver "github.com/Ekotlikoff/gochess/internal/server/backend/match"
@@ -24,6 +25,8 @@ var (
 	serverSession      *httptest.Server
 	serverSync         *httptest.Server
 	serverAsync        *httptest.Server
+	serverMoves        *httptest.Server
+	serverAnalysis     *httptest.Server
 	serverMatchTimeout *httptest.Server
 )
 
@@ -31,9 +34,11 @@ func init() {
 	serverSession = httptest.NewServer(http.HandlerFunc(gateway.StartSession))
 	serverAsync = httptest.NewServer(http.Handler(makeAsyncHandler()))
 	serverSync = httptest.NewServer(http.Handler(makeSyncHandler()))
+	serverMoves = httptest.NewServer(http.Handler(makeLegalMovesHandler()))
 	matchingServer := matchserver.NewMatchingServer()
 	serverMatch = httptest.NewServer(
 		makeSearchForMatchHandler(&matchingServer))
+	serverAnalysis = httptest.NewServer(makeAnalysisHandler(&matchingServer))
 	exitChan := make(chan bool, 1)
 	close(exitChan)
 	matchingServer.StartMatchServers(10, exitChan)
@@ -140,7 +145,8 @@ func TestHTTPServerDraw(t *testing.T) {
 	responseAsync = matchserver.ResponseAsync{}
 	json.NewDecoder(resp.Body).Decode(&responseAsync)
 	if !responseAsync.GameOver || responseAsync.Winner != "" ||
-		!responseAsync.Draw || responseAsync.Resignation {
+		!responseAsync.Draw || responseAsync.Resignation ||
+		responseAsync.Termination != model.Agreement {
 		t.Error("Expected gameover got ", responseAsync)
 	}
 }
@@ -159,7 +165,8 @@ func TestHTTPServerResign(t *testing.T) {
 	responseAsync := matchserver.ResponseAsync{}
 	json.NewDecoder(resp.Body).Decode(&responseAsync)
 	if !responseAsync.GameOver || responseAsync.Winner != blackName ||
-		!responseAsync.Resignation {
+		!responseAsync.Resignation ||
+		responseAsync.Termination != model.Resignation {
 		t.Error("Expected gameover got ", responseAsync)
 	}
 }
@@ -175,11 +182,171 @@ func TestHTTPServerTimeout(t *testing.T) {
 	responseAsync := matchserver.ResponseAsync{}
 	json.NewDecoder(resp.Body).Decode(&responseAsync)
 	if !responseAsync.GameOver || responseAsync.Winner != blackName ||
-		!responseAsync.Timeout {
+		!responseAsync.Timeout || responseAsync.Termination != model.Timeout {
 		t.Error("Expected timeout got ", responseAsync)
 	}
 }
 
+func TestHTTPServerLegalMoves(t *testing.T) {
+	if debug {
+		fmt.Println("Test LegalMoves")
+	}
+	black, white, _, _ := createMatch(serverMatch)
+	sendMove(white, serverSync, 4, 1, 0, 2)
+	resp, _ := black.Get(serverMoves.URL)
+	legalMoves := matchserver.LegalMovesResponse{}
+	json.NewDecoder(resp.Body).Decode(&legalMoves)
+	resp.Body.Close()
+	if legalMoves.Turn != model.Black || len(legalMoves.Moves) != 20 ||
+		legalMoves.InCheck || legalMoves.Checkmate || legalMoves.Stalemate {
+		t.Error("Expected black's 20 moves got ", legalMoves)
+	}
+	resp, _ = black.Get(serverMoves.URL + "?file=6&rank=7")
+	legalMoves = matchserver.LegalMovesResponse{}
+	json.NewDecoder(resp.Body).Decode(&legalMoves)
+	resp.Body.Close()
+	if len(legalMoves.Moves) != 2 ||
+		legalMoves.Moves[0].Position != (model.Position{File: 6, Rank: 7}) {
+		t.Error("Expected the knight's two moves got ", legalMoves)
+	}
+	resp, _ = black.Get(serverMoves.URL + "?file=8&rank=7")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Error("Expected a bad request got ", resp.StatusCode)
+	}
+}
+
+func TestHTTPServerAnalysis(t *testing.T) {
+	if debug {
+		fmt.Println("Test Analysis")
+	}
+	black, white, _, _ := createMatch(serverMatch)
+	sendMove(white, serverSync, 4, 1, 0, 2)
+	sendMove(black, serverSync, 4, 6, 0, -2)
+	resp, _ := white.Get(serverAnalysis.URL + "?depth=2")
+	analysis := matchserver.GameAnalysis{}
+	json.NewDecoder(resp.Body).Decode(&analysis)
+	resp.Body.Close()
+	if len(analysis.Moves) != 2 || analysis.Moves[1].Move != "e7e5" ||
+		analysis.Moves[1].Depth != 2 {
+		t.Error("Expected the game in progress to be analyzed got ", analysis)
+	}
+	resp, _ = white.Post(serverAnalysis.URL+"?depth=2",
+		"application/x-chess-pgn",
+		strings.NewReader("1. e4 e5 2. Qh5 Nc6 3. Bc4 Nf6 4. Qxf7# 1-0"))
+	analysis = matchserver.GameAnalysis{}
+	json.NewDecoder(resp.Body).Decode(&analysis)
+	resp.Body.Close()
+	if len(analysis.Moves) != 7 ||
+		analysis.Moves[5].Classification != matchserver.Blunder {
+		t.Error("Expected Nf6 to be a blunder got ", analysis)
+	}
+	for _, query := range []string{"?depth=x", "?depth=64", "?movetime=60000"} {
+		resp, _ = white.Get(serverAnalysis.URL + query)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Error("Expected a bad request for ", query, " got ",
+				resp.StatusCode)
+		}
+	}
+	jar, _ := cookiejar.New(&cookiejar.Options{})
+	client := &http.Client{Jar: jar}
+	startSession(client, "player3")
+	resp, _ = client.Get(serverAnalysis.URL)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Error("Expected no game to review got ", resp.StatusCode)
+	}
+}
+
+func TestHTTPServerMatchBotLevel(t *testing.T) {
+	if debug {
+		fmt.Println("Test MatchBotLevel")
+	}
+	jar, _ := cookiejar.New(&cookiejar.Options{})
+	client := &http.Client{Jar: jar}
+	startSession(client, "player1")
+	resp, _ := client.Get(serverMatch.URL + "?bot=grandmaster")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Error("Expected an unknown bot level to be rejected got ",
+			resp.StatusCode)
+	}
+	// The test matching server has no engine.
+	resp, _ = client.Get(serverMatch.URL + "?bot=casual")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Error("Expected bot matching to be disabled got ", resp.StatusCode)
+	}
+}
+
+func TestHTTPServerMatchInvalidSeek(t *testing.T) {
+	if debug {
+		fmt.Println("Test MatchInvalidSeek")
+	}
+	jar, _ := cookiejar.New(&cookiejar.Options{})
+	client := &http.Client{Jar: jar}
+	startSession(client, "player1")
+	for _, query := range []string{"?timecontrol=x", "?variant=Shogi",
+		"?timecontrol=180%2B2&variant=Bughouse"} {
+		resp, _ := client.Get(serverMatch.URL + query)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Error("Expected ", query, " to be rejected got ",
+				resp.StatusCode)
+		}
+	}
+}
+
+func TestHTTPServerCancelMatch(t *testing.T) {
+	if debug {
+		fmt.Println("Test CancelMatch")
+	}
+	clients := []*http.Client{}
+	for _, name := range []string{"cancel1", "cancel2", "cancel3"} {
+		jar, _ := cookiejar.New(&cookiejar.Options{})
+		client := &http.Client{Jar: jar}
+		startSession(client, name)
+		clients = append(clients, client)
+	}
+	cancelMatch := func() int {
+		req, _ := http.NewRequest("DELETE", serverMatch.URL, nil)
+		resp, _ := clients[0].Do(req)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	if status := cancelMatch(); status != http.StatusConflict {
+		t.Error("Expected no match to cancel got ", status)
+	}
+	go func() {
+		if resp, err := clients[0].Get(serverMatch.URL); err == nil {
+			resp.Body.Close()
+		}
+	}()
+	status := cancelMatch()
+	for tries := 0; status != http.StatusOK && tries < 50; tries++ {
+		time.Sleep(time.Millisecond)
+		status = cancelMatch()
+	}
+	if status != http.StatusOK {
+		t.Fatal("Expected the match request to be cancelled got ", status)
+	}
+	// The cancelled player would be paired first were they still waiting.
+	wait := make(chan struct{})
+	var resp *http.Response
+	go func() { resp, _ = clients[2].Get(serverMatch.URL); close(wait) }()
+	resp2, _ := clients[1].Get(serverMatch.URL)
+	<-wait
+	defer resp.Body.Close()
+	defer resp2.Body.Close()
+	matchedResponse := matchserver.MatchedResponse{}
+	json.NewDecoder(resp2.Body).Decode(&matchedResponse)
+	if matchedResponse.OpponentName != "cancel3" {
+		t.Error("Expected cancel2 to be matched with cancel3 got ",
+			matchedResponse.OpponentName)
+	}
+}
+
 func createMatch(testMatchServer *httptest.Server) (
 	black *http.Client, white *http.Client, blackName string, whiteName string,
 ) {
@@ -236,11 +403,16 @@ func startSession(client *http.Client, username string) {
 	serverMatchURL, _ := url.Parse(serverMatch.URL)
 	serverSyncURL, _ := url.Parse(serverSync.URL)
 	serverAsyncURL, _ := url.Parse(serverAsync.URL)
+	serverMovesURL, _ := url.Parse(serverMoves.URL)
+	serverAnalysisURL, _ := url.Parse(serverAnalysis.URL)
 	// Ensure that the various test handler URLs get passed the session cookie
 	// by the client.
 	client.Jar.SetCookies(serverMatchURL, client.Jar.Cookies(serverSessionURL))
 	client.Jar.SetCookies(serverSyncURL, client.Jar.Cookies(serverSessionURL))
 	client.Jar.SetCookies(serverAsyncURL, client.Jar.Cookies(serverSessionURL))
+	client.Jar.SetCookies(serverMovesURL, client.Jar.Cookies(serverSessionURL))
+	client.Jar.SetCookies(serverAnalysisURL,
+		client.Jar.Cookies(serverSessionURL))
 	if err == nil {
 		defer resp.Body.Close()
 	}
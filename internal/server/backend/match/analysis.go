@@ -0,0 +1,294 @@
+package matchserver
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	pb "github.com/Ekotlikoff/gochess/api"
+	"github.com/Ekotlikoff/gochess/internal/engine"
+	"github.com/Ekotlikoff/gochess/internal/model"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// inaccuracyLoss, mistakeLoss and blunderLoss are the least a move must
+	// lose its player in centipawns to be an inaccuracy, a mistake or a
+	// blunder
+	inaccuracyLoss = 50
+	mistakeLoss    = 100
+	blunderLoss    = 300
+	// evalCap bounds evaluations when classifying moves, so that a forced
+	// mate is worth the same as a decisive advantage and going from one
+	// winning evaluation to a lesser one is no mistake
+	evalCap = 1000
+	// analysisDefaultMoveTime is how long each position is searched for when
+	// the analysis is given no limits
+	analysisDefaultMoveTime = 500 * time.Millisecond
+)
+
+const (
+	// AnalysisMaxDepth is the deepest each position of an analysis may be
+	// searched, whatever the bots are limited to
+	AnalysisMaxDepth = 20
+	// AnalysisMaxMoveTime is the longest each position of an analysis may be
+	// searched for, also the limit of a search given only a depth
+	AnalysisMaxMoveTime = 5 * time.Second
+)
+
+// MoveClassification is how much a move lost its player, by the engine's
+// evaluation
+type MoveClassification string
+
+const (
+	// Good is a move that lost less than an inaccuracy
+	Good = MoveClassification("good")
+	// Inaccuracy is a move that lost at least inaccuracyLoss centipawns
+	Inaccuracy = MoveClassification("inaccuracy")
+	// Mistake is a move that lost at least mistakeLoss centipawns
+	Mistake = MoveClassification("mistake")
+	// Blunder is a move that lost at least blunderLoss centipawns
+	Blunder = MoveClassification("blunder")
+)
+
+type (
+	// PositionEval is an engine's evaluation of a position in centipawns
+	// from White's point of view, or the moves to a forced mate if Mate is
+	// set, positive if White mates. A finished game is worth nothing if drawn
+	// and evalCap to the winner otherwise. The best line is in UCI notation.
+	PositionEval struct {
+		Eval     int
+		Mate     int
+		BestLine []string
+		Depth    int
+	}
+
+	// MoveAnalysis is a move in UCI notation and the evaluation of the
+	// position it reached, with the best move from the position before it
+	// and how much the move lost its player
+	MoveAnalysis struct {
+		Ply  int
+		Move string
+		PositionEval
+		BestMove       string
+		Loss           int
+		Classification MoveClassification
+	}
+
+	// GameAnalysis is the evaluation of a game's start position and of each
+	// of its moves
+	GameAnalysis struct {
+		Start PositionEval
+		Moves []MoveAnalysis
+	}
+
+	// analyzer evaluates the positions of one game, with a remote engine if
+	// one can analyze and the built-in engine otherwise
+	analyzer struct {
+		matchingServer *MatchingServer
+		request        *pb.AnalysisRequest
+		depth          int
+		moveTime       time.Duration
+		builtin        *engine.Engine
+	}
+)
+
+// AnalyzeGame evaluate the game's start position and each position reached,
+// classifying each move by how much it lost its player. Each position is
+// searched to the depth and for the move time where given, within
+// AnalysisMaxDepth and AnalysisMaxMoveTime.
+func (matchingServer *MatchingServer) AnalyzeGame(
+	ctx context.Context, game *model.Game, depth int, moveTime time.Duration,
+) (GameAnalysis, error) {
+	if game.Variant() == model.Bughouse {
+		// The pieces passed by a partner are not in the moves.
+		return GameAnalysis{}, errors.New(
+			"Bughouse games cannot be analyzed move by move")
+	} else if depth > AnalysisMaxDepth || moveTime > AnalysisMaxMoveTime {
+		return GameAnalysis{}, errors.New(
+			"analysis limits are above the maximum")
+	}
+	if depth <= 0 && moveTime <= 0 {
+		moveTime = analysisDefaultMoveTime
+	}
+	position, err := newAnalysisGame(game)
+	if err != nil {
+		return GameAnalysis{}, err
+	}
+	analyzer := analyzer{
+		matchingServer: matchingServer, depth: depth, moveTime: moveTime,
+		request: &pb.AnalysisRequest{
+			Fen: game.StartFEN(), Chess960: game.Chess960(),
+			Variant: game.Variant().Name(), SearchDepth: uint32(depth),
+			MoveTimeMs: uint32(moveTime.Milliseconds()),
+		},
+	}
+	if analyzer.moveTime <= 0 {
+		analyzer.moveTime = AnalysisMaxMoveTime
+	}
+	start, err := analyzer.evaluate(ctx, position)
+	if err != nil {
+		return GameAnalysis{}, err
+	}
+	analysis := GameAnalysis{Start: start, Moves: []MoveAnalysis{}}
+	before := start
+	for i, move := range game.MoveHistory() {
+		mover := position.Turn()
+		if err := position.Move(move); err != nil {
+			return GameAnalysis{}, err
+		}
+		analyzer.request.Moves = append(analyzer.request.Moves, move.UCI())
+		after, err := analyzer.evaluate(ctx, position)
+		if err != nil {
+			return GameAnalysis{}, err
+		}
+		moveAnalysis := MoveAnalysis{
+			Ply: i + 1, Move: move.UCI(), PositionEval: after,
+		}
+		if len(before.BestLine) > 0 {
+			moveAnalysis.BestMove = before.BestLine[0]
+		}
+		// Playing the best move loses nothing whatever a deeper look finds.
+		if moveAnalysis.BestMove != moveAnalysis.Move {
+			moveAnalysis.Loss = cappedEval(before, mover) -
+				cappedEval(after, mover)
+			if moveAnalysis.Loss < 0 {
+				moveAnalysis.Loss = 0
+			}
+		}
+		moveAnalysis.Classification = classifyMove(moveAnalysis.Loss)
+		analysis.Moves = append(analysis.Moves, moveAnalysis)
+		before = after
+	}
+	return analysis, nil
+}
+
+// newAnalysisGame create a game from the game's start position to replay its
+// moves on
+func newAnalysisGame(game *model.Game) (*model.Game, error) {
+	var position *model.Game
+	var err error
+	if game.Chess960() {
+		position, err = model.NewGameChess960FromFEN(game.StartFEN())
+	} else {
+		position, err = model.NewGameVariantFromFEN(game.Variant(),
+			game.StartFEN())
+	}
+	if err != nil {
+		return nil, err
+	}
+	position.SetDrawRules(model.FIDEDraws)
+	return position, nil
+}
+
+// evaluate the position, which the request's moves reach
+func (analyzer *analyzer) evaluate(
+	ctx context.Context, position *model.Game,
+) (PositionEval, error) {
+	if position.GameOver() {
+		result := position.Result()
+		if result.Draw {
+			return PositionEval{}, nil
+		} else if result.Winner == model.White {
+			return PositionEval{Eval: evalCap}, nil
+		}
+		return PositionEval{Eval: -evalCap}, nil
+	}
+	if eval, ok := analyzer.evaluateRemote(ctx); ok {
+		return eval, nil
+	} else if ctx.Err() != nil {
+		return PositionEval{}, ctx.Err()
+	}
+	if analyzer.builtin == nil {
+		builtin := analyzer.matchingServer.newBuiltinEngine()
+		analyzer.builtin = builtin.newEngine(analyzer.depth, analyzer.moveTime)
+	}
+	result, err := analyzer.builtin.SearchContext(ctx, position,
+		analyzer.moveTime)
+	if err != nil {
+		return PositionEval{}, err
+	} else if ctx.Err() != nil {
+		return PositionEval{}, ctx.Err()
+	}
+	sign := 1
+	if position.Turn() == model.Black {
+		sign = -1
+	}
+	eval := PositionEval{
+		Eval: sign * result.Score, Mate: sign * engine.MateIn(result.Score),
+		BestLine: []string{}, Depth: result.Depth,
+	}
+	if eval.Mate != 0 {
+		eval.Eval = 0
+	}
+	for _, move := range result.Line {
+		eval.BestLine = append(eval.BestLine, move.UCI())
+	}
+	return eval, nil
+}
+
+// evaluateRemote evaluate the request's position with one of the pool's
+// engines, if one is healthy and can analyze
+func (analyzer *analyzer) evaluateRemote(
+	ctx context.Context,
+) (PositionEval, bool) {
+	pool := analyzer.matchingServer.enginePool
+	if pool == nil {
+		return PositionEval{}, false
+	}
+	remote, client := pool.acquire()
+	if remote == nil {
+		return PositionEval{}, false
+	}
+	defer pool.release(remote)
+	analysis, err := client.Analyze(ctx, analyzer.request)
+	if err != nil {
+		// Engines that cannot analyze can still play.
+		if status.Code(err) != codes.Unimplemented && ctx.Err() == nil {
+			log.Println("Failed to analyze with the engine at", remote.addr,
+				err)
+		}
+		if status.Code(err) == codes.Unavailable {
+			pool.fail(remote)
+		}
+		return PositionEval{}, false
+	}
+	eval := PositionEval{
+		Eval: int(analysis.ScoreCp), Mate: int(analysis.Mate),
+		BestLine: analysis.BestLine, Depth: int(analysis.Depth),
+	}
+	if eval.BestLine == nil {
+		eval.BestLine = []string{}
+	}
+	return eval, true
+}
+
+// cappedEval get the evaluation for the color, within evalCap
+func cappedEval(eval PositionEval, color model.Color) int {
+	value := eval.Eval
+	if eval.Mate > 0 || value > evalCap {
+		value = evalCap
+	} else if eval.Mate < 0 || value < -evalCap {
+		value = -evalCap
+	}
+	if color == model.Black {
+		return -value
+	}
+	return value
+}
+
+// classifyMove get the classification of a move that lost its player the
+// centipawns
+func classifyMove(loss int) MoveClassification {
+	switch {
+	case loss >= blunderLoss:
+		return Blunder
+	case loss >= mistakeLoss:
+		return Mistake
+	case loss >= inaccuracyLoss:
+		return Inaccuracy
+	}
+	return Good
+}
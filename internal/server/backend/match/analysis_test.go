@@ -0,0 +1,139 @@
+package matchserver
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Ekotlikoff/gochess/internal/model"
+)
+
+// scholarsMate is a game lost by the blunder Nf6 on the sixth ply
+var scholarsMate = []string{"e2e4", "e7e5", "d1h5", "b8c6", "f1c4", "g8f6",
+	"h5f7"}
+
+func newScholarsMate(t *testing.T) *model.Game {
+	game := model.NewGame()
+	for _, move := range scholarsMate {
+		if err := game.MoveUCI(move); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return game
+}
+
+// checkScholarsMateAnalysis check the analysis finds the blunder and the
+// mate that punishes it
+func checkScholarsMateAnalysis(t *testing.T, analysis GameAnalysis) {
+	if len(analysis.Moves) != len(scholarsMate) ||
+		len(analysis.Start.BestLine) == 0 {
+		t.Fatal("Expected every move to be analyzed got ", analysis)
+	}
+	blunder := analysis.Moves[5]
+	if blunder.Ply != 6 || blunder.Move != "g8f6" || blunder.Mate != 1 ||
+		blunder.Classification != Blunder || blunder.Loss < blunderLoss {
+		t.Error("Expected Nf6 to be a blunder got ", blunder)
+	}
+	mate := analysis.Moves[6]
+	if mate.BestMove != "h5f7" || mate.Loss != 0 ||
+		mate.Classification != Good || mate.Eval != evalCap ||
+		len(mate.BestLine) != 0 {
+		t.Error("Expected Qxf7 to be the best move and mate got ", mate)
+	}
+}
+
+func TestAnalyzeGameBuiltin(t *testing.T) {
+	matchingServer := NewMatchingServer()
+	analysis, err := matchingServer.AnalyzeGame(context.Background(),
+		newScholarsMate(t), 3, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkScholarsMateAnalysis(t, analysis)
+	if analysis.Moves[0].Depth != 3 {
+		t.Error("Expected a depth 3 search got ", analysis.Moves[0].Depth)
+	}
+}
+
+func TestAnalyzeGameRemote(t *testing.T) {
+	addr := startFakeEngine(t, nil, false)
+	matchingServer := NewMatchingServerWithEngine(addr, time.Minute,
+		time.Second)
+	defer matchingServer.enginePool.close()
+	analysis, err := matchingServer.AnalyzeGame(context.Background(),
+		newScholarsMate(t), 3, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkScholarsMateAnalysis(t, analysis)
+}
+
+func TestAnalyzeGameUnanalyzable(t *testing.T) {
+	matchingServer := NewMatchingServer()
+	game := model.NewGameVariant(model.Bughouse)
+	if _, err := matchingServer.AnalyzeGame(context.Background(), game, 1,
+		0); err == nil {
+		t.Error("Expected Bughouse games not to be analyzed")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	addr := startFakeEngine(t, nil, false)
+	remoteServer := NewMatchingServerWithEngine(addr, time.Minute,
+		time.Second)
+	defer remoteServer.enginePool.close()
+	if _, err := remoteServer.AnalyzeGame(ctx, model.NewGame(), 1,
+		0); err == nil {
+		t.Error("Expected a cancelled analysis to fail")
+	}
+}
+
+func TestAnalyzeGameLimits(t *testing.T) {
+	matchingServer := NewMatchingServer()
+	if _, err := matchingServer.AnalyzeGame(context.Background(),
+		model.NewGame(), AnalysisMaxDepth+1, 0); err == nil {
+		t.Error("Expected a search deeper than the maximum to fail")
+	}
+	if _, err := matchingServer.AnalyzeGame(context.Background(),
+		model.NewGame(), 0, AnalysisMaxMoveTime+time.Millisecond); err == nil {
+		t.Error("Expected a search longer than the maximum to fail")
+	}
+	// A deep search given no move time still stops with the context.
+	ctx, cancel := context.WithTimeout(context.Background(),
+		50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := matchingServer.AnalyzeGame(ctx, newScholarsMate(t),
+		AnalysisMaxDepth, 0); err != context.DeadlineExceeded {
+		t.Error("Expected the cancelled analysis to fail got ", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Error("Expected the search to stop with the context got ", elapsed)
+	}
+}
+
+func TestClassifyMove(t *testing.T) {
+	for loss, expected := range map[int]MoveClassification{
+		0: Good, 49: Good, 50: Inaccuracy, 99: Inaccuracy, 100: Mistake,
+		299: Mistake, 300: Blunder, 2000: Blunder,
+	} {
+		if classifyMove(loss) != expected {
+			t.Error("Expected ", expected, " got ", classifyMove(loss))
+		}
+	}
+}
+
+func TestPlayerReviewGame(t *testing.T) {
+	player := NewPlayer("player")
+	if _, err := player.ReviewGame(); err == nil {
+		t.Error("Expected no game to review")
+	}
+	match := NewMatch(player, NewPlayer("opponent"), 1000)
+	player.SetMatch(&match)
+	if game, _ := player.ReviewGame(); game != match.game {
+		t.Error("Expected the game in progress got ", game)
+	}
+	player.Reset()
+	if game, _ := player.ReviewGame(); game != match.game {
+		t.Error("Expected the last finished game got ", game)
+	}
+}
@@ -0,0 +1,77 @@
+package matchserver
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+)
+
+// BotLevel is a bot difficulty preset. The engine searches no deeper than
+// the depth and for no longer than the move time, and plays a random legal
+// move in place of its best one at the blunder rate, aiming for the Elo
+// rating.
+type BotLevel struct {
+	Name        string
+	BotName     string
+	Depth       int
+	MoveTime    time.Duration
+	BlunderRate float64
+	Elo         int
+}
+
+// BotLevels are the bot difficulty presets from weakest to strongest
+var BotLevels = []BotLevel{
+	{Name: "beginner", BotName: "jessicabot", Depth: 1,
+		MoveTime: 100 * time.Millisecond, BlunderRate: 0.3, Elo: 800},
+	{Name: "casual", BotName: "cherrybot", Depth: 2,
+		MoveTime: 250 * time.Millisecond, BlunderRate: 0.15, Elo: 1200},
+	{Name: "intermediate", BotName: "gumdropbot", Depth: 3,
+		MoveTime: 500 * time.Millisecond, BlunderRate: 0.08, Elo: 1500},
+	{Name: "advanced", BotName: "rolandbot", Depth: 4,
+		MoveTime: time.Second, BlunderRate: 0.03, Elo: 1800},
+	{Name: "expert", BotName: "pumpkinbot", Depth: 6,
+		MoveTime: 2 * time.Second, BlunderRate: 0, Elo: 2200},
+}
+
+// BotLevelFromName get the bot difficulty preset with the name, ignoring
+// case
+func BotLevelFromName(name string) (BotLevel, error) {
+	for _, level := range BotLevels {
+		if strings.EqualFold(level.Name, name) {
+			return level, nil
+		}
+	}
+	return BotLevel{}, fmt.Errorf("unknown bot level %q", name)
+}
+
+// randomBotLevel get a random bot difficulty preset, for players matched
+// with a bot because no human opponent was found in time
+func randomBotLevel() BotLevel {
+	return BotLevels[rand.Intn(len(BotLevels))]
+}
+
+// MatchPlayerWithBot queue the player to be matched with a bot of the named
+// difficulty level for the seek's game as soon as a match server is idle,
+// rather than with a human opponent
+func (matchingServer *MatchingServer) MatchPlayerWithBot(
+	player *Player, levelName string, seek Seek,
+) error {
+	level, err := BotLevelFromName(levelName)
+	if err != nil {
+		return err
+	} else if !matchingServer.botsAvailable() {
+		return errors.New("bot matching is disabled")
+	}
+	matchingServer.queue(player, seek, &level)
+	return nil
+}
+
+// startBot create a player for a bot of the level and start its engine
+// session
+func (matchingServer *MatchingServer) startBot(level BotLevel) *Player {
+	botPlayer := NewPlayer(level.BotName)
+	go matchingServer.engineSession(botPlayer, level)
+	return botPlayer
+}
@@ -0,0 +1,231 @@
+package matchserver
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/Ekotlikoff/gochess/internal/model"
+)
+
+// bughouseStartTimeout is how long the first board of a Bughouse game waits
+// for the second board's players before it is aborted
+const bughouseStartTimeout = 2 * time.Minute
+
+type (
+	// bughouseLink links the two boards of a Bughouse game. The white player
+	// on each board partners the black player on the other, so a captured
+	// piece goes to the pocket of its own color on the other board.
+	bughouseLink struct {
+		games    [2]*model.Game
+		players  [2][2]*Player
+		gameOver [2]chan struct{}
+		// started is closed once both boards' players are matched, so that
+		// both boards start together. The first board is aborted if it waits
+		// longer than startTimeout.
+		started      chan struct{}
+		startTimeout time.Duration
+		// joined is set once the second board is taken.
+		joined bool
+		// over is closed with the result once the first board ends.
+		over   chan struct{}
+		once   sync.Once
+		result bughouseResult
+		mutex  sync.Mutex
+	}
+
+	bughouseResult struct {
+		board       int
+		winner      model.Color
+		draw        bool
+		termination model.Termination
+	}
+)
+
+// CreateBughouseMatchGenerator create a generator that pairs the matches it
+// creates into Bughouse games of two linked boards, with a custom match
+// length in seconds
+func CreateBughouseMatchGenerator(matchPlayerTimeSeconds int) MatchGenerator {
+	return CreateTimeControlBughouseMatchGenerator(
+		NewTimeControl(int64(matchPlayerTimeSeconds*1000), 0))
+}
+
+// CreateTimeControlBughouseMatchGenerator create a generator that pairs the
+// matches it creates into Bughouse games of two linked boards, with the time
+// control. Each capture is passed to the captor's partner on the other
+// board, and the first board to end decides both. The first board waits for
+// the second's players before it starts, so at least two matches must be
+// played at once, and is aborted if they do not come within
+// bughouseStartTimeout or one of its players leaves.
+func CreateTimeControlBughouseMatchGenerator(
+	timeControl TimeControl,
+) MatchGenerator {
+	var mutex sync.Mutex
+	var pending *bughouseLink
+	return func(p1 *Player, p2 *Player) Match {
+		mutex.Lock()
+		defer mutex.Unlock()
+		link, board := pending, 1
+		if link == nil || !link.join() {
+			link, board = newBughouseLink(), 0
+			pending = link
+		} else {
+			pending = nil
+		}
+		r := rand.New(rand.NewSource(time.Now().UnixNano()))
+		if r.Intn(2) > 0 {
+			return link.newMatch(board, p1, p2, timeControl)
+		}
+		return link.newMatch(board, p2, p1, timeControl)
+	}
+}
+
+func newBughouseLink() *bughouseLink {
+	return &bughouseLink{
+		games: [2]*model.Game{
+			model.NewGameVariant(model.Bughouse),
+			model.NewGameVariant(model.Bughouse),
+		},
+		started: make(chan struct{}), startTimeout: bughouseStartTimeout,
+		over: make(chan struct{}),
+	}
+}
+
+// join take the second board for its players, false if the game was aborted
+// before they came
+func (link *bughouseLink) join() bool {
+	link.mutex.Lock()
+	defer link.mutex.Unlock()
+	if link.joined || link.ended() {
+		return false
+	}
+	link.joined = true
+	return true
+}
+
+// newMatch create the match on the board
+func (link *bughouseLink) newMatch(
+	board int, black *Player, white *Player, timeControl TimeControl,
+) Match {
+	link.mutex.Lock()
+	defer link.mutex.Unlock()
+	setUpPlayers(black, white)
+	link.games[board].SetDrawRules(model.FIDEDraws)
+	link.players[board] = [2]*Player{black, white}
+	link.gameOver[board] = make(chan struct{})
+	if board == 1 {
+		close(link.started)
+	}
+	return Match{black: black, white: white, game: link.games[board],
+		gameOver: link.gameOver[board], timeControl: timeControl,
+		clocks: timeControl.newClocks(), bughouse: link, board: board}
+}
+
+// passCapture pass the piece the last move on the board captured to the
+// captor's partner, telling the other board's players their new pockets
+func (link *bughouseLink) passCapture(board int) {
+	link.mutex.Lock()
+	defer link.mutex.Unlock()
+	pieceType, color, ok := link.games[board].LastCapture()
+	partnerGame := link.games[1-board]
+	if !ok || partnerGame.AddToPocket(color, pieceType) != nil {
+		return
+	}
+	gameOver := link.gameOver[1-board]
+	response := ResponseAsync{Pockets: [2]map[model.PieceType]uint8{
+		partnerGame.Pocket(model.Black), partnerGame.Pocket(model.White),
+	}}
+	for _, player := range link.players[1-board] {
+		thisPlayer := player
+		go func() {
+			select {
+			case thisPlayer.ResponseChanAsync <- response:
+			case <-gameOver:
+			}
+		}()
+	}
+}
+
+// waitForBoards block until both boards' players are matched, false if the
+// game is aborted first as one of the match's players left or resigned, or
+// the other board's players did not come in time
+func (link *bughouseLink) waitForBoards(match *Match) bool {
+	timeout := time.NewTimer(link.startTimeout)
+	defer timeout.Stop()
+	for {
+		request := RequestAsync{}
+		select {
+		case <-link.started:
+			return true
+		case request = <-match.black.RequestChanAsync:
+		case request = <-match.white.RequestChanAsync:
+		case <-timeout.C:
+			if link.abortUnstarted() {
+				return false
+			}
+		}
+		// Leaving the first board aborts both, even once the second started.
+		if request.Abandon || request.Resign {
+			link.abort()
+			return false
+		}
+	}
+}
+
+// abort end the game as aborted before the first board starts
+func (link *bughouseLink) abort() {
+	link.mutex.Lock()
+	defer link.mutex.Unlock()
+	link.end(0, model.White, false, model.Aborted)
+}
+
+// abortUnstarted end the game as aborted if the second board was not taken
+func (link *bughouseLink) abortUnstarted() bool {
+	link.mutex.Lock()
+	defer link.mutex.Unlock()
+	if link.joined {
+		return false
+	}
+	link.end(0, model.White, false, model.Aborted)
+	return true
+}
+
+// end record the board's result as the game's if it is the first to end
+func (link *bughouseLink) end(
+	board int, winner model.Color, draw bool, termination model.Termination,
+) {
+	link.once.Do(func() {
+		link.result = bughouseResult{board, winner, draw, termination}
+		close(link.over)
+	})
+}
+
+func (link *bughouseLink) ended() bool {
+	select {
+	case <-link.over:
+		return true
+	default:
+		return false
+	}
+}
+
+// handlePartnerGameOver end the match when the other board of its Bughouse
+// game ends first. Partners play opposite colors, so the winning team plays
+// the other color on this board.
+func (match *Match) handlePartnerGameOver() {
+	link := match.bughouse
+	select {
+	case <-match.gameOver:
+		return
+	case <-link.over:
+	}
+	result := link.result
+	if result.board == match.board {
+		return
+	}
+	winner := match.white
+	if result.winner == model.White {
+		winner = match.black
+	}
+	match.handleGameOver(result.draw, result.termination, winner)
+}
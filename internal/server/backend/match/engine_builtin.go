@@ -0,0 +1,105 @@
+package matchserver
+
+import (
+	"context"
+	"time"
+
+	"github.com/Ekotlikoff/gochess/internal/engine"
+	"github.com/Ekotlikoff/gochess/internal/model"
+)
+
+// botsAvailable get whether a player waiting too long for a match can be
+// matched with a bot, played by a UCI engine, the remote engine or the
+// built-in one
+func (matchingServer *MatchingServer) botsAvailable() bool {
+	return matchingServer.uciEnginePath != "" ||
+		matchingServer.botMatchingEnabled() ||
+		matchingServer.builtinEngineEnabled
+}
+
+// builtinMovesToGo is how many more moves the built-in engine expects to
+// play, sharing its remaining time between them
+const builtinMovesToGo = 30
+
+// builtinEngine is the in-process engine, which searches a copy of the game
+// at the bot's level, within the matching server's limits and its clock
+type builtinEngine struct {
+	maxDepth    int
+	maxMoveTime time.Duration
+	engine      *engine.Engine
+	blunderRate float64
+	color       model.Color
+}
+
+func (matchingServer *MatchingServer) newBuiltinEngine() *builtinEngine {
+	return &builtinEngine{
+		maxDepth:    matchingServer.builtinEngineDepth,
+		maxMoveTime: matchingServer.builtinEngineMoveTime,
+	}
+}
+
+func (bot *builtinEngine) start(start engineStart) error {
+	bot.engine = bot.newEngine(start.level.Depth, start.level.MoveTime)
+	bot.blunderRate = start.level.BlunderRate
+	bot.color = start.color
+	return nil
+}
+
+// newEngine create an engine searching to the depth and for the move time,
+// within the matching server's limits
+func (bot *builtinEngine) newEngine(
+	depth int, moveTime time.Duration,
+) *engine.Engine {
+	if bot.maxDepth > 0 && (depth <= 0 || depth > bot.maxDepth) {
+		depth = bot.maxDepth
+	}
+	if bot.maxMoveTime > 0 && (moveTime <= 0 || moveTime > bot.maxMoveTime) {
+		moveTime = bot.maxMoveTime
+	}
+	return engine.NewEngine(depth, moveTime)
+}
+
+func (bot *builtinEngine) opponentMoved(move model.MoveRequest) error {
+	return nil
+}
+
+func (bot *builtinEngine) opponentOfferedDraw() error {
+	return nil
+}
+
+func (bot *builtinEngine) requests() <-chan RequestAsync {
+	return nil
+}
+
+func (bot *builtinEngine) bestMove(
+	ctx context.Context, game *model.Game, clock engineClock,
+) (model.MoveRequest, error) {
+	result, err := bot.engine.SearchContext(ctx, game,
+		builtinMoveTime(clock.of(bot.color)))
+	if err != nil {
+		return model.MoveRequest{}, err
+	} else if ctx.Err() != nil {
+		return model.MoveRequest{}, ctx.Err()
+	}
+	return blunder(game, result.Move, bot.blunderRate), nil
+}
+
+// builtinMoveTime get how long the bot may search with its clock, a share
+// of its remaining time plus the increment, and never more than half of what
+// is left
+func builtinMoveTime(clock Clock) time.Duration {
+	movesToGo := int64(builtinMovesToGo)
+	if clock.MovesToGo > 0 && int64(clock.MovesToGo) < movesToGo {
+		movesToGo = int64(clock.MovesToGo)
+	}
+	moveTimeMs := clock.RemainingMs/movesToGo + clock.IncrementMs
+	if moveTimeMs > clock.RemainingMs/2 {
+		moveTimeMs = clock.RemainingMs / 2
+	}
+	if moveTimeMs < 1 {
+		moveTimeMs = 1
+	}
+	return time.Duration(moveTimeMs) * time.Millisecond
+}
+
+func (bot *builtinEngine) gameOver(result model.GameResult) {}
@@ -2,127 +2,235 @@ package matchserver
 
 import (
 	"context"
+	"errors"
 	"io"
 	"log"
-	"time"
+	"sync"
 
 	"github.com/Ekotlikoff/gochess/internal/model"
 
 	pb "github.com/Ekotlikoff/gochess/api"
-	"google.golang.org/grpc"
 )
 
-func (matchingServer *MatchingServer) createEngineClient(
-	engineAddr string, engineConnTimeout time.Duration) {
-	var opts []grpc.DialOption
-	opts = append(opts, grpc.WithInsecure())
-	opts = append(opts, grpc.WithBlock())
-	ctx, cancel := context.WithTimeout(context.Background(), engineConnTimeout)
-	defer cancel()
-	conn, err := grpc.DialContext(ctx, engineAddr, opts...)
-	if err != nil {
-		log.Println("ERROR: Failed to connect to chess engine at addr: " +
-			engineAddr + " with error: " + err.Error())
-	} else {
-		log.Println("Successfully connected to chess engine")
-		matchingServer.botMatchingEnabled = true
-		matchingServer.engineClient = pb.NewRustChessClient(conn)
-		matchingServer.engineClientConn = conn
-	}
+// grpcEngine is a remote engine from the pool, which plays a match over a
+// RustChess Game stream and sends the bot's moves as it finds them
+type grpcEngine struct {
+	pool   *enginePool
+	remote *remoteEngine
+	client pb.RustChessClient
+	stream pb.RustChess_GameClient
+	// sendMutex guards the stream's sends, which the session and its async
+	// loop both make.
+	sendMutex sync.Mutex
+	moves     chan model.MoveRequest
+	asyncs    chan RequestAsync
+	quit      chan struct{}
+	done      chan struct{}
+	err       error
 }
 
-func (matchingServer *MatchingServer) engineSession(botPlayer *Player) {
-	stream, err := matchingServer.engineClient.Game(context.Background())
-	if err != nil {
-		log.Println("FATAL: Failed to instantiate GRPC conn to engine")
+// newGRPCEngine take a game on one of the pool's engines, or get nil if none
+// can play one
+func newGRPCEngine(pool *enginePool) *grpcEngine {
+	remote, client := pool.acquire()
+	if remote == nil {
+		return nil
+	}
+	return &grpcEngine{
+		pool: pool, remote: remote, client: client,
+		moves: make(chan model.MoveRequest), asyncs: make(chan RequestAsync),
+		quit: make(chan struct{}), done: make(chan struct{}),
 	}
-	err = botPlayer.WaitForMatchStart()
+}
+
+func (engine *grpcEngine) start(start engineStart) error {
+	stream, err := engine.client.Game(context.Background())
 	if err != nil {
-		log.Println("Bot failed to find match")
-		return
+		engine.pool.fail(engine.remote)
+		engine.pool.release(engine.remote)
+		return err
 	}
-	botPlayer.SetSearchingForMatch(false)
-	botPBColor := pb.GameStart_BLACK
-	if botPlayer.Color() == model.White {
-		botPBColor = pb.GameStart_WHITE
+	botPBColor, opponentColor := pb.GameStart_BLACK, model.White
+	if start.color == model.White {
+		botPBColor, opponentColor = pb.GameStart_WHITE, model.Black
 	}
+	level := start.level
 	gameStartMsg := pb.GameMessage{
 		Request: &pb.GameMessage_GameStart{
 			GameStart: &pb.GameStart{
-				PlayerColor: botPBColor,
-				PlayerGameTime: &pb.GameTime{
-					PlayerMainTime: uint32(botPlayer.MatchMaxTimeMs()),
-				},
+				PlayerColor:      botPBColor,
+				PlayerGameTime:   clockToPB(start.clock.of(start.color)),
+				OpponentGameTime: clockToPB(start.clock.of(opponentColor)),
+				StartFen:         start.startFEN,
+				Chess960:         start.chess960,
+				Variant:          start.variant.Name(),
+				BotLevel:         level.Name,
+				SearchDepth:      uint32(level.Depth),
+				MoveTimeMs:       uint32(level.MoveTime.Milliseconds()),
+				BlunderRate:      level.BlunderRate,
+				TargetElo:        uint32(level.Elo),
 			},
 		},
 	}
 	if err := stream.Send(&gameStartMsg); err != nil {
-		log.Printf("FATAL: Failed to send gameStartMsg to bot: %v", err)
-		// TODO resign in this case?
-		return
-	}
-	gameOver := botPlayer.match.gameOver
-	waitc := make(chan struct{})
-	go engineReceiveLoop(matchingServer, botPlayer, stream, waitc)
-	for {
-		select {
-		case move := <-botPlayer.OpponentPlayedMove:
-			moveMsg := moveToPB(move)
-			stream.Send(&moveMsg)
-		case <-gameOver:
-			stream.CloseSend()
-			<-waitc
-			botPlayer.ClientDoneWithMatch()
-			return
-		}
+		stream.CloseSend()
+		engine.pool.fail(engine.remote)
+		engine.pool.release(engine.remote)
+		return err
 	}
+	engine.stream = stream
+	go engine.receiveLoop()
+	return nil
 }
 
-func engineReceiveLoop(
-	matchingServer *MatchingServer, botPlayer *Player,
-	stream pb.RustChess_GameClient, waitc chan struct{}) {
+func (engine *grpcEngine) receiveLoop() {
+	defer close(engine.done)
 	for {
-		in, err := stream.Recv()
+		in, err := engine.stream.Recv()
 		if err == io.EOF {
 			// read done.
-			close(waitc)
 			return
 		}
 		if err != nil {
-			log.Printf("Failed to receive a msg, closing engine conn: %v", err)
-			if botPlayer.GetMatch() != nil && !botPlayer.GetMatch().GameOver() {
-				botPlayer.RequestChanAsync <- RequestAsync{
-					Resign: true,
-				}
-			}
-			matchingServer.botMatchingEnabled = false
-			close(waitc)
+			log.Printf("Failed to receive a msg from the engine at %s: %v",
+				engine.remote.addr, err)
+			engine.err = err
+			engine.pool.fail(engine.remote)
 			return
 		}
-		botMove := pbToMove(in.GetChessMove())
-		botPlayer.requestChanSync <- botMove
-		<-botPlayer.ResponseChanSync
+		if chessMove := in.GetChessMove(); chessMove != nil {
+			select {
+			case engine.moves <- pbToMove(chessMove):
+			case <-engine.quit:
+				return
+			}
+		} else if asyncRequest := in.GetAsyncRequest(); asyncRequest != nil {
+			request := RequestAsync{RequestToDraw: true}
+			if asyncRequest.Type == pb.AsyncRequest_RESIGN {
+				request = RequestAsync{Resign: true}
+			}
+			select {
+			case engine.asyncs <- request:
+			case <-engine.quit:
+				return
+			}
+		}
 	}
 }
 
+func (engine *grpcEngine) opponentMoved(move model.MoveRequest) error {
+	moveMsg := moveToPB(move)
+	return engine.send(&moveMsg)
+}
+
+func (engine *grpcEngine) opponentOfferedDraw() error {
+	return engine.send(&pb.GameMessage{
+		Request: &pb.GameMessage_AsyncRequest{
+			AsyncRequest: &pb.AsyncRequest{Type: pb.AsyncRequest_DRAW},
+		},
+	})
+}
+
+func (engine *grpcEngine) requests() <-chan RequestAsync {
+	return engine.asyncs
+}
+
+func (engine *grpcEngine) send(msg *pb.GameMessage) error {
+	engine.sendMutex.Lock()
+	defer engine.sendMutex.Unlock()
+	return engine.stream.Send(msg)
+}
+
+func (engine *grpcEngine) bestMove(
+	ctx context.Context, game *model.Game, clock engineClock,
+) (model.MoveRequest, error) {
+	for {
+		select {
+		case move := <-engine.moves:
+			return move, nil
+		case request := <-engine.asyncs:
+			// A draw offer before the bot's move would be withdrawn by it.
+			if request.Resign {
+				return model.MoveRequest{}, errEngineResigned
+			}
+		case <-engine.done:
+			if engine.err != nil {
+				return model.MoveRequest{}, engine.err
+			}
+			return model.MoveRequest{}, errors.New("the engine ended the game")
+		case <-ctx.Done():
+			return model.MoveRequest{}, ctx.Err()
+		}
+	}
+}
+
+func (engine *grpcEngine) gameOver(result model.GameResult) {
+	gameOverMsg := gameOverToPB(result)
+	engine.send(&gameOverMsg)
+	engine.sendMutex.Lock()
+	engine.stream.CloseSend()
+	engine.sendMutex.Unlock()
+	close(engine.quit)
+	<-engine.done
+	engine.pool.release(engine.remote)
+}
+
 func moveToPB(move model.MoveRequest) pb.GameMessage {
+	chessMove := &pb.ChessMove{
+		OriginalPosition: &pb.Position{
+			File: uint32(move.Position.File),
+			Rank: uint32(move.Position.Rank),
+		},
+		NewPosition: &pb.Position{
+			File: uint32(int8(move.Position.File) + move.Move.X),
+			Rank: uint32(int8(move.Position.Rank) + move.Move.Y),
+		},
+		Drop: move.IsDrop(),
+	}
+	if move.PromoteTo != nil {
+		// The proto enum is numbered one more than model.PieceType.
+		chessMove.PromoteTo = pb.ChessMove_PieceType(*move.PromoteTo + 1)
+	}
+	return pb.GameMessage{
+		Request: &pb.GameMessage_ChessMove{ChessMove: chessMove},
+	}
+}
+
+func gameOverToPB(result model.GameResult) pb.GameMessage {
 	return pb.GameMessage{
-		Request: &pb.GameMessage_ChessMove{
-			ChessMove: &pb.ChessMove{
-				OriginalPosition: &pb.Position{
-					File: uint32(move.Position.File),
-					Rank: uint32(move.Position.Rank),
-				},
-				NewPosition: &pb.Position{
-					File: uint32(int8(move.Position.File) + move.Move.X),
-					Rank: uint32(int8(move.Position.Rank) + move.Move.Y),
-				},
+		Request: &pb.GameMessage_GameOver{
+			GameOver: &pb.GameOver{
+				// The proto enum is numbered the same as model.Termination.
+				Termination: pb.GameOver_Termination(result.Termination),
+				Draw:        result.Draw,
+				Winner:      pb.GameStart_Color(result.Winner),
 			},
 		},
 	}
 }
 
+func clockToPB(clock Clock) *pb.GameTime {
+	return &pb.GameTime{
+		PlayerMainTime: uint32(clock.RemainingMs),
+		Increment:      uint32(clock.IncrementMs),
+		Delay:          uint32(clock.DelayMs),
+		BronsteinDelay: clock.Bronstein,
+		MovesToGo:      uint32(clock.MovesToGo),
+	}
+}
+
 func pbToMove(msg *pb.ChessMove) model.MoveRequest {
+	var promoteTo *model.PieceType
+	if msg.PromoteTo != pb.ChessMove_NO_PIECE {
+		pieceType := model.PieceType(msg.PromoteTo - 1)
+		promoteTo = &pieceType
+	}
+	if msg.Drop && promoteTo != nil {
+		return model.NewDropRequest(*promoteTo, model.Position{
+			File: uint8(msg.NewPosition.File),
+			Rank: uint8(msg.NewPosition.Rank),
+		})
+	}
 	return model.MoveRequest{
 		Position: model.Position{
 			File: uint8(msg.OriginalPosition.File),
@@ -134,6 +242,6 @@ func pbToMove(msg *pb.ChessMove) model.MoveRequest {
 			Y: int8(msg.NewPosition.Rank -
 				msg.OriginalPosition.Rank),
 		},
+		PromoteTo: promoteTo,
 	}
-
 }
@@ -0,0 +1,187 @@
+package matchserver
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	pb "github.com/Ekotlikoff/gochess/api"
+	"github.com/Ekotlikoff/gochess/internal/fakeengine"
+	"github.com/Ekotlikoff/gochess/internal/model"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// startFakeEngine serve a fake engine that plays the script, for the length
+// of the test
+func startFakeEngine(t *testing.T, script []string, acceptDraws bool) string {
+	listener := listen(t)
+	server := grpc.NewServer()
+	pb.RegisterRustChessServer(server,
+		fakeengine.NewServer(script, acceptDraws, 1))
+	healthpb.RegisterHealthServer(server, health.NewServer())
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+	return listener.Addr().String()
+}
+
+// startFakeEngineMatch match the player, as white, with a bot played by the
+// fake engine at the address
+func startFakeEngineMatch(t *testing.T, addr string, player *Player) *Match {
+	return startFakeEngineMatchFromFEN(t, addr, player, model.StartingFEN)
+}
+
+// startFakeEngineMatchFromFEN match the player, as white, with a bot played
+// by the fake engine at the address from the position
+func startFakeEngineMatchFromFEN(
+	t *testing.T, addr string, player *Player, fen string,
+) *Match {
+	game, err := model.NewGameFromFEN(fen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	matchingServer := NewMatchingServerWithEngine(addr, time.Millisecond,
+		time.Second)
+	if !matchingServer.botMatchingEnabled() {
+		t.Fatal("Expected bot matching to be enabled with the fake engine")
+	}
+	quit := make(chan bool)
+	t.Cleanup(func() { close(quit) })
+	go matchingServer.StartCustomMatchServers(1,
+		func(player *Player, bot *Player) Match {
+			return newMatchFromGame(bot, player, game,
+				NewTimeControl(60000, 0))
+		}, quit)
+	go matchingServer.MatchPlayer(player, Seek{})
+	if err := player.WaitForMatchStart(); err != nil {
+		t.Fatal("Expected to be matched with a bot got ", err)
+	}
+	return player.GetMatch()
+}
+
+// playUCI make the player's move in UCI notation
+func playUCI(t *testing.T, player *Player, match *Match, uci string) {
+	move, err := match.game.ParseUCI(uci)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !player.MakeMove(move) {
+		t.Fatal("Expected a successful move got failure for ", uci)
+	}
+}
+
+// expectBotMove wait for the bot's move and check it is the expected one in
+// UCI notation, if given
+func expectBotMove(t *testing.T, player *Player, expected string) {
+	move := player.GetSyncUpdate()
+	if move == nil {
+		t.Fatal("Expected the bot to move")
+	} else if expected != "" && move.UCI() != expected {
+		t.Error("Expected ", expected, " got ", move.UCI())
+	}
+}
+
+func TestFakeEngineMatchCheckmate(t *testing.T) {
+	addr := startFakeEngine(t, []string{"e7e5", "d8h4"}, false)
+	player := NewPlayer("player")
+	match := startFakeEngineMatch(t, addr, player)
+	playUCI(t, player, match, "f2f3")
+	expectBotMove(t, player, "e7e5")
+	playUCI(t, player, match, "g2g4")
+	expectBotMove(t, player, "d8h4")
+	response := player.GetAsyncUpdate()
+	if response == nil || !response.GameOver ||
+		response.Termination != model.Checkmate ||
+		response.Winner != match.PlayerName(model.Black) {
+		t.Error("Expected the bot to win by checkmate got ", response)
+	}
+}
+
+func TestFakeEngineMatchPromotion(t *testing.T) {
+	addr := startFakeEngine(t, []string{"b2b1n"}, false)
+	player := NewPlayer("player")
+	match := startFakeEngineMatchFromFEN(t, addr, player,
+		"8/1P5k/8/8/8/8/1p5K/8 w - - 0 1")
+	playUCI(t, player, match, "b7b8q")
+	expectBotMove(t, player, "b2b1n")
+	if fen := match.game.FEN(); !strings.HasPrefix(fen,
+		"1Q6/7k/8/8/8/8/7K/1n6 w") {
+		t.Error("Expected both pawns promoted got ", fen)
+	}
+}
+
+func TestFakeEngineMatchResign(t *testing.T) {
+	addr := startFakeEngine(t, []string{"e7e5", fakeengine.Resign}, false)
+	player := NewPlayer("player")
+	match := startFakeEngineMatch(t, addr, player)
+	playUCI(t, player, match, "e2e4")
+	expectBotMove(t, player, "e7e5")
+	playUCI(t, player, match, "g1f3")
+	response := player.GetAsyncUpdate()
+	if response == nil || !response.GameOver ||
+		response.Termination != model.Resignation ||
+		response.Winner != player.Name() {
+		t.Error("Expected the bot to resign got ", response)
+	}
+}
+
+func TestFakeEngineMatchAcceptsDraw(t *testing.T) {
+	addr := startFakeEngine(t, nil, true)
+	player := NewPlayer("player")
+	match := startFakeEngineMatch(t, addr, player)
+	playUCI(t, player, match, "e2e4")
+	expectBotMove(t, player, "")
+	player.RequestAsync(RequestAsync{RequestToDraw: true})
+	response := player.GetAsyncUpdate()
+	if response == nil || !response.GameOver || !response.Draw ||
+		response.Termination != model.Agreement {
+		t.Error("Expected the bot to accept the draw got ", response)
+	}
+}
+
+func TestFakeEngineMatchOffersDraw(t *testing.T) {
+	addr := startFakeEngine(t, []string{fakeengine.Draw, "e7e5"}, false)
+	player := NewPlayer("player")
+	match := startFakeEngineMatch(t, addr, player)
+	playUCI(t, player, match, "e2e4")
+	expectBotMove(t, player, "e7e5")
+	response := player.GetAsyncUpdate()
+	if response == nil || !response.RequestToDraw {
+		t.Fatal("Expected the bot to offer a draw got ", response)
+	}
+	player.RequestAsync(RequestAsync{RequestToDraw: true})
+	response = player.GetAsyncUpdate()
+	if response == nil || !response.GameOver || !response.Draw ||
+		response.Termination != model.Agreement {
+		t.Error("Expected the draw to be agreed got ", response)
+	}
+}
+
+func TestFakeEngineMatchRandomMoves(t *testing.T) {
+	addr := startFakeEngine(t, nil, false)
+	player := NewPlayer("player")
+	match := startFakeEngineMatch(t, addr, player)
+	for i := 0; i < 5; i++ {
+		if !player.MakeMove(match.game.LegalMoves()[0]) {
+			t.Fatal("Expected a successful move")
+		}
+		expectBotMove(t, player, "")
+	}
+	player.RequestAsync(RequestAsync{Resign: true})
+	response := player.GetAsyncUpdate()
+	if response == nil || !response.GameOver ||
+		response.Winner != match.PlayerName(model.Black) {
+		t.Error("Expected the bot to win by resignation got ", response)
+	}
+}
+
+func TestEngineMoveDropRoundTrip(t *testing.T) {
+	drop := model.NewDropRequest(model.Knight, model.Position{File: 5, Rank: 2})
+	msg := moveToPB(drop)
+	move := pbToMove(msg.GetChessMove())
+	if !move.IsDrop() || *move.PromoteTo != model.Knight ||
+		move.Position != drop.Position {
+		t.Error("Expected ", drop.UCI(), " got ", move.UCI())
+	}
+}
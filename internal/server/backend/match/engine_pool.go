@@ -0,0 +1,241 @@
+package matchserver
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	pb "github.com/Ekotlikoff/gochess/api"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// engineHealthCheckInterval is how often a healthy engine is checked
+	engineHealthCheckInterval = 10 * time.Second
+	// engineMinBackoff and engineMaxBackoff bound how long an unhealthy
+	// engine is left before it is reconnected to, doubling after each failure
+	engineMinBackoff = time.Second
+	engineMaxBackoff = time.Minute
+)
+
+type (
+	// enginePool is the remote engines that bots play with. Each is health
+	// checked in the background and reconnected to with backoff when it
+	// fails, and bot games go to the healthy engine with the fewest games.
+	enginePool struct {
+		engines             []*remoteEngine
+		maxGamesPerEngine   int
+		connTimeout         time.Duration
+		healthCheckInterval time.Duration
+		minBackoff          time.Duration
+		maxBackoff          time.Duration
+		mutex               sync.Mutex
+		quit                chan struct{}
+		done                sync.WaitGroup
+	}
+
+	// remoteEngine is one of the pool's engines
+	remoteEngine struct {
+		addr    string
+		conn    *grpc.ClientConn
+		client  pb.RustChessClient
+		healthy bool
+		games   int
+		// failed wakes the engine's health checks when one of its games has
+		// failed.
+		failed chan struct{}
+	}
+)
+
+// newEnginePool create a pool of the engines at the addresses, each playing
+// at most the max games at once, or any number if it is 0
+func newEnginePool(
+	engineAddrs []string, maxGamesPerEngine int, connTimeout time.Duration,
+) *enginePool {
+	pool := &enginePool{
+		maxGamesPerEngine: maxGamesPerEngine, connTimeout: connTimeout,
+		healthCheckInterval: engineHealthCheckInterval,
+		minBackoff:          engineMinBackoff, maxBackoff: engineMaxBackoff,
+		quit: make(chan struct{}),
+	}
+	for _, addr := range engineAddrs {
+		pool.engines = append(pool.engines,
+			&remoteEngine{addr: addr, failed: make(chan struct{}, 1)})
+	}
+	return pool
+}
+
+// start check each engine's health once, then keep checking it in the
+// background until the pool is closed
+func (pool *enginePool) start() {
+	var wg sync.WaitGroup
+	for _, engine := range pool.engines {
+		wg.Add(1)
+		go func(engine *remoteEngine) {
+			defer wg.Done()
+			pool.checkHealth(engine)
+		}(engine)
+	}
+	wg.Wait()
+	for _, engine := range pool.engines {
+		pool.done.Add(1)
+		go pool.monitor(engine)
+	}
+}
+
+// monitor check the engine's health at the interval while it is healthy, and
+// with backoff while it is not
+func (pool *enginePool) monitor(engine *remoteEngine) {
+	defer pool.done.Done()
+	backoff := pool.minBackoff
+	for {
+		wait := pool.healthCheckInterval
+		if !pool.isHealthy(engine) {
+			wait = backoff
+			backoff *= 2
+			if backoff > pool.maxBackoff {
+				backoff = pool.maxBackoff
+			}
+		} else {
+			backoff = pool.minBackoff
+		}
+		select {
+		case <-time.After(wait):
+		case <-engine.failed:
+			// Check right away whether the engine is still reachable.
+		case <-pool.quit:
+			return
+		}
+		pool.checkHealth(engine)
+	}
+}
+
+// checkHealth connect to the engine if need be and ask whether it is serving,
+// dropping the connection if not so that the next check reconnects
+func (pool *enginePool) checkHealth(engine *remoteEngine) {
+	pool.mutex.Lock()
+	conn := engine.conn
+	pool.mutex.Unlock()
+	ctx, cancel := context.WithTimeout(context.Background(), pool.connTimeout)
+	defer cancel()
+	var err error
+	if conn == nil {
+		conn, err = grpc.DialContext(ctx, engine.addr, grpc.WithInsecure(),
+			grpc.WithBlock())
+	}
+	if err == nil {
+		var response *healthpb.HealthCheckResponse
+		response, err = healthpb.NewHealthClient(conn).Check(ctx,
+			&healthpb.HealthCheckRequest{})
+		if status.Code(err) == codes.Unimplemented {
+			// Engines without the health service are healthy if they answer.
+			err = nil
+		} else if err == nil &&
+			response.Status != healthpb.HealthCheckResponse_SERVING {
+			err = status.Error(codes.Unavailable, response.Status.String())
+		}
+	}
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+	wasHealthy := engine.healthy
+	engine.healthy = err == nil
+	if err != nil {
+		if conn != nil {
+			conn.Close()
+		}
+		engine.conn, engine.client = nil, nil
+		log.Println("ERROR: Chess engine at addr: " + engine.addr +
+			" is unhealthy with error: " + err.Error())
+		return
+	}
+	engine.conn, engine.client = conn, pb.NewRustChessClient(conn)
+	if !wasHealthy {
+		log.Println("Successfully connected to chess engine at addr: " +
+			engine.addr)
+	}
+}
+
+// healthy get whether any of the engines is healthy
+func (pool *enginePool) healthy() bool {
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+	for _, engine := range pool.engines {
+		if engine.healthy {
+			return true
+		}
+	}
+	return false
+}
+
+// botMatchingEnabled get whether any of the matching server's remote engines
+// is healthy
+func (matchingServer *MatchingServer) botMatchingEnabled() bool {
+	return matchingServer.enginePool != nil &&
+		matchingServer.enginePool.healthy()
+}
+
+func (pool *enginePool) isHealthy(engine *remoteEngine) bool {
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+	return engine.healthy
+}
+
+// acquire take a game on the healthy engine with the fewest games that can
+// play another, or get nil if there is none
+func (pool *enginePool) acquire() (*remoteEngine, pb.RustChessClient) {
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+	var best *remoteEngine
+	for _, engine := range pool.engines {
+		if !engine.healthy || (pool.maxGamesPerEngine > 0 &&
+			engine.games >= pool.maxGamesPerEngine) {
+			continue
+		}
+		if best == nil || engine.games < best.games {
+			best = engine
+		}
+	}
+	if best == nil {
+		return nil, nil
+	}
+	best.games++
+	return best, best.client
+}
+
+// release give back a game taken on the engine
+func (pool *enginePool) release(engine *remoteEngine) {
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+	engine.games--
+}
+
+// fail mark the engine unhealthy after one of its games failed, until its
+// health checks find it healthy again
+func (pool *enginePool) fail(engine *remoteEngine) {
+	pool.mutex.Lock()
+	engine.healthy = false
+	pool.mutex.Unlock()
+	select {
+	case engine.failed <- struct{}{}:
+	default:
+	}
+}
+
+// close stop the health checks and close the connections
+func (pool *enginePool) close() {
+	close(pool.quit)
+	pool.done.Wait()
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+	for _, engine := range pool.engines {
+		if engine.conn != nil {
+			engine.conn.Close()
+			engine.conn, engine.client = nil, nil
+		}
+		engine.healthy = false
+	}
+}
@@ -0,0 +1,145 @@
+package matchserver
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// startTestEngine serve the gRPC health service on the listener, standing in
+// for an engine
+func startTestEngine(t *testing.T, listener net.Listener) *health.Server {
+	server := grpc.NewServer()
+	healthServer := health.NewServer()
+	healthpb.RegisterHealthServer(server, healthServer)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+	return healthServer
+}
+
+func newTestEnginePool(addrs []string, maxGamesPerEngine int) *enginePool {
+	pool := newEnginePool(addrs, maxGamesPerEngine, time.Second)
+	pool.healthCheckInterval = 10 * time.Millisecond
+	pool.minBackoff = 10 * time.Millisecond
+	pool.maxBackoff = 40 * time.Millisecond
+	return pool
+}
+
+// waitForHealthy wait for the pool's health to be as expected
+func waitForHealthy(pool *enginePool, healthy bool) bool {
+	for i := 0; i < 200; i++ {
+		if pool.healthy() == healthy {
+			return true
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return false
+}
+
+func listen(t *testing.T) net.Listener {
+	listener, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return listener
+}
+
+func TestEnginePoolHealthCheck(t *testing.T) {
+	listener := listen(t)
+	healthServer := startTestEngine(t, listener)
+	pool := newTestEnginePool([]string{listener.Addr().String()}, 0)
+	pool.start()
+	defer pool.close()
+	if !pool.healthy() {
+		t.Error("Expected the serving engine to be healthy")
+	}
+	healthServer.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+	if !waitForHealthy(pool, false) {
+		t.Error("Expected the engine to be unhealthy once not serving")
+	}
+	if remote, _ := pool.acquire(); remote != nil {
+		t.Error("Expected no engine for a game got ", remote.addr)
+	}
+	healthServer.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
+	if !waitForHealthy(pool, true) {
+		t.Error("Expected the engine to be healthy again once serving")
+	}
+}
+
+func TestEnginePoolReconnect(t *testing.T) {
+	listener := listen(t)
+	addr := listener.Addr().String()
+	listener.Close()
+	pool := newTestEnginePool([]string{addr}, 0)
+	pool.connTimeout = 50 * time.Millisecond
+	pool.start()
+	defer pool.close()
+	if pool.healthy() {
+		t.Error("Expected the unreachable engine to be unhealthy")
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		t.Skip("Could not listen on the engine's address again: ", err)
+	}
+	startTestEngine(t, listener)
+	if !waitForHealthy(pool, true) {
+		t.Error("Expected the engine to be reconnected to")
+	}
+	remote, client := pool.acquire()
+	if remote == nil || client == nil {
+		t.Fatal("Expected a game on the reconnected engine")
+	}
+	pool.fail(remote)
+	if !waitForHealthy(pool, true) {
+		t.Error("Expected the failed engine to be healthy again")
+	}
+	pool.release(remote)
+}
+
+func TestEnginePoolLoadBalancing(t *testing.T) {
+	listener1, listener2 := listen(t), listen(t)
+	startTestEngine(t, listener1)
+	startTestEngine(t, listener2)
+	pool := newTestEnginePool([]string{listener1.Addr().String(),
+		listener2.Addr().String()}, 1)
+	pool.start()
+	defer pool.close()
+	remote1, _ := pool.acquire()
+	remote2, _ := pool.acquire()
+	if remote1 == nil || remote2 == nil || remote1 == remote2 {
+		t.Fatal("Expected a game on each engine got ", remote1, remote2)
+	}
+	if remote, _ := pool.acquire(); remote != nil {
+		t.Error("Expected both engines to be busy got ", remote.addr)
+	}
+	pool.release(remote2)
+	if remote, _ := pool.acquire(); remote != remote2 {
+		t.Error("Expected a game on the released engine got ", remote)
+	}
+}
+
+func TestMatchingServerEngineRecovers(t *testing.T) {
+	listener := listen(t)
+	healthServer := startTestEngine(t, listener)
+	healthServer.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+	matchingServer := NewMatchingServer()
+	matchingServer.enginePool = newTestEnginePool(
+		[]string{listener.Addr().String()}, 0)
+	matchingServer.enginePool.start()
+	defer matchingServer.enginePool.close()
+	if matchingServer.botsAvailable() {
+		t.Error("Expected bot matching to be disabled with no healthy engine")
+	}
+	healthServer.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
+	for i := 0; i < 200 && !matchingServer.botsAvailable(); i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !matchingServer.botMatchingEnabled() {
+		t.Error("Expected bot matching to be re-enabled once the engine",
+			"recovered")
+	}
+}
@@ -0,0 +1,218 @@
+package matchserver
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math/rand"
+	"strings"
+
+	"github.com/Ekotlikoff/gochess/internal/model"
+)
+
+type (
+	// botEngine is a chess engine that plays a bot's side of one match, the
+	// remote engine, a local UCI engine process or the built-in engine
+	botEngine interface {
+		// start the engine's game
+		start(start engineStart) error
+		// opponentMoved tell the engine the opponent's move
+		opponentMoved(move model.MoveRequest) error
+		// opponentOfferedDraw tell the engine the opponent offered a draw
+		opponentOfferedDraw() error
+		// requests get the engine's draw offers and resignations made while
+		// the opponent is to move, nil if it makes none
+		requests() <-chan RequestAsync
+		// bestMove get the bot's move in the game, which has the bot to
+		// move, giving up once the context is done, or errEngineResigned if
+		// the engine resigns instead
+		bestMove(ctx context.Context, game *model.Game, clock engineClock) (
+			model.MoveRequest, error)
+		// gameOver tell the engine the game's result and release it
+		gameOver(result model.GameResult)
+	}
+
+	// engineStart is the bot's match as the engine starts it
+	engineStart struct {
+		color    model.Color
+		clock    engineClock
+		startFEN string
+		chess960 bool
+		variant  model.Variant
+		level    BotLevel
+	}
+
+	// engineClock is each side's clock when the bot is to move
+	engineClock struct {
+		white, black Clock
+	}
+)
+
+// of get the color's clock
+func (clock engineClock) of(color model.Color) Clock {
+	if color == model.White {
+		return clock.white
+	}
+	return clock.black
+}
+
+// errEngineResigned is the error from an engine's bestMove when it resigns
+var errEngineResigned = errors.New("the engine resigned")
+
+// newBotEngine create the engine bots play with, a local UCI engine if one
+// is configured, else a remote engine if one is healthy and can play another
+// game, else the built-in engine
+func (matchingServer *MatchingServer) newBotEngine() botEngine {
+	if matchingServer.uciEnginePath != "" {
+		return newUCIEngine(matchingServer.uciEnginePath)
+	} else if matchingServer.botMatchingEnabled() {
+		// The pool's engines may all be busy.
+		if engine := newGRPCEngine(matchingServer.enginePool); engine != nil {
+			return engine
+		}
+	}
+	return matchingServer.newBuiltinEngine()
+}
+
+// engineSession play the bot's match with the engine, falling back to the
+// built-in engine if the engine fails to start
+func (matchingServer *MatchingServer) engineSession(
+	botPlayer *Player, level BotLevel,
+) {
+	err := botPlayer.WaitForMatchStart()
+	if err != nil {
+		log.Println("Bot failed to find match")
+		return
+	}
+	botPlayer.SetSearchingForMatch(false)
+	match := botPlayer.GetMatch()
+	gameOver := match.gameOver
+	start := engineStart{
+		color: botPlayer.Color(), clock: match.clock(),
+		startFEN: match.StartFEN(), chess960: match.Chess960(),
+		variant: match.Variant(), level: level,
+	}
+	engine := matchingServer.newBotEngine()
+	if err := engine.start(start); err != nil {
+		log.Println("FATAL: Failed to start the engine:", err)
+		engine = matchingServer.newBuiltinEngine()
+		if !matchingServer.builtinEngineEnabled || engine.start(start) != nil {
+			abandonEngineSession(botPlayer, gameOver)
+			return
+		}
+		log.Println("Falling back to the built-in engine")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-gameOver
+		cancel()
+	}()
+	go engineDrawOfferLoop(engine, botPlayer.ResponseChanAsync, gameOver)
+	// The bot's turn is followed by its own and the opponent's moves rather
+	// than read from the game, which the match changes before the clocks.
+	toMove := startTurn(start.startFEN) == start.color
+	for {
+		if toMove {
+			move, err := engine.bestMove(ctx, match.game, match.clock())
+			if err == errEngineResigned {
+				select {
+				case botPlayer.RequestChanAsync <- RequestAsync{Resign: true}:
+				case <-gameOver:
+				}
+			} else if err != nil && ctx.Err() == nil {
+				log.Println("Engine failed to move, abandoning:", err)
+				engine.gameOver(match.game.Result())
+				abandonEngineSession(botPlayer, gameOver)
+				return
+			} else if err == nil {
+				botPlayer.requestChanSync <- move
+				select {
+				case response := <-botPlayer.ResponseChanSync:
+					if !response.MoveSuccess {
+						log.Println("Engine played an illegal move, abandoning")
+						engine.gameOver(match.game.Result())
+						abandonEngineSession(botPlayer, gameOver)
+						return
+					}
+				case <-gameOver:
+				}
+			}
+			toMove = false
+		}
+		// The engine's draw offers are only passed on once its moves have
+		// been played, since a move withdraws the mover's offer.
+		select {
+		case request := <-engine.requests():
+			select {
+			case botPlayer.RequestChanAsync <- request:
+			case <-gameOver:
+			}
+		case move := <-botPlayer.OpponentPlayedMove:
+			toMove = true
+			if err := engine.opponentMoved(move); err != nil {
+				log.Println("Failed to send the opponent's move to the engine:",
+					err)
+			}
+		case <-gameOver:
+			engine.gameOver(match.game.Result())
+			botPlayer.ClientDoneWithMatch()
+			return
+		}
+	}
+}
+
+// startTurn get the side to move in the start position in Forsyth-Edwards
+// Notation
+func startTurn(fen string) model.Color {
+	if fields := strings.Fields(fen); len(fields) > 1 && fields[1] == "b" {
+		return model.Black
+	}
+	return model.White
+}
+
+// engineDrawOfferLoop pass the opponent's draw offers on to the engine until
+// the game is over
+func engineDrawOfferLoop(
+	engine botEngine, responseChanAsync <-chan ResponseAsync,
+	gameOver chan struct{},
+) {
+	for {
+		select {
+		case response := <-responseChanAsync:
+			if response.RequestToDraw {
+				if err := engine.opponentOfferedDraw(); err != nil {
+					log.Println("Failed to send the draw offer to the engine:",
+						err)
+				}
+			}
+		case <-gameOver:
+			return
+		}
+	}
+}
+
+// abandonEngineSession abandon the bot's match when its engine has failed
+func abandonEngineSession(botPlayer *Player, gameOver chan struct{}) {
+	select {
+	case botPlayer.RequestChanAsync <- RequestAsync{Abandon: true}:
+	case <-gameOver:
+	}
+	<-gameOver
+	botPlayer.ClientDoneWithMatch()
+}
+
+// blunder get a random legal move in place of the best move at the rate, as
+// a weaker bot level plays
+func blunder(
+	game *model.Game, best model.MoveRequest, rate float64,
+) model.MoveRequest {
+	if rate <= 0 || rand.Float64() >= rate {
+		return best
+	}
+	moves := game.LegalMoves()
+	if len(moves) == 0 {
+		return best
+	}
+	return moves[rand.Intn(len(moves))]
+}
@@ -0,0 +1,245 @@
+package matchserver
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/Ekotlikoff/gochess/internal/model"
+)
+
+// uciTimeout is how long a UCI engine may take to answer a command other
+// than go
+const uciTimeout = 10 * time.Second
+
+// uciVariantNames are the variants' names for the UCI_Variant option, as
+// used by variant engines such as Fairy-Stockfish
+var uciVariantNames = map[model.Variant]string{
+	model.Standard: "chess", model.KingOfTheHill: "kingofthehill",
+	model.ThreeCheck: "3check", model.Antichess: "antichess",
+	model.Atomic: "atomic", model.Horde: "horde",
+	model.Crazyhouse: "crazyhouse", model.Bughouse: "bughouse",
+}
+
+// uciEngine is a local engine process spoken to over the Universal Chess
+// Interface. It is sent the whole game before each of the bot's moves, so it
+// needs no other state.
+type uciEngine struct {
+	path     string
+	cmd      *exec.Cmd
+	stdin    io.WriteCloser
+	lines    chan string
+	startFEN string
+	variant  model.Variant
+	level    BotLevel
+	options  map[string]bool
+}
+
+func newUCIEngine(path string) *uciEngine {
+	return &uciEngine{path: path, options: map[string]bool{}}
+}
+
+// start launch the engine process and set it up for the game
+func (engine *uciEngine) start(start engineStart) error {
+	engine.cmd = exec.Command(engine.path)
+	stdin, err := engine.cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := engine.cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := engine.cmd.Start(); err != nil {
+		return err
+	}
+	engine.stdin = stdin
+	engine.lines = make(chan string)
+	go engine.readLoop(stdout)
+	engine.startFEN, engine.variant = start.startFEN, start.variant
+	engine.level = start.level
+	if err := engine.handshake(start); err != nil {
+		engine.gameOver(model.GameResult{})
+		return err
+	}
+	return nil
+}
+
+// readLoop pass the engine's output on line by line until it exits
+func (engine *uciEngine) readLoop(stdout io.Reader) {
+	defer close(engine.lines)
+	scanner := bufio.NewScanner(stdout)
+	for scanner.Scan() {
+		engine.lines <- scanner.Text()
+	}
+}
+
+// handshake check that the engine speaks UCI and supports the game, setting
+// its options for the game and bot level
+func (engine *uciEngine) handshake(start engineStart) error {
+	engine.send("uci")
+	err := engine.waitFor(context.Background(), "uciok", func(line string) {
+		fields := strings.Fields(line)
+		if len(fields) >= 3 && fields[0] == "option" && fields[1] == "name" {
+			name := strings.Join(fields[2:], " ")
+			if i := strings.Index(name, " type "); i >= 0 {
+				name = name[:i]
+			}
+			engine.options[name] = true
+		}
+	})
+	if err != nil {
+		return err
+	}
+	if start.chess960 {
+		if !engine.options["UCI_Chess960"] {
+			return errors.New("the UCI engine does not support Chess960")
+		}
+		engine.setOption("UCI_Chess960", "true")
+	}
+	if start.variant != model.Standard {
+		if !engine.options["UCI_Variant"] {
+			return errors.New("the UCI engine does not support " +
+				start.variant.Name())
+		}
+		engine.setOption("UCI_Variant", uciVariantNames[start.variant])
+	}
+	if start.level.Elo > 0 && engine.options["UCI_LimitStrength"] &&
+		engine.options["UCI_Elo"] {
+		engine.setOption("UCI_LimitStrength", "true")
+		engine.setOption("UCI_Elo", fmt.Sprint(start.level.Elo))
+	}
+	engine.send("ucinewgame")
+	engine.send("isready")
+	return engine.waitFor(context.Background(), "readyok", nil)
+}
+
+func (engine *uciEngine) opponentMoved(move model.MoveRequest) error {
+	return nil
+}
+
+func (engine *uciEngine) opponentOfferedDraw() error {
+	return nil
+}
+
+func (engine *uciEngine) requests() <-chan RequestAsync {
+	return nil
+}
+
+// bestMove send the engine the game and clocks and wait for its best move,
+// stopping it if the context is done first
+func (engine *uciEngine) bestMove(
+	ctx context.Context, game *model.Game, clock engineClock,
+) (model.MoveRequest, error) {
+	position := "position fen " + engine.startFEN
+	moves := []string{}
+	for _, move := range game.MoveHistory() {
+		moves = append(moves, move.UCI())
+	}
+	if engine.variant == model.Bughouse {
+		// A partner's passed pieces are not in the moves.
+		position, moves = "position fen "+game.FEN(), nil
+	}
+	if len(moves) > 0 {
+		position += " moves " + strings.Join(moves, " ")
+	}
+	engine.send(position)
+	goCommand := fmt.Sprintf("go wtime %d btime %d winc %d binc %d",
+		clock.white.RemainingMs, clock.black.RemainingMs,
+		clock.white.IncrementMs, clock.black.IncrementMs)
+	if movesToGo := clock.of(game.Turn()).MovesToGo; movesToGo > 0 {
+		goCommand += fmt.Sprintf(" movestogo %d", movesToGo)
+	}
+	if engine.level.Depth > 0 {
+		goCommand += fmt.Sprintf(" depth %d", engine.level.Depth)
+	}
+	if engine.level.MoveTime > 0 {
+		goCommand += fmt.Sprintf(" movetime %d",
+			engine.level.MoveTime.Milliseconds())
+	}
+	engine.send(goCommand)
+	var best string
+	err := engine.waitFor(ctx, "bestmove", func(line string) {
+		if fields := strings.Fields(line); len(fields) >= 2 &&
+			fields[0] == "bestmove" {
+			best = fields[1]
+		}
+	})
+	if ctx.Err() != nil {
+		engine.send("stop")
+		return model.MoveRequest{}, ctx.Err()
+	} else if err != nil {
+		return model.MoveRequest{}, err
+	}
+	move, err := game.ParseUCI(best)
+	if err != nil {
+		return model.MoveRequest{}, fmt.Errorf(
+			"the UCI engine played an invalid move: %v", err)
+	}
+	return blunder(game, move, engine.level.BlunderRate), nil
+}
+
+// gameOver quit the engine, killing it if it does not exit in time
+func (engine *uciEngine) gameOver(result model.GameResult) {
+	engine.send("quit")
+	engine.stdin.Close()
+	go func() {
+		// Discard the rest of the engine's output so that it can exit.
+		for range engine.lines {
+		}
+	}()
+	exited := make(chan struct{})
+	go func() {
+		engine.cmd.Wait()
+		close(exited)
+	}()
+	select {
+	case <-exited:
+	case <-time.After(uciTimeout):
+		engine.cmd.Process.Kill()
+		<-exited
+	}
+}
+
+func (engine *uciEngine) send(command string) {
+	io.WriteString(engine.stdin, command+"\n")
+}
+
+func (engine *uciEngine) setOption(name, value string) {
+	engine.send("setoption name " + name + " value " + value)
+}
+
+// waitFor read the engine's output until a line starting with the token,
+// passing each line read to the handler if there is one. Only go may take
+// longer than uciTimeout, since it is limited by the clocks.
+func (engine *uciEngine) waitFor(
+	ctx context.Context, token string, handler func(line string),
+) error {
+	var timeout <-chan time.Time
+	if token != "bestmove" {
+		timeout = time.After(uciTimeout)
+	}
+	for {
+		select {
+		case line, ok := <-engine.lines:
+			if !ok {
+				return errors.New("the UCI engine exited")
+			}
+			if handler != nil {
+				handler(line)
+			}
+			if strings.HasPrefix(line, token) {
+				return nil
+			}
+		case <-timeout:
+			return fmt.Errorf("the UCI engine did not answer with %s", token)
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
@@ -16,9 +16,18 @@ type (
 		white         *Player
 		game          *model.Game
 		gameOver      chan struct{}
-		maxTimeMs     int64
+		timeControl   TimeControl
+		clocks        [2]playerClock
 		requestedDraw *Player
 		mutex         sync.RWMutex
+		// bughouse links the match to the other board of its Bughouse game,
+		// where it is the board with the index.
+		bughouse *bughouseLink
+		board    int
+		// ratings is where a rated match records its result, in the rating
+		// pool, and is nil for a casual match.
+		ratings    *ratingStore
+		ratingPool string
 	}
 
 	// MatchGenerator takes two players and creates a match
@@ -27,28 +36,64 @@ type (
 
 // NewMatch create a new match between two players
 func NewMatch(black *Player, white *Player, maxTimeMs int64) Match {
-	black.color = model.Black
-	white.color = model.White
-	if black.name == white.name {
-		black.name = black.name + "_black"
-		white.name = white.name + "_white"
+	return newMatchFromGame(black, white, model.NewGame(),
+		NewTimeControl(maxTimeMs, 0))
+}
+
+// NewTimeControlMatch create a new match of the variant between two players
+// with the time control
+func NewTimeControlMatch(
+	black *Player, white *Player, timeControl TimeControl,
+	variant model.Variant,
+) Match {
+	return newMatchFromGame(black, white, model.NewGameVariant(variant),
+		timeControl)
+}
+
+// NewChess960Match create a new Chess960 match between two players from the
+// start position with the index, from 0 to 959
+func NewChess960Match(
+	black *Player, white *Player, maxTimeMs int64, index int,
+) (Match, error) {
+	game, err := model.NewGameChess960(index)
+	if err != nil {
+		return Match{}, err
 	}
-	game := model.NewGame()
-	return Match{black, white, game, make(chan struct{}), maxTimeMs, nil,
-		sync.RWMutex{}}
+	return newMatchFromGame(black, white, game,
+		NewTimeControl(maxTimeMs, 0)), nil
+}
+
+// NewVariantMatch create a new match of the variant between two players
+func NewVariantMatch(
+	black *Player, white *Player, maxTimeMs int64, variant model.Variant,
+) Match {
+	return NewTimeControlMatch(black, white, NewTimeControl(maxTimeMs, 0),
+		variant)
 }
 
 // Create a new match between two players with no pawns
 func newMatchNoPawns(black *Player, white *Player, maxTimeMs int64) Match {
+	return newMatchFromGame(black, white, model.NewGameNoPawns(),
+		NewTimeControl(maxTimeMs, 0))
+}
+
+func newMatchFromGame(
+	black *Player, white *Player, game *model.Game, timeControl TimeControl,
+) Match {
+	setUpPlayers(black, white)
+	game.SetDrawRules(model.FIDEDraws)
+	return Match{black: black, white: white, game: game,
+		gameOver: make(chan struct{}), timeControl: timeControl,
+		clocks: timeControl.newClocks()}
+}
+
+func setUpPlayers(black *Player, white *Player) {
 	black.color = model.Black
 	white.color = model.White
 	if black.name == white.name {
 		black.name = black.name + "_black"
 		white.name = white.name + "_white"
 	}
-	game := model.NewGameNoPawns()
-	return Match{black, white, game, make(chan struct{}), maxTimeMs, nil,
-		sync.RWMutex{}}
 }
 
 // DefaultMatchGenerator default match generator
@@ -72,6 +117,60 @@ func CreateCustomMatchGenerator(matchPlayerTimeSeconds int) MatchGenerator {
 	}
 }
 
+// Chess960MatchGenerator create a Chess960 match from a random start
+// position
+func Chess960MatchGenerator(p1 *Player, p2 *Player) Match {
+	return CreateCustomChess960MatchGenerator(1200)(p1, p2)
+}
+
+// CreateCustomChess960MatchGenerator create a generator that creates Chess960
+// matches from a random start position with a custom match length in seconds
+func CreateCustomChess960MatchGenerator(
+	matchPlayerTimeSeconds int,
+) MatchGenerator {
+	return CreateTimeControlChess960MatchGenerator(
+		NewTimeControl(int64(matchPlayerTimeSeconds*1000), 0))
+}
+
+// CreateTimeControlChess960MatchGenerator create a generator that creates
+// Chess960 matches from a random start position with the time control
+func CreateTimeControlChess960MatchGenerator(
+	timeControl TimeControl,
+) MatchGenerator {
+	return func(p1 *Player, p2 *Player) Match {
+		r := rand.New(rand.NewSource(time.Now().UnixNano()))
+		// Every index is a valid start position so this cannot fail.
+		game, _ := model.NewGameChess960(r.Intn(960))
+		if r.Intn(2) > 0 {
+			return newMatchFromGame(p1, p2, game, timeControl)
+		}
+		return newMatchFromGame(p2, p1, game, timeControl)
+	}
+}
+
+// CreateCustomVariantMatchGenerator create a generator that creates matches of
+// the variant with a custom match length in seconds
+func CreateCustomVariantMatchGenerator(
+	matchPlayerTimeSeconds int, variant model.Variant,
+) MatchGenerator {
+	return CreateTimeControlMatchGenerator(
+		NewTimeControl(int64(matchPlayerTimeSeconds*1000), 0), variant)
+}
+
+// CreateTimeControlMatchGenerator create a generator that creates matches of
+// the variant with the time control
+func CreateTimeControlMatchGenerator(
+	timeControl TimeControl, variant model.Variant,
+) MatchGenerator {
+	return func(p1 *Player, p2 *Player) Match {
+		r := rand.New(rand.NewSource(time.Now().UnixNano()))
+		if r.Intn(2) > 0 {
+			return NewTimeControlMatch(p1, p2, timeControl, variant)
+		}
+		return NewTimeControlMatch(p2, p1, timeControl, variant)
+	}
+}
+
 // PlayerName get the player name corresponding to the input color
 func (match *Match) PlayerName(color model.Color) string {
 	match.mutex.RLock()
@@ -103,14 +202,80 @@ func (match *Match) SetRequestedDraw(player *Player) {
 	match.requestedDraw = player
 }
 
-// MaxTimeMs return the match's max time
+// StartFEN get the match's start position in Forsyth-Edwards Notation
+func (match *Match) StartFEN() string {
+	return match.game.StartFEN()
+}
+
+// Chess960 get whether the match is a Chess960 match
+func (match *Match) Chess960() bool {
+	return match.game.Chess960()
+}
+
+// Variant get the match's variant
+func (match *Match) Variant() model.Variant {
+	return match.game.Variant()
+}
+
+// LegalMoves get the legal moves for the side to move, only those of the
+// piece on the position if it is given
+func (match *Match) LegalMoves(from *model.Position) LegalMovesResponse {
+	// A copy taken at once is answered for, rather than a game that may be
+	// moved between its queries.
+	position := match.game.Clone()
+	var moves []model.MoveRequest
+	if from == nil {
+		moves = position.LegalMoves()
+	} else {
+		moves = position.LegalMovesFrom(*from)
+	}
+	return LegalMovesResponse{
+		Turn: position.Turn(), Moves: moves, InCheck: position.InCheck(),
+		Checkmate: position.IsCheckmate(), Stalemate: position.IsStalemate(),
+	}
+}
+
+// MaxTimeMs return the match's max time, the base time of its time
+// control's first stage
 func (match *Match) MaxTimeMs() int64 {
-	return match.maxTimeMs
+	return match.timeControl.Stages[0].BaseMs
+}
+
+// TimeControl get the match's time control
+func (match *Match) TimeControl() TimeControl {
+	return match.timeControl
+}
+
+// Rated get whether the match moves the players' ratings
+func (match *Match) Rated() bool {
+	return match.ratings != nil
+}
+
+// Rating get the color's rating in the match's pool
+func (match *Match) Rating(color model.Color) Rating {
+	return match.ratings.get(match.ratingPool, match.PlayerName(color))
+}
+
+// Clock get the color's clock, not counting the current turn
+func (match *Match) Clock(color model.Color) Clock {
+	match.mutex.RLock()
+	defer match.mutex.RUnlock()
+	return match.timeControl.clock(match.clocks[color])
+}
+
+// clock get each side's clock, not counting the current turn
+func (match *Match) clock() engineClock {
+	return engineClock{
+		white: match.Clock(model.White), black: match.Clock(model.Black),
+	}
 }
 
 func (match *Match) play() {
 	waitc := make(chan struct{})
 	go match.handleAsyncRequests(waitc)
+	if match.bughouse != nil {
+		go match.handlePartnerGameOver()
+	}
 	for !match.game.GameOver() {
 		match.handleTurn()
 	}
@@ -129,9 +294,10 @@ func (match *Match) handleTurn() {
 		opponent = match.black
 	}
 	turnStart := time.Now()
-	timeRemaining := match.maxTimeMs - player.elapsedMs
-	timer := time.AfterFunc(time.Duration(timeRemaining)*time.Millisecond,
-		match.handleTimeout(opponent))
+	match.mutex.RLock()
+	flagAfter := match.timeControl.flagAfter(match.clocks[player.color])
+	match.mutex.RUnlock()
+	timer := time.AfterFunc(flagAfter, match.handleTimeout(opponent))
 	defer timer.Stop()
 	request := model.MoveRequest{}
 	select {
@@ -159,10 +325,19 @@ func (match *Match) handleTurn() {
 		return
 	}
 	match.SetRequestedDraw(nil)
-	player.elapsedMs += time.Since(turnStart).Milliseconds()
+	if match.bughouse != nil {
+		match.bughouse.passCapture(match.board)
+	}
+	usedMs := time.Since(turnStart).Milliseconds()
+	player.elapsedMs += usedMs
+	match.mutex.Lock()
+	match.timeControl.punch(&match.clocks[player.color], usedMs)
+	match.mutex.Unlock()
 	player.ResponseChanSync <- ResponseSync{
 		MoveSuccess: true, ElapsedMs: int(player.elapsedMs),
 		ElapsedMsOpponent: int(opponent.elapsedMs),
+		Clock:             match.Clock(player.color),
+		OpponentClock:     match.Clock(opponent.color),
 	}
 	opponent.OpponentPlayedMove <- request
 	if match.game.GameOver() {
@@ -171,20 +346,17 @@ func (match *Match) handleTurn() {
 		if result.Winner == model.White {
 			winner = match.white
 		}
-		match.handleGameOver(result.Draw, false, false, winner)
+		match.handleGameOver(result.Draw, result.Termination, winner)
 	}
 }
 
 func (match *Match) handleTimeout(opponent *Player) func() {
 	return func() {
-		onlyKing := match.game.OnlyKing(model.Black)
-		if opponent.color == model.White {
-			onlyKing = match.game.OnlyKing(model.White)
-		}
-		if onlyKing {
-			match.handleGameOver(true, false, true, opponent)
+		if !match.game.CanWin(opponent.color) {
+			match.handleGameOver(true, model.TimeoutVsInsufficientMaterial,
+				opponent)
 		} else {
-			match.handleGameOver(false, false, true, opponent)
+			match.handleGameOver(false, model.Timeout, opponent)
 		}
 	}
 }
@@ -203,19 +375,36 @@ func (match *Match) handleAsyncRequests(waitc chan struct{}) {
 		case <-match.gameOver:
 			return
 		}
-		if request.Resign {
-			match.handleGameOver(false, true, false, opponent)
+		if request.Abandon {
+			// Leaving before both players have moved aborts the game.
+			if len(match.game.MoveHistory()) < 2 {
+				match.handleGameOver(false, model.Aborted, opponent)
+			} else {
+				match.handleGameOver(false, model.Abandonment, opponent)
+			}
 			return
+		} else if request.Resign {
+			match.handleGameOver(false, model.Resignation, opponent)
+			return
+		} else if request.ClaimDraw {
+			// Claims are ignored unless the claimant is to move and the
+			// position allows one, as under FIDE articles 9.2 and 9.3.
+			if match.game.Turn() == player.color &&
+				match.game.ClaimDraw() == nil {
+				match.handleGameOver(true, match.game.Result().Termination,
+					opponent)
+				return
+			}
 		} else if request.RequestToDraw {
 			if match.GetRequestedDraw() == opponent {
-				match.handleGameOver(true, false, false, opponent)
+				match.handleGameOver(true, model.Agreement, opponent)
 			} else if match.GetRequestedDraw() == player {
 				// Consider the second requestToDraw a toggle.
 				match.SetRequestedDraw(nil)
 				go func() {
 					select {
 					case opponent.ResponseChanAsync <- ResponseAsync{
-						false, true, false, false, false, "",
+						RequestToDraw: true,
 					}:
 					case <-match.gameOver:
 					}
@@ -225,7 +414,7 @@ func (match *Match) handleAsyncRequests(waitc chan struct{}) {
 				go func() {
 					select {
 					case opponent.ResponseChanAsync <- ResponseAsync{
-						false, true, false, false, false, "",
+						RequestToDraw: true,
 					}:
 					case <-match.gameOver:
 					}
@@ -236,7 +425,7 @@ func (match *Match) handleAsyncRequests(waitc chan struct{}) {
 }
 
 func (match *Match) handleGameOver(
-	draw, resignation, timeout bool, winner *Player,
+	draw bool, termination model.Termination, winner *Player,
 ) {
 	match.mutex.Lock()
 	defer match.mutex.Unlock()
@@ -246,25 +435,62 @@ func (match *Match) handleGameOver(
 	default:
 		break
 	}
-	match.game.SetGameResult(winner.color, draw)
+	match.game.SetGameResult(winner.color, draw, termination)
 	winnerName := winner.name
-	if draw {
+	if draw || termination == model.Aborted {
 		winnerName = ""
 	}
 	response := ResponseAsync{GameOver: true, Draw: draw,
-		Resignation: resignation, Timeout: timeout, Winner: winnerName}
+		Resignation: termination == model.Resignation,
+		Timeout: termination == model.Timeout ||
+			termination == model.TimeoutVsInsufficientMaterial,
+		Winner: winnerName, Termination: termination}
+	responses := [2]ResponseAsync{response, response}
+	if match.Rated() && termination != model.Aborted {
+		responses = match.recordRatings(response, draw, winner.color)
+	}
 	var wg sync.WaitGroup
-	for _, player := range [2]*Player{match.black, match.white} {
-		thisPlayer := player
+	// Colors index black then white.
+	for color, player := range [2]*Player{match.black, match.white} {
+		thisPlayer, thisResponse := player, responses[color]
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
 			select {
-			case thisPlayer.ResponseChanAsync <- response:
+			case thisPlayer.ResponseChanAsync <- thisResponse:
 			case <-time.After(5 * time.Second):
 			}
 		}()
 	}
 	wg.Wait()
 	close(match.gameOver)
+	if match.bughouse != nil {
+		match.bughouse.end(match.board, winner.color, draw, termination)
+	}
+}
+
+// recordRatings record the rated match's result and get each color's game
+// over response with the ratings it moved them to
+func (match *Match) recordRatings(
+	response ResponseAsync, draw bool, winner model.Color,
+) [2]ResponseAsync {
+	whiteScore := 0.5
+	if !draw && winner == model.White {
+		whiteScore = 1
+	} else if !draw {
+		whiteScore = 0
+	}
+	before, after := match.ratings.record(match.ratingPool,
+		[2]string{match.black.name, match.white.name}, whiteScore)
+	responses := [2]ResponseAsync{}
+	for color := range responses {
+		responses[color] = response
+		responses[color].Rating = after[color]
+		responses[color].RatingDelta = after[color].Rating -
+			before[color].Rating
+		responses[color].OpponentRating = after[1-color]
+		responses[color].OpponentRatingDelta = after[1-color].Rating -
+			before[1-color].Rating
+	}
+	return responses
 }
@@ -0,0 +1,100 @@
+package matchserver
+
+import (
+	"math"
+	"time"
+)
+
+// matchmakingInterval is how often the pools are searched for pairings as
+// their players' rating windows widen
+const matchmakingInterval = 100 * time.Millisecond
+
+// matchmakingPolicy is how far apart in rating two players may be to be
+// paired, and how soon the same two players may be paired again
+type matchmakingPolicy struct {
+	// initialWindow is the rating difference a player accepts on joining a
+	// pool, which widens by widenBy for each widenEvery they wait.
+	initialWindow float64
+	widenBy       float64
+	widenEvery    time.Duration
+	// rematchAfter is how long a player waits before they may be paired with
+	// their last opponent again.
+	rematchAfter time.Duration
+}
+
+var defaultMatchmakingPolicy = matchmakingPolicy{
+	initialWindow: 100, widenBy: 50, widenEvery: 5 * time.Second,
+	rematchAfter: 20 * time.Second,
+}
+
+// window get the rating difference a player accepts after waiting
+func (policy matchmakingPolicy) window(waited time.Duration) float64 {
+	return policy.initialWindow +
+		policy.widenBy*float64(waited/policy.widenEvery)
+}
+
+// matchmake pair the pool's players, in the order they joined it, each with
+// the closest rated player with the same seek within the window of whichever
+// of the two has waited longer, never with their last opponent until they
+// have waited long enough for a rematch. Players seeking a bot, and players
+// left who have waited the bot wait unless it is negative, are to be paired
+// with bots. Get the pairs, the players to be paired with bots, and the
+// players still waiting.
+func (policy matchmakingPolicy) matchmake(
+	seeking []*seekingPlayer, now time.Time, botWait time.Duration,
+) (pairs [][2]*seekingPlayer, bots []*seekingPlayer,
+	waiting []*seekingPlayer) {
+	paired := make([]bool, len(seeking))
+	for i, player := range seeking {
+		if paired[i] {
+			continue
+		}
+		best := -1
+		for j := i + 1; j < len(seeking); j++ {
+			if !paired[j] && policy.acceptable(player, seeking[j], now) &&
+				(best < 0 || ratingGap(player, seeking[j]) <
+					ratingGap(player, seeking[best])) {
+				best = j
+			}
+		}
+		if best >= 0 {
+			paired[i], paired[best] = true, true
+			pairs = append(pairs, [2]*seekingPlayer{player, seeking[best]})
+		}
+	}
+	for i, player := range seeking {
+		if paired[i] {
+			continue
+		} else if player.botLevel != nil ||
+			(botWait >= 0 && now.Sub(player.since) >= botWait) {
+			bots = append(bots, player)
+		} else {
+			waiting = append(waiting, player)
+		}
+	}
+	return pairs, bots, waiting
+}
+
+// acceptable get whether the players may be paired now
+func (policy matchmakingPolicy) acceptable(
+	player1 *seekingPlayer, player2 *seekingPlayer, now time.Time,
+) bool {
+	if player1.seek.String() != player2.seek.String() ||
+		player1.botLevel != nil || player2.botLevel != nil {
+		return false
+	}
+	waited := now.Sub(player1.since)
+	if player2.since.Before(player1.since) {
+		waited = now.Sub(player2.since)
+	}
+	if (player1.player.lastOpponent() == player2.player ||
+		player2.player.lastOpponent() == player1.player) &&
+		waited < policy.rematchAfter {
+		return false
+	}
+	return ratingGap(player1, player2) <= policy.window(waited)
+}
+
+func ratingGap(player1 *seekingPlayer, player2 *seekingPlayer) float64 {
+	return math.Abs(player1.rating - player2.rating)
+}
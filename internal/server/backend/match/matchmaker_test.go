@@ -0,0 +1,205 @@
+package matchserver
+
+import (
+	"math/rand"
+	"reflect"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// simulatedPairing is a pairing the matchmaker made in a simulation, after
+// the longer waiting player waited, with a bot if the second player is nil
+type simulatedPairing struct {
+	at               time.Duration
+	player1, player2 *seekingPlayer
+	waited           time.Duration
+}
+
+// simulatedPlayer is a player in a simulation who joins the pool at a time
+type simulatedPlayer struct {
+	seeking *seekingPlayer
+	joinAt  time.Duration
+	joined  bool
+}
+
+// simulate run the policy over simulated time, searching the pool every
+// matchmaking interval as the real matchmaker does. Players with the ratings
+// join the pool at the times, and rejoin it after each game, which lasts a
+// random time under the max game length drawn from the seeded source.
+func simulate(
+	policy matchmakingPolicy, seed int64, ratings []float64,
+	joinAt []time.Duration, maxGameLength time.Duration,
+	duration time.Duration, botWait time.Duration,
+) []simulatedPairing {
+	source := rand.New(rand.NewSource(seed))
+	start := time.Unix(0, 0)
+	players := []*simulatedPlayer{}
+	for i, rating := range ratings {
+		players = append(players, &simulatedPlayer{
+			seeking: &seekingPlayer{
+				player: NewPlayer("player" + strconv.Itoa(i)), rating: rating,
+			},
+			joinAt: joinAt[i],
+		})
+	}
+	pairings := []simulatedPairing{}
+	seeking := []*seekingPlayer{}
+	for at := time.Duration(0); at <= duration; at += matchmakingInterval {
+		// Players join the pool in the order they arrive.
+		sort.SliceStable(players, func(i, j int) bool {
+			return players[i].joinAt < players[j].joinAt
+		})
+		for _, player := range players {
+			if !player.joined && player.joinAt <= at {
+				player.joined = true
+				player.seeking.since = start.Add(player.joinAt)
+				seeking = append(seeking, player.seeking)
+			}
+		}
+		now := start.Add(at)
+		pairs, bots, waiting := policy.matchmake(seeking, now, botWait)
+		seeking = waiting
+		paired := map[*seekingPlayer]bool{}
+		for _, pair := range pairs {
+			paired[pair[0]], paired[pair[1]] = true, true
+			pair[0].player.setLastOpponent(pair[1].player)
+			pair[1].player.setLastOpponent(pair[0].player)
+			since := pair[0].since
+			if pair[1].since.Before(since) {
+				since = pair[1].since
+			}
+			pairings = append(pairings, simulatedPairing{
+				at: at, player1: pair[0], player2: pair[1],
+				waited: now.Sub(since),
+			})
+		}
+		for _, bot := range bots {
+			paired[bot] = true
+			pairings = append(pairings, simulatedPairing{
+				at: at, player1: bot, waited: now.Sub(bot.since),
+			})
+		}
+		for _, player := range players {
+			if paired[player.seeking] {
+				player.joined = false
+				player.joinAt = at +
+					time.Duration(source.Int63n(int64(maxGameLength)))
+			}
+		}
+	}
+	return pairings
+}
+
+func TestMatchmakerSimulation(t *testing.T) {
+	source := rand.New(rand.NewSource(1))
+	ratings, joinAt := []float64{}, []time.Duration{}
+	for i := 0; i < 40; i++ {
+		ratings = append(ratings, 1500+source.NormFloat64()*300)
+		joinAt = append(joinAt,
+			time.Duration(source.Int63n(int64(time.Minute))))
+	}
+	botWait := 45 * time.Second
+	pairings := simulate(defaultMatchmakingPolicy, 1, ratings, joinAt,
+		5*time.Minute, time.Hour, botWait)
+	lastOpponents := map[*seekingPlayer]*seekingPlayer{}
+	bots := 0
+	for _, pairing := range pairings {
+		if pairing.player2 == nil {
+			bots++
+			if pairing.waited < botWait ||
+				pairing.waited >= botWait+matchmakingInterval {
+				t.Error("Expected a bot after ", botWait, " got ",
+					pairing.waited)
+			}
+			lastOpponents[pairing.player1] = nil
+			continue
+		}
+		if gap := ratingGap(pairing.player1, pairing.player2); gap >
+			defaultMatchmakingPolicy.window(pairing.waited) {
+			t.Error("Expected a rating gap within the window got ", gap,
+				" after ", pairing.waited)
+		}
+		if (lastOpponents[pairing.player1] == pairing.player2 ||
+			lastOpponents[pairing.player2] == pairing.player1) &&
+			pairing.waited < defaultMatchmakingPolicy.rematchAfter {
+			t.Error("Expected no immediate rematch got ",
+				pairing.player1.player.name, " and ",
+				pairing.player2.player.name, " at ", pairing.at)
+		}
+		lastOpponents[pairing.player1] = pairing.player2
+		lastOpponents[pairing.player2] = pairing.player1
+	}
+	if len(pairings) < 200 || bots > len(pairings)/4 {
+		t.Error("Expected mostly human pairings got ", len(pairings),
+			" pairings with ", bots, " bots")
+	}
+}
+
+func TestMatchmakerSimulationDeterministic(t *testing.T) {
+	run := func() []string {
+		ratings := []float64{1200, 1500, 1550, 1900, 1450, 1300}
+		joinAt := []time.Duration{0, time.Second, 2 * time.Second,
+			3 * time.Second, 10 * time.Second, 20 * time.Second}
+		names := []string{}
+		for _, pairing := range simulate(defaultMatchmakingPolicy, 7,
+			ratings, joinAt, time.Minute, 10*time.Minute, time.Minute) {
+			name := pairing.at.String() + " " + pairing.player1.player.name
+			if pairing.player2 != nil {
+				name += " " + pairing.player2.player.name
+			}
+			names = append(names, name)
+		}
+		return names
+	}
+	first, second := run(), run()
+	if len(first) == 0 || !reflect.DeepEqual(first, second) {
+		t.Error("Expected the same pairings from the same seed got ", first,
+			" and ", second)
+	}
+}
+
+func TestMatchmakerWidening(t *testing.T) {
+	pairings := simulate(defaultMatchmakingPolicy, 1, []float64{1500, 1800},
+		[]time.Duration{0, 0}, time.Hour, time.Minute, -1)
+	// The window widens from 100 by 50 each 5 seconds to reach 300.
+	if len(pairings) != 1 || pairings[0].at != 20*time.Second {
+		t.Error("Expected a pairing after 20s got ", pairings)
+	}
+}
+
+func TestMatchmakerClosestOpponent(t *testing.T) {
+	pairings := simulate(defaultMatchmakingPolicy, 1,
+		[]float64{1500, 1580, 1510, 1590}, []time.Duration{0, 0, 0, 0},
+		time.Hour, 0, -1)
+	if len(pairings) != 2 || pairings[0].player2.rating != 1510 ||
+		pairings[1].player2.rating != 1590 {
+		t.Error("Expected the closest players paired got ", pairings)
+	}
+}
+
+func TestMatchmakerRematch(t *testing.T) {
+	// The two players rejoin the pool as soon as they are paired.
+	pairings := simulate(defaultMatchmakingPolicy, 1, []float64{1500, 1500},
+		[]time.Duration{0, 0}, time.Nanosecond, 30*time.Second, -1)
+	if len(pairings) != 2 || pairings[1].at != 20*time.Second {
+		t.Error("Expected a rematch only after 20s got ", pairings)
+	}
+	pairings = simulate(defaultMatchmakingPolicy, 1,
+		[]float64{1500, 1500, 1600}, []time.Duration{0, 0, time.Second},
+		time.Nanosecond, time.Second, -1)
+	if len(pairings) != 2 || pairings[1].player2.rating != 1600 {
+		t.Error("Expected a new opponent rather than a rematch got ",
+			pairings)
+	}
+}
+
+func TestMatchmakerBotWait(t *testing.T) {
+	pairings := simulate(defaultMatchmakingPolicy, 1, []float64{1500, 2500},
+		[]time.Duration{0, 0}, time.Hour, time.Minute, 30*time.Second)
+	if len(pairings) != 2 || pairings[0].player2 != nil ||
+		pairings[0].at != 30*time.Second {
+		t.Error("Expected bots after 30s got ", pairings)
+	}
+}
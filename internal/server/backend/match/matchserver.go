@@ -4,15 +4,12 @@ import (
 	"context"
 	"errors"
 	"log"
-	"math/rand"
 	"strconv"
 	"sync"
 	"time"
 
-	pb "github.com/Ekotlikoff/gochess/api"
 	"github.com/Ekotlikoff/gochess/internal/model"
 	"github.com/prometheus/client_golang/prometheus"
-	"google.golang.org/grpc"
 )
 
 var (
@@ -37,6 +34,12 @@ const (
 	ResponseAsyncT = WebsocketResponseType(iota)
 	// OpponentPlayedMoveT is the WS response type for an opponent's move
 	OpponentPlayedMoveT = WebsocketResponseType(iota)
+	// RequestLegalMovesT is the WS request type for a legal moves query
+	RequestLegalMovesT = WebsocketRequestType(iota)
+	// ResponseLegalMovesT is the WS response type for a legal moves query
+	ResponseLegalMovesT = WebsocketResponseType(iota)
+	// CancelSeekT is the WS request type for cancelling a match request
+	CancelSeekT = WebsocketRequestType(iota)
 )
 
 type (
@@ -51,6 +54,7 @@ type (
 		ResponseSync          ResponseSync
 		ResponseAsync         ResponseAsync
 		OpponentPlayedMove    model.MoveRequest
+		LegalMoves            LegalMovesResponse
 	}
 
 	// WebsocketRequestType represents the different type of requests supported
@@ -62,13 +66,29 @@ type (
 		WebsocketRequestType WebsocketRequestType
 		RequestSync          model.MoveRequest
 		RequestAsync         RequestAsync
+		// LegalMovesFrom limits a legal moves query to the piece on the
+		// position if it is set.
+		LegalMovesFrom *model.Position
 	}
 
 	// MatchedResponse is a struct for the matched response
 	MatchedResponse struct {
-		Color        model.Color
-		OpponentName string
-		MaxTimeMs    int64
+		Color         model.Color
+		OpponentName  string
+		MaxTimeMs     int64
+		TimeControl   TimeControl
+		Clock         Clock
+		OpponentClock Clock
+		StartFEN      string
+		Chess960      bool
+		Variant       string
+		// Rated games move the players' ratings in the pool, by as much as
+		// the rating deltas for the player's result.
+		Rated          bool
+		Pool           string
+		Rating         Rating
+		OpponentRating Rating
+		RatingDeltas   RatingDeltas
 	}
 
 	// Player is a struct representing a matchserver client, containing channels
@@ -89,6 +109,15 @@ type (
 		matchMutex          sync.RWMutex
 		searchingForMatch   bool
 		match               *Match
+		// lastGame is the player's last finished game, kept for review.
+		lastGame *model.Game
+		// lastPaired is the player's last opponent, whom the matchmaker
+		// avoids pairing them with again right away.
+		lastPaired *Player
+
+		// ResponseChanLegalMoves carries the answers to a websocket client's
+		// legal moves queries.
+		ResponseChanLegalMoves chan LegalMovesResponse
 	}
 )
 
@@ -129,9 +158,39 @@ func (player *Player) GetMatch() *Match {
 func (player *Player) SetMatch(match *Match) {
 	player.matchMutex.Lock()
 	defer player.matchMutex.Unlock()
+	if player.match != nil && match == nil {
+		player.lastGame = player.match.game
+	}
 	player.match = match
 }
 
+// lastOpponent get the player the matchmaker last paired the player with
+func (player *Player) lastOpponent() *Player {
+	player.matchMutex.RLock()
+	defer player.matchMutex.RUnlock()
+	return player.lastPaired
+}
+
+// setLastOpponent set the player the matchmaker last paired the player with
+func (player *Player) setLastOpponent(opponent *Player) {
+	player.matchMutex.Lock()
+	defer player.matchMutex.Unlock()
+	player.lastPaired = opponent
+}
+
+// ReviewGame get the player's game in progress, or else their last finished
+// game, for analysis
+func (player *Player) ReviewGame() (*model.Game, error) {
+	player.matchMutex.RLock()
+	defer player.matchMutex.RUnlock()
+	if player.match != nil {
+		return player.match.game, nil
+	} else if player.lastGame != nil {
+		return player.lastGame, nil
+	}
+	return nil, errors.New("player has no game to review")
+}
+
 // MatchedOpponentName returns the matched opponent name
 func (player *Player) MatchedOpponentName() string {
 	player.matchMutex.RLock()
@@ -148,6 +207,35 @@ func (player *Player) MatchMaxTimeMs() int64 {
 	return player.GetMatch().MaxTimeMs()
 }
 
+// MatchedResponse get the details of the player's newly started match
+func (player *Player) MatchedResponse() MatchedResponse {
+	match := player.GetMatch()
+	color, opponentColor := player.Color(), model.White
+	if color == model.White {
+		opponentColor = model.Black
+	}
+	matchedResponse := MatchedResponse{
+		Color:         color,
+		OpponentName:  player.MatchedOpponentName(),
+		MaxTimeMs:     match.MaxTimeMs(),
+		TimeControl:   match.TimeControl(),
+		Clock:         match.Clock(color),
+		OpponentClock: match.Clock(opponentColor),
+		StartFEN:      match.StartFEN(),
+		Chess960:      match.Chess960(),
+		Variant:       match.Variant().Name(),
+	}
+	if match.Rated() {
+		matchedResponse.Rated = true
+		matchedResponse.Pool = match.ratingPool
+		matchedResponse.Rating = match.Rating(color)
+		matchedResponse.OpponentRating = match.Rating(opponentColor)
+		matchedResponse.RatingDeltas = matchedResponse.Rating.deltas(
+			matchedResponse.OpponentRating)
+	}
+	return matchedResponse
+}
+
 // Color returns player color
 func (player *Player) Color() model.Color {
 	player.matchMutex.RLock()
@@ -188,11 +276,43 @@ func (player *Player) MakeMoveWS(pieceMove model.MoveRequest) {
 
 // MakeMove player makes a move
 func (player *Player) MakeMove(pieceMove model.MoveRequest) bool {
+	return player.MakeMoveResponse(pieceMove).MoveSuccess
+}
+
+// MakeMoveResponse player makes a move and gets the match's response, with
+// the clocks if the move succeeded
+func (player *Player) MakeMoveResponse(
+	pieceMove model.MoveRequest,
+) ResponseSync {
 	player.ChannelMutex.RLock()
 	defer player.ChannelMutex.RUnlock()
 	player.requestChanSync <- pieceMove
-	response := <-player.ResponseChanSync
-	return response.MoveSuccess
+	return <-player.ResponseChanSync
+}
+
+// LegalMoves get the legal moves in the player's match, only those of the
+// piece on the position if it is given
+func (player *Player) LegalMoves(
+	from *model.Position,
+) (LegalMovesResponse, error) {
+	match := player.GetMatch()
+	if match == nil {
+		return LegalMovesResponse{}, errors.New("player is not in a match")
+	}
+	return match.LegalMoves(from), nil
+}
+
+// RequestLegalMovesWS player (websocket client) query the legal moves in their
+// match, the answer is sent on ResponseChanLegalMoves
+func (player *Player) RequestLegalMovesWS(from *model.Position) error {
+	legalMoves, err := player.LegalMoves(from)
+	if err != nil {
+		return err
+	}
+	player.ChannelMutex.RLock()
+	defer player.ChannelMutex.RUnlock()
+	player.ResponseChanLegalMoves <- legalMoves
+	return nil
 }
 
 // GetSyncUpdate get the next sync update for a player
@@ -242,6 +362,7 @@ func (player *Player) Reset() {
 	player.RequestChanAsync = make(chan RequestAsync, 1)
 	player.ResponseChanAsync = make(chan ResponseAsync, 1)
 	player.OpponentPlayedMove = make(chan model.MoveRequest, 10)
+	player.ResponseChanLegalMoves = make(chan LegalMovesResponse, 1)
 	player.matchStartMutex.Lock()
 	defer player.matchStartMutex.Unlock()
 	player.matchStart = make(chan struct{})
@@ -271,22 +392,53 @@ func (player *Player) WaitForClientToBeDoneWithMatch() {
 	<-player.clientDoneWithMatch
 }
 
-// ResponseSync represents a response to the client related to a move
+// ResponseSync represents a response to the client related to a move, with
+// the time each player has spent on their moves and both clocks
 type ResponseSync struct {
 	MoveSuccess       bool
 	ElapsedMs         int
 	ElapsedMsOpponent int
+	Clock             Clock
+	OpponentClock     Clock
 }
 
-// RequestAsync represents a request from the client unrelated to a move
+// LegalMovesResponse represents the legal moves for the side to move in a
+// match, so that clients need not know the rules
+type LegalMovesResponse struct {
+	Turn                          model.Color
+	Moves                         []model.MoveRequest
+	InCheck, Checkmate, Stalemate bool
+}
+
+// RequestAsync represents a request from the client unrelated to a move,
+// Abandon is sent on the player's behalf when their connection is lost
 type RequestAsync struct {
-	Match, RequestToDraw, Resign bool
+	Match, RequestToDraw, ClaimDraw, Resign, Abandon bool
+
+	// BotLevel is the name of a bot difficulty level to be matched with
+	// right away when requesting a match, rather than a human opponent.
+	BotLevel string
+	// TimeControl and Variant are the game sought when requesting a match,
+	// as NewSeek takes them, or empty for the server's own games, which is
+	// rated if Rated is set.
+	TimeControl, Variant string
+	Rated                bool
 }
 
 // ResponseAsync represents a response to the client unrelated to a move
 type ResponseAsync struct {
 	GameOver, RequestToDraw, Draw, Resignation, Timeout bool
 	Winner                                              string
+	Termination                                         model.Termination
+
+	// Pockets are each color's pocketed pieces by type, sent when a Bughouse
+	// partner passes a piece.
+	Pockets [2]map[model.PieceType]uint8
+
+	// Rating and OpponentRating are the players' ratings after a rated
+	// game, which moved by RatingDelta and OpponentRatingDelta.
+	Rating, OpponentRating           Rating
+	RatingDelta, OpponentRatingDelta float64
 }
 
 // MatchingServer handles matching players and carrying out the game
@@ -294,13 +446,19 @@ type MatchingServer struct {
 	id                        int
 	liveMatches               []*Match
 	liveMatchesMetric         prometheus.Gauge
-	matchingQueueLengthMetric prometheus.Gauge
+	matchingQueueLengthMetric *prometheus.GaugeVec
 	mutex                     *sync.Mutex
-	matchingPlayers           chan *Player
-	pendingMatch              *sync.Mutex
-	botMatchingEnabled        bool
-	engineClient              pb.RustChessClient
-	engineClientConn          *grpc.ClientConn
+	idleMatchServers          int
+	pools                     map[string]*matchingPool
+	pairings                  chan pairing
+	matchmakingPolicy         matchmakingPolicy
+	ratings                   *ratingStore
+	enginePool                *enginePool
+	builtinEngineEnabled      bool
+	builtinEngineDepth        int
+	builtinEngineMoveTime     time.Duration
+	uciEnginePath             string
+	matchGenerator            MatchGenerator
 	maxMatchingDuration       time.Duration
 }
 
@@ -308,19 +466,24 @@ type MatchingServer struct {
 func NewMatchingServer() MatchingServer {
 	matchingServer := MatchingServer{
 		id: matchingServerID, mutex: &sync.Mutex{},
-		matchingPlayers: make(chan *Player), pendingMatch: &sync.Mutex{},
+		pools: newMatchingPools(), pairings: make(chan pairing),
+		ratings:           newRatingStore(),
+		matchmakingPolicy: defaultMatchmakingPolicy,
 	}
 	matchingServerID++
-	matchingQueueLengthMetric := prometheus.NewGauge(prometheus.GaugeOpts{
+	matchingQueueLengthMetric := prometheus.NewGaugeVec(prometheus.GaugeOpts{
 		Namespace: "gochess",
 		Subsystem: "matchserver",
 		Name:      "matching_queue_length",
-		Help:      "The number of players in the matching queue.",
+		Help:      "The number of players in each pool's matching queue.",
 		ConstLabels: prometheus.Labels{
 			"matching_server_id": strconv.Itoa(matchingServer.id),
 		},
-	})
+	}, []string{"pool"})
 	prometheus.MustRegister(matchingQueueLengthMetric)
+	for _, pool := range Pools {
+		matchingQueueLengthMetric.WithLabelValues(pool).Set(0)
+	}
 	matchingServer.matchingQueueLengthMetric = matchingQueueLengthMetric
 	return matchingServer
 }
@@ -329,13 +492,51 @@ func NewMatchingServer() MatchingServer {
 func NewMatchingServerWithEngine(
 	engineAddr string, maxMatchingDuration time.Duration,
 	engineConnTimeout time.Duration,
+) MatchingServer {
+	return NewMatchingServerWithEngines([]string{engineAddr}, 0,
+		maxMatchingDuration, engineConnTimeout)
+}
+
+// NewMatchingServerWithEngines create a matching server with a pool of the
+// engines at the addresses, each playing at most the max games at once or any
+// number if it is 0. Bot games go to the healthy engine with the fewest
+// games, and failed engines are reconnected to with backoff.
+func NewMatchingServerWithEngines(
+	engineAddrs []string, maxGamesPerEngine int,
+	maxMatchingDuration time.Duration, engineConnTimeout time.Duration,
 ) MatchingServer {
 	matchingServer := NewMatchingServer()
-	matchingServer.createEngineClient(engineAddr, engineConnTimeout)
+	matchingServer.enginePool = newEnginePool(engineAddrs, maxGamesPerEngine,
+		engineConnTimeout)
+	matchingServer.enginePool.start()
 	matchingServer.maxMatchingDuration = maxMatchingDuration
 	return matchingServer
 }
 
+// NewMatchingServerWithUCIEngine create a matching server whose bots are
+// played by the local UCI engine binary at the path, which is launched for
+// each bot match
+func NewMatchingServerWithUCIEngine(
+	uciEnginePath string, maxMatchingDuration time.Duration,
+) MatchingServer {
+	matchingServer := NewMatchingServer()
+	matchingServer.uciEnginePath = uciEnginePath
+	matchingServer.maxMatchingDuration = maxMatchingDuration
+	return matchingServer
+}
+
+// SetBuiltinEngine let bots play with the in-process engine whenever the
+// remote engine is unreachable, searching no deeper than the depth in plies
+// and for no longer than the move time per move whatever the bot's level.
+// Zero limits leave the level's own.
+func (matchingServer *MatchingServer) SetBuiltinEngine(
+	depth int, moveTime time.Duration,
+) {
+	matchingServer.builtinEngineEnabled = true
+	matchingServer.builtinEngineDepth = depth
+	matchingServer.builtinEngineMoveTime = moveTime
+}
+
 // LiveMatches current matches being played
 func (matchingServer *MatchingServer) LiveMatches() []*Match {
 	matchingServer.mutex.Lock()
@@ -344,55 +545,68 @@ func (matchingServer *MatchingServer) LiveMatches() []*Match {
 	return liveMatches
 }
 
-func (matchingServer *MatchingServer) matchAndPlay(
-	matchGenerator MatchGenerator, playServerID int,
-) {
-	var player1, player2 *Player
-	maxMatchingTimer := time.NewTimer(0)
-	<-maxMatchingTimer.C
-	// Lock until a full match is found and started, thus avoiding unmatched
-	// players stranded across goroutines.
-	matchingServer.pendingMatch.Lock()
-	for {
-		select {
-		case player := <-matchingServer.matchingPlayers:
-			if player1 == nil {
-				player1 = player
-				if matchingServer.botMatchingEnabled {
-					maxMatchingTimer.Reset(matchingServer.maxMatchingDuration)
-				}
-			} else if player2 == nil {
-				player2 = player
-				match := matchGenerator(player1, player2)
-				matchingServer.matchingQueueLengthMetric.Sub(2)
-				player1.SetMatch(&match)
-				player2.SetMatch(&match)
-				matchingServer.mutex.Lock()
-				matchingServer.liveMatches =
-					append(matchingServer.liveMatches, &match)
-				matchingServer.mutex.Unlock()
-				matchingServer.pendingMatch.Unlock()
-				player1.startMatch()
-				player2.startMatch()
-				matchingServer.liveMatchesMetric.Inc()
-				(&match).play()
-				matchingServer.liveMatchesMetric.Dec()
-				matchingServer.removeMatch(&match)
-				player1, player2 = nil, nil
-				matchingServer.pendingMatch.Lock()
-			}
-		case <-maxMatchingTimer.C:
-			// The maxMatchingTimer has fired and we should match player1 with a
-			// bot.
-			botNames := [5]string{
-				"jessica", "cherry", "gumdrop", "roland", "pumpkin",
-			}
-			botPlayer := NewPlayer(botNames[rand.Intn(len(botNames))] + "bot")
-			go matchingServer.engineSession(botPlayer)
-			matchingServer.matchingQueueLengthMetric.Inc()
-			go (func() { matchingServer.matchingPlayers <- botPlayer })()
+// Rating get the named player's rating in the pool
+func (matchingServer *MatchingServer) Rating(name string, pool string) Rating {
+	return matchingServer.ratings.get(pool, name)
+}
+
+// matchAndPlay play the pools' pairings one match at a time, starting the
+// bot of pairings with a bot, and pair the pools' players whenever idle again
+func (matchingServer *MatchingServer) matchAndPlay() {
+	for pairing := range matchingServer.pairings {
+		if pairing.player2 == nil {
+			pairing.player2 = matchingServer.startBot(pairing.botLevel)
 		}
+		matchingServer.playMatch(pairing.seek, pairing.player1,
+			pairing.player2)
+		matchingServer.mutex.Lock()
+		matchingServer.idleMatchServers++
+		matchingServer.mutex.Unlock()
+		// Pair in the background as this match server is the one to take
+		// the next pairing.
+		go matchingServer.matchmakePools(time.Now())
+	}
+}
+
+// addMatch create the players' match for the seek and add it to the live
+// matches
+func (matchingServer *MatchingServer) addMatch(
+	seek Seek, player1 *Player, player2 *Player,
+) *Match {
+	match := seek.generator(matchingServer.matchGenerator)(player1, player2)
+	if seek.Rated {
+		match.ratings = matchingServer.ratings
+		match.ratingPool = seek.Pool()
+	}
+	player1.SetMatch(&match)
+	player2.SetMatch(&match)
+	matchingServer.mutex.Lock()
+	matchingServer.liveMatches = append(matchingServer.liveMatches, &match)
+	matchingServer.mutex.Unlock()
+	return &match
+}
+
+// playLiveMatch start the live match and play it to the end
+func (matchingServer *MatchingServer) playLiveMatch(match *Match) {
+	aborted := match.bughouse != nil && !match.bughouse.waitForBoards(match)
+	match.black.startMatch()
+	match.white.startMatch()
+	if aborted {
+		// The players are told right away that the game is over.
+		match.handleGameOver(false, model.Aborted, match.white)
 	}
+	matchingServer.liveMatchesMetric.Inc()
+	match.play()
+	matchingServer.liveMatchesMetric.Dec()
+	matchingServer.removeMatch(match)
+}
+
+// playMatch create the players' match for the seek and play it to the end
+func (matchingServer *MatchingServer) playMatch(
+	seek Seek, player1 *Player, player2 *Player,
+) {
+	matchingServer.playLiveMatch(
+		matchingServer.addMatch(seek, player1, player2))
 }
 
 // StartMatchServers using default match generator
@@ -418,13 +632,22 @@ func (matchingServer *MatchingServer) StartCustomMatchServers(
 		},
 	})
 	prometheus.MustRegister(matchingServer.liveMatchesMetric)
+	matchingServer.matchGenerator = matchGenerator
 	log.Printf("Starting %d matchAndPlay threads ...", maxConcurrentGames)
+	matchingServer.mutex.Lock()
+	matchingServer.idleMatchServers += maxConcurrentGames
+	matchingServer.mutex.Unlock()
 	for i := 0; i < maxConcurrentGames; i++ {
-		go matchingServer.matchAndPlay(matchGenerator, i)
+		go matchingServer.matchAndPlay()
 	}
+	// Pair the players who queued before the match servers started.
+	matchingServer.matchmakePools(time.Now())
+	done := make(chan struct{})
+	go matchingServer.matchmake(done)
 	<-quit // Wait to be told to exit.
-	if matchingServer.engineClientConn != nil {
-		matchingServer.engineClientConn.Close()
+	close(done)
+	if matchingServer.enginePool != nil {
+		matchingServer.enginePool.close()
 	}
 }
 
@@ -445,8 +668,8 @@ func (matchingServer *MatchingServer) removeMatch(matchToRemove *Match) {
 	}
 }
 
-// MatchPlayer queues the player for matching
-func (matchingServer *MatchingServer) MatchPlayer(player *Player) {
-	matchingServer.matchingPlayers <- player
-	matchingServer.matchingQueueLengthMetric.Inc()
+// MatchPlayer queues the player for matching with another player with the
+// same seek, in the seek's pool
+func (matchingServer *MatchingServer) MatchPlayer(player *Player, seek Seek) {
+	matchingServer.queue(player, seek, nil)
 }
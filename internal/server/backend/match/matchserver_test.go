@@ -1,19 +1,26 @@
 package matchserver
 
 import (
+	"context"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/Ekotlikoff/gochess/internal/model"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 func TestMatchingServer(t *testing.T) {
 	player1 := NewPlayer("player1")
 	player2 := NewPlayer("player2")
 	matchingServer := NewMatchingServer()
-	go matchingServer.MatchPlayer(player1)
-	go matchingServer.MatchPlayer(player2)
+	go matchingServer.MatchPlayer(player1, Seek{})
+	go matchingServer.MatchPlayer(player2, Seek{})
 	exitChan := make(chan bool, 1)
 	exitChan <- true
 	matchingServer.StartMatchServers(1, exitChan)
@@ -29,8 +36,8 @@ func TestMatchingServerTimeout(t *testing.T) {
 	player1 := NewPlayer("player1")
 	player2 := NewPlayer("player2")
 	matchingServer := NewMatchingServer()
-	go matchingServer.MatchPlayer(player1)
-	go matchingServer.MatchPlayer(player2)
+	go matchingServer.MatchPlayer(player1, Seek{})
+	go matchingServer.MatchPlayer(player2, Seek{})
 	exitChan := make(chan bool, 1)
 	exitChan <- true
 	generator := func(black *Player, white *Player) Match {
@@ -44,7 +51,8 @@ func TestMatchingServerTimeout(t *testing.T) {
 	}
 	liveMatch := matchingServer.LiveMatches()[0]
 	response := <-player1.ResponseChanAsync
-	if !liveMatch.game.GameOver() || !response.GameOver || !response.Timeout {
+	if !liveMatch.game.GameOver() || !response.GameOver || !response.Timeout ||
+		response.Termination != model.Timeout {
 		t.Error("Expected timed out game got", response)
 	}
 }
@@ -53,8 +61,8 @@ func TestMatchingServerInsufficientMaterialDraw(t *testing.T) {
 	player1 := NewPlayer("player1")
 	player2 := NewPlayer("player2")
 	matchingServer := NewMatchingServer()
-	go matchingServer.MatchPlayer(player1)
-	go matchingServer.MatchPlayer(player2)
+	go matchingServer.MatchPlayer(player1, Seek{})
+	go matchingServer.MatchPlayer(player2, Seek{})
 	exitChan := make(chan bool, 1)
 	exitChan <- true
 	generator := func(black *Player, white *Player) Match {
@@ -185,8 +193,8 @@ func TestMatchingServerInsufficientMaterialTimeoutDraw(t *testing.T) {
 	player1 := NewPlayer("player1")
 	player2 := NewPlayer("player2")
 	matchingServer := NewMatchingServer()
-	go matchingServer.MatchPlayer(player1)
-	go matchingServer.MatchPlayer(player2)
+	go matchingServer.MatchPlayer(player1, Seek{})
+	go matchingServer.MatchPlayer(player2, Seek{})
 	exitChan := make(chan bool, 1)
 	exitChan <- true
 	generator := func(black *Player, white *Player) Match {
@@ -298,7 +306,8 @@ func TestMatchingServerInsufficientMaterialTimeoutDraw(t *testing.T) {
 	black.GetSyncUpdate()
 	response := <-white.ResponseChanAsync
 	if !liveMatch.game.GameOver() || !response.GameOver ||
-		!response.Draw || !response.Timeout {
+		!response.Draw || !response.Timeout ||
+		response.Termination != model.TimeoutVsInsufficientMaterial {
 		t.Error("Expected draw got", response)
 	}
 }
@@ -307,8 +316,8 @@ func TestMatchingServerDraw(t *testing.T) {
 	player1 := NewPlayer("player1")
 	player2 := NewPlayer("player2")
 	matchingServer := NewMatchingServer()
-	go matchingServer.MatchPlayer(player1)
-	go matchingServer.MatchPlayer(player2)
+	go matchingServer.MatchPlayer(player1, Seek{})
+	go matchingServer.MatchPlayer(player2, Seek{})
 	exitChan := make(chan bool, 1)
 	exitChan <- true
 	matchingServer.StartMatchServers(1, exitChan)
@@ -350,7 +359,7 @@ func TestMatchingServerDraw(t *testing.T) {
 	player2.RequestChanAsync <- RequestAsync{RequestToDraw: true}
 	response = <-player2.ResponseChanAsync
 	if !liveMatch.game.GameOver() || !response.GameOver ||
-		!response.Draw {
+		!response.Draw || response.Termination != model.Agreement {
 		t.Error("Expected a draw got ", response.Draw, response.GameOver, liveMatch.game.GameOver())
 	}
 }
@@ -359,8 +368,8 @@ func TestMatchingServerResignation(t *testing.T) {
 	player1 := NewPlayer("player1")
 	player2 := NewPlayer("player2")
 	matchingServer := NewMatchingServer()
-	go matchingServer.MatchPlayer(player1)
-	go matchingServer.MatchPlayer(player2)
+	go matchingServer.MatchPlayer(player1, Seek{})
+	go matchingServer.MatchPlayer(player2, Seek{})
 	exitChan := make(chan bool, 1)
 	exitChan <- true
 	matchingServer.StartMatchServers(1, exitChan)
@@ -373,17 +382,73 @@ func TestMatchingServerResignation(t *testing.T) {
 	player1.RequestChanAsync <- RequestAsync{Resign: true}
 	response := <-player1.ResponseChanAsync
 	if !liveMatch.game.GameOver() || !response.GameOver ||
-		!response.Resignation {
+		!response.Resignation || response.Termination != model.Resignation {
 		t.Error("Expected resignation got ", response)
 	}
 }
 
+func TestMatchingServerAbort(t *testing.T) {
+	player1 := NewPlayer("player1")
+	player2 := NewPlayer("player2")
+	matchingServer := NewMatchingServer()
+	go matchingServer.MatchPlayer(player1, Seek{})
+	go matchingServer.MatchPlayer(player2, Seek{})
+	exitChan := make(chan bool, 1)
+	exitChan <- true
+	matchingServer.StartMatchServers(1, exitChan)
+	tries := 0
+	for len(matchingServer.LiveMatches()) == 0 && tries < 10 {
+		time.Sleep(time.Millisecond)
+		tries++
+	}
+	liveMatch := matchingServer.LiveMatches()[0]
+	liveMatch.white.RequestChanAsync <- RequestAsync{Abandon: true}
+	response := <-liveMatch.black.ResponseChanAsync
+	if !liveMatch.game.GameOver() || !response.GameOver || response.Draw ||
+		response.Winner != "" || response.Termination != model.Aborted {
+		t.Error("Expected aborted game got ", response)
+	}
+}
+
+func TestMatchingServerAbandonment(t *testing.T) {
+	player1 := NewPlayer("player1")
+	player2 := NewPlayer("player2")
+	matchingServer := NewMatchingServer()
+	go matchingServer.MatchPlayer(player1, Seek{})
+	go matchingServer.MatchPlayer(player2, Seek{})
+	exitChan := make(chan bool, 1)
+	exitChan <- true
+	matchingServer.StartMatchServers(1, exitChan)
+	tries := 0
+	for len(matchingServer.LiveMatches()) == 0 && tries < 10 {
+		time.Sleep(time.Millisecond)
+		tries++
+	}
+	liveMatch := matchingServer.LiveMatches()[0]
+	white, black := liveMatch.white, liveMatch.black
+	white.MakeMove(model.MoveRequest{
+		Position: model.Position{File: 4, Rank: 1},
+		Move:     model.Move{X: 0, Y: 2}})
+	black.GetSyncUpdate()
+	black.MakeMove(model.MoveRequest{
+		Position: model.Position{File: 4, Rank: 6},
+		Move:     model.Move{X: 0, Y: -2}})
+	white.GetSyncUpdate()
+	black.RequestChanAsync <- RequestAsync{Abandon: true}
+	response := <-white.ResponseChanAsync
+	if !liveMatch.game.GameOver() || !response.GameOver ||
+		response.Winner != white.name ||
+		response.Termination != model.Abandonment {
+		t.Error("Expected abandoned game got ", response)
+	}
+}
+
 func TestMatchingServerPlayerSecondGame(t *testing.T) {
 	player1 := NewPlayer("player1")
 	player2 := NewPlayer("player2")
 	matchingServer := NewMatchingServer()
-	go matchingServer.MatchPlayer(player1)
-	go matchingServer.MatchPlayer(player2)
+	go matchingServer.MatchPlayer(player1, Seek{})
+	go matchingServer.MatchPlayer(player2, Seek{})
 	exitChan := make(chan bool, 1)
 	go matchingServer.StartMatchServers(1, exitChan)
 	tries := 0
@@ -405,8 +470,10 @@ func TestMatchingServerPlayerSecondGame(t *testing.T) {
 		!response.Resignation || len(matchingServer.LiveMatches()) > 0 {
 		t.Error("Expected resignation got ", response.GameOver)
 	}
-	go matchingServer.MatchPlayer(player1)
-	go matchingServer.MatchPlayer(player2)
+	// The matchmaker would not pair player1 with player2 again right away.
+	player3 := NewPlayer("player3")
+	go matchingServer.MatchPlayer(player1, Seek{})
+	go matchingServer.MatchPlayer(player3, Seek{})
 	for len(matchingServer.LiveMatches()) == 0 && tries < 10 {
 		time.Sleep(time.Millisecond)
 		tries++
@@ -421,12 +488,112 @@ func TestMatchingServerPlayerSecondGame(t *testing.T) {
 	exitChan <- true
 }
 
+func TestMatchingServerClaimDraw(t *testing.T) {
+	player1 := NewPlayer("player1")
+	player2 := NewPlayer("player2")
+	matchingServer := NewMatchingServer()
+	go matchingServer.MatchPlayer(player1, Seek{})
+	go matchingServer.MatchPlayer(player2, Seek{})
+	exitChan := make(chan bool, 1)
+	exitChan <- true
+	matchingServer.StartMatchServers(1, exitChan)
+	tries := 0
+	for len(matchingServer.LiveMatches()) == 0 && tries < 10 {
+		time.Sleep(time.Millisecond)
+		tries++
+	}
+	liveMatch := matchingServer.LiveMatches()[0]
+	white, black := liveMatch.white, liveMatch.black
+	// An ineligible claim is ignored, give it time to be handled before the
+	// position allows one.
+	white.RequestChanAsync <- RequestAsync{ClaimDraw: true}
+	time.Sleep(10 * time.Millisecond)
+	knightShuffle := []struct {
+		player, opponent *Player
+		request          model.MoveRequest
+	}{
+		{white, black, model.MoveRequest{
+			Position: model.Position{File: 6, Rank: 0},
+			Move:     model.Move{X: -1, Y: 2}}},
+		{black, white, model.MoveRequest{
+			Position: model.Position{File: 6, Rank: 7},
+			Move:     model.Move{X: -1, Y: -2}}},
+		{white, black, model.MoveRequest{
+			Position: model.Position{File: 5, Rank: 2},
+			Move:     model.Move{X: 1, Y: -2}}},
+		{black, white, model.MoveRequest{
+			Position: model.Position{File: 5, Rank: 5},
+			Move:     model.Move{X: 1, Y: 2}}},
+	}
+	for i := 0; i < 2; i++ {
+		for _, move := range knightShuffle {
+			if !move.player.MakeMove(move.request) {
+				t.Fatal("Expected a valid move got ", move.request)
+			}
+			move.opponent.GetSyncUpdate()
+		}
+	}
+	if liveMatch.GameOver() {
+		t.Error("Expected threefold repetition to need a claim")
+	}
+	// Only the player to move may claim.
+	black.RequestChanAsync <- RequestAsync{ClaimDraw: true}
+	time.Sleep(10 * time.Millisecond)
+	if liveMatch.GameOver() {
+		t.Error("Expected a claim on the opponent's turn to be ignored")
+	}
+	white.RequestChanAsync <- RequestAsync{ClaimDraw: true}
+	response := <-black.ResponseChanAsync
+	if !liveMatch.GameOver() || !response.GameOver || !response.Draw ||
+		response.Termination != model.ThreefoldRepetition {
+		t.Error("Expected a claimed draw got ", response)
+	}
+}
+
+func TestMatchingServerChess960(t *testing.T) {
+	player1 := NewPlayer("player1")
+	player2 := NewPlayer("player2")
+	matchingServer := NewMatchingServer()
+	go matchingServer.MatchPlayer(player1, Seek{})
+	go matchingServer.MatchPlayer(player2, Seek{})
+	exitChan := make(chan bool, 1)
+	exitChan <- true
+	matchingServer.StartCustomMatchServers(1, Chess960MatchGenerator, exitChan)
+	tries := 0
+	for len(matchingServer.LiveMatches()) == 0 && tries < 10 {
+		time.Sleep(time.Millisecond)
+		tries++
+	}
+	liveMatch := matchingServer.LiveMatches()[0]
+	matchedResponse := player1.MatchedResponse()
+	if !liveMatch.Chess960() || !matchedResponse.Chess960 ||
+		matchedResponse.StartFEN != liveMatch.game.FEN() ||
+		matchedResponse.MaxTimeMs != 1200000 {
+		t.Error("Expected a Chess960 match got ", matchedResponse)
+	}
+}
+
+func TestNewChess960Match(t *testing.T) {
+	match, err := NewChess960Match(
+		NewPlayer("player1"), NewPlayer("player2"), 1000, 518)
+	if err != nil || match.StartFEN() != model.StartingFEN ||
+		!match.Chess960() {
+		t.Error("Expected the standard start position got ", match.StartFEN(),
+			err)
+	}
+	_, err = NewChess960Match(
+		NewPlayer("player1"), NewPlayer("player2"), 1000, 960)
+	if err == nil {
+		t.Error("Expected an error for start position 960")
+	}
+}
+
 func TestMatchingServerValidMoves(t *testing.T) {
 	player1 := NewPlayer("player1")
 	player2 := NewPlayer("player2")
 	matchingServer := NewMatchingServer()
-	go matchingServer.MatchPlayer(player1)
-	go matchingServer.MatchPlayer(player2)
+	go matchingServer.MatchPlayer(player1, Seek{})
+	go matchingServer.MatchPlayer(player2, Seek{})
 	exitChan := make(chan bool, 1)
 	exitChan <- true
 	matchingServer.StartMatchServers(1, exitChan)
@@ -456,8 +623,8 @@ func TestMatchingServerInvalidMoves(t *testing.T) {
 	player1 := NewPlayer("player1")
 	player2 := NewPlayer("player2")
 	matchingServer := NewMatchingServer()
-	go matchingServer.MatchPlayer(player1)
-	go matchingServer.MatchPlayer(player2)
+	go matchingServer.MatchPlayer(player1, Seek{})
+	go matchingServer.MatchPlayer(player2, Seek{})
 	exitChan := make(chan bool, 1)
 	exitChan <- true
 	matchingServer.StartMatchServers(1, exitChan)
@@ -493,8 +660,8 @@ func TestMatchingServerCheckmate(t *testing.T) {
 	player1 := NewPlayer("player1")
 	player2 := NewPlayer("player2")
 	matchingServer := NewMatchingServer()
-	go matchingServer.MatchPlayer(player1)
-	go matchingServer.MatchPlayer(player2)
+	go matchingServer.MatchPlayer(player1, Seek{})
+	go matchingServer.MatchPlayer(player2, Seek{})
 	exitChan := make(chan bool, 1)
 	exitChan <- true
 	matchingServer.StartMatchServers(1, exitChan)
@@ -554,7 +721,8 @@ func TestMatchingServerCheckmate(t *testing.T) {
 		t.Error("Expected gameover got ", liveMatch)
 	}
 	response := <-black.ResponseChanAsync
-	if !response.GameOver || !(response.Winner == white.name) {
+	if !response.GameOver || !(response.Winner == white.name) ||
+		response.Termination != model.Checkmate {
 		t.Error("Expected checkmate got ", response)
 	}
 }
@@ -562,18 +730,256 @@ func TestMatchingServerCheckmate(t *testing.T) {
 func TestMatchingServerEngineTimeout(t *testing.T) {
 	matchingServer := NewMatchingServerWithEngine("localhost:50000",
 		time.Millisecond, time.Millisecond)
-	if matchingServer.botMatchingEnabled {
+	if matchingServer.botMatchingEnabled() {
 		t.Error("Expected bot matching to be disabled due to failed connection",
 			"with engine..")
 	}
 }
 
+func TestMatchingServerBuiltinEngine(t *testing.T) {
+	matchingServer := NewMatchingServerWithEngine("localhost:50000",
+		time.Millisecond, time.Millisecond)
+	matchingServer.SetBuiltinEngine(2, 0)
+	player := NewPlayer("player")
+	go matchingServer.MatchPlayer(player, Seek{})
+	// The matchmaker pairs the player with a bot until told to exit.
+	quit := make(chan bool)
+	t.Cleanup(func() { close(quit) })
+	go matchingServer.StartMatchServers(1, quit)
+	if err := player.WaitForMatchStart(); err != nil {
+		t.Fatal("Expected to be matched with a bot got ", err)
+	}
+	if player.Color() == model.Black && player.GetSyncUpdate() == nil {
+		t.Fatal("Expected the bot to open")
+	}
+	liveMatch := player.GetMatch()
+	if !strings.HasSuffix(liveMatch.PlayerName(1-player.Color()), "bot") {
+		t.Error("Expected a bot opponent got ",
+			liveMatch.PlayerName(1-player.Color()))
+	}
+	if !player.MakeMove(liveMatch.game.LegalMoves()[0]) {
+		t.Error("Expected a successful move")
+	}
+	if player.GetSyncUpdate() == nil {
+		t.Error("Expected the bot to reply")
+	}
+	player.RequestAsync(RequestAsync{Resign: true})
+	response := <-player.ResponseChanAsync
+	if !response.GameOver || response.Termination != model.Resignation {
+		t.Error("Expected the bot to win by resignation got ", response)
+	}
+}
+
+func TestBuiltinMoveTime(t *testing.T) {
+	for _, test := range []struct {
+		clock    Clock
+		expected time.Duration
+	}{
+		{Clock{RemainingMs: 60000}, 2 * time.Second},
+		{Clock{RemainingMs: 60000, IncrementMs: 1000}, 3 * time.Second},
+		{Clock{RemainingMs: 1000, IncrementMs: 2000}, 500 * time.Millisecond},
+		{Clock{RemainingMs: 60000, MovesToGo: 10}, 6 * time.Second},
+		{Clock{}, time.Millisecond},
+	} {
+		if moveTime := builtinMoveTime(test.clock); moveTime != test.expected {
+			t.Error("Expected ", test.expected, " for ", test.clock, " got ",
+				moveTime)
+		}
+	}
+}
+
+func TestBuiltinEngineStopsWithContext(t *testing.T) {
+	matchingServer := NewMatchingServer()
+	bot := matchingServer.newBuiltinEngine()
+	bot.start(engineStart{color: model.White,
+		level: BotLevel{Depth: 64, MoveTime: time.Minute}})
+	clock := Clock{RemainingMs: 600000}
+	ctx, cancel := context.WithTimeout(context.Background(),
+		10*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := bot.bestMove(ctx, model.NewGame(), engineClock{clock, clock})
+	if elapsed := time.Since(start); err == nil || elapsed > time.Second {
+		t.Error("Expected the search to stop with the match got ", elapsed,
+			err)
+	}
+}
+
+func TestMatchingServerBotLevel(t *testing.T) {
+	matchingServer := NewMatchingServerWithEngine("localhost:50000",
+		time.Minute, time.Millisecond)
+	matchingServer.SetBuiltinEngine(2, 0)
+	exitChan := make(chan bool, 1)
+	exitChan <- true
+	matchingServer.StartMatchServers(1, exitChan)
+	player := NewPlayer("player")
+	err := matchingServer.MatchPlayerWithBot(player, "Grandmaster", Seek{})
+	if err == nil {
+		t.Error("Expected an unknown bot level error")
+	}
+	err = matchingServer.MatchPlayerWithBot(player, "Casual", Seek{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := player.WaitForMatchStart(); err != nil {
+		t.Fatal("Expected to be matched with the bot got ", err)
+	}
+	liveMatch := player.GetMatch()
+	if liveMatch.PlayerName(1-player.Color()) != "cherrybot" {
+		t.Error("Expected the casual bot got ",
+			liveMatch.PlayerName(1-player.Color()))
+	}
+	player.RequestAsync(RequestAsync{Resign: true})
+	<-player.ResponseChanAsync
+	noBots := NewMatchingServer()
+	if err := noBots.MatchPlayerWithBot(player, "casual",
+		Seek{}); err == nil {
+		t.Error("Expected an error without bot matching")
+	}
+}
+
+func TestMatchingServerBotMatchServers(t *testing.T) {
+	matchingServer := NewMatchingServerWithEngine("localhost:50000",
+		time.Minute, time.Millisecond)
+	matchingServer.SetBuiltinEngine(1, 0)
+	exitChan := make(chan bool, 1)
+	exitChan <- true
+	matchingServer.StartMatchServers(1, exitChan)
+	player1, player2 := NewPlayer("player1"), NewPlayer("player2")
+	matchingServer.MatchPlayerWithBot(player1, "beginner", Seek{})
+	if err := player1.WaitForMatchStart(); err != nil {
+		t.Fatal("Expected to be matched with the bot got ", err)
+	}
+	matchingServer.MatchPlayerWithBot(player2, "beginner", Seek{})
+	ctx, cancel := context.WithTimeout(context.Background(),
+		10*time.Millisecond)
+	defer cancel()
+	if player2.HasMatchStarted(ctx) {
+		t.Error("Expected player2 to wait for the busy match server")
+	}
+	if !matchingServer.CancelSeek(player2) {
+		t.Error("Expected player2's bot match to be cancellable")
+	}
+	player1.RequestAsync(RequestAsync{Resign: true})
+	<-player1.ResponseChanAsync
+}
+
+func TestBotLevelFromName(t *testing.T) {
+	for _, level := range BotLevels {
+		found, err := BotLevelFromName(level.Name)
+		if err != nil || found != level {
+			t.Error("Expected ", level, " got ", found, err)
+		}
+	}
+	if _, err := BotLevelFromName(""); err == nil {
+		t.Error("Expected an error for an empty level name")
+	}
+}
+
+// buildFakeUCI build the fake UCI engine in testdata, which logs the commands
+// it reads to the returned log path
+func buildFakeUCI(t *testing.T) (path string, logPath string) {
+	dir := t.TempDir()
+	path = filepath.Join(dir, "fakeuci")
+	build := exec.Command("go", "build", "-o", path, "./testdata/fakeuci")
+	if out, err := build.CombinedOutput(); err != nil {
+		t.Fatal("Failed to build the fake UCI engine ", string(out), err)
+	}
+	logPath = filepath.Join(dir, "commands.log")
+	os.Setenv("FAKEUCI_LOG", logPath)
+	return path, logPath
+}
+
+func TestUCIEngine(t *testing.T) {
+	path, logPath := buildFakeUCI(t)
+	level, _ := BotLevelFromName("casual")
+	level.BlunderRate = 0
+	engine := newUCIEngine(path)
+	clock := engineClock{
+		white: Clock{RemainingMs: 60000}, black: Clock{RemainingMs: 59000},
+	}
+	err := engine.start(engineStart{color: model.White, clock: clock,
+		startFEN: model.StartingFEN, variant: model.Standard, level: level})
+	if err != nil {
+		t.Fatal(err)
+	}
+	game := model.NewGame()
+	move, err := engine.bestMove(context.Background(), game, clock)
+	if err != nil || move.UCI() != game.LegalMoves()[0].UCI() {
+		t.Error("Expected the fake engine's first legal move got ", move, err)
+	}
+	game.Move(move)
+	game.MoveUCI("e7e5")
+	move, err = engine.bestMove(context.Background(), game, engineClock{
+		white: Clock{RemainingMs: 58000, IncrementMs: 2000, MovesToGo: 39},
+		black: Clock{RemainingMs: 57000, IncrementMs: 2000, MovesToGo: 39},
+	})
+	if err != nil || game.Move(move) != nil {
+		t.Error("Expected a legal second move got ", move, err)
+	}
+	engine.gameOver(game.Result())
+	commands, _ := ioutil.ReadFile(logPath)
+	for _, expected := range []string{
+		"setoption name UCI_LimitStrength value true",
+		"setoption name UCI_Elo value 1200",
+		"go wtime 60000 btime 59000 winc 0 binc 0 depth 2 movetime 250",
+		"go wtime 58000 btime 57000 winc 2000 binc 2000 movestogo 39 " +
+			"depth 2 movetime 250",
+		"position fen " + model.StartingFEN + " moves " +
+			game.MoveHistory()[0].UCI() + " e7e5",
+		"quit",
+	} {
+		if !strings.Contains(string(commands), expected+"\n") {
+			t.Error("Expected the engine to be sent ", expected, " got ",
+				string(commands))
+		}
+	}
+}
+
+func TestUCIEngineUnsupportedVariant(t *testing.T) {
+	path, _ := buildFakeUCI(t)
+	engine := newUCIEngine(path)
+	err := engine.start(engineStart{color: model.White,
+		startFEN: model.Atomic.StartFEN(), variant: model.Atomic})
+	if err == nil {
+		t.Error("Expected the fake engine not to support Atomic")
+	}
+}
+
+func TestMatchingServerUCIEngine(t *testing.T) {
+	path, _ := buildFakeUCI(t)
+	matchingServer := NewMatchingServerWithUCIEngine(path, time.Minute)
+	exitChan := make(chan bool, 1)
+	exitChan <- true
+	matchingServer.StartMatchServers(1, exitChan)
+	player := NewPlayer("player")
+	err := matchingServer.MatchPlayerWithBot(player, "expert", Seek{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := player.WaitForMatchStart(); err != nil {
+		t.Fatal("Expected to be matched with the bot got ", err)
+	}
+	if player.Color() == model.Black && player.GetSyncUpdate() == nil {
+		t.Fatal("Expected the UCI engine to open")
+	}
+	liveMatch := player.GetMatch()
+	if !player.MakeMove(liveMatch.game.LegalMoves()[0]) {
+		t.Error("Expected a successful move")
+	}
+	if player.GetSyncUpdate() == nil {
+		t.Error("Expected the UCI engine to reply")
+	}
+	player.RequestAsync(RequestAsync{Resign: true})
+	<-player.ResponseChanAsync
+}
+
 func TestMatchingServerMultiple(t *testing.T) {
 	matchingServer := NewMatchingServer()
 	players := []*Player{}
 	for i := 0; i < 7; i++ {
 		players = append(players, NewPlayer("player"+strconv.Itoa(i)))
-		go matchingServer.MatchPlayer(players[i])
+		go matchingServer.MatchPlayer(players[i], Seek{})
 	}
 	exitChan := make(chan bool, 1)
 	exitChan <- true
@@ -588,3 +994,157 @@ func TestMatchingServerMultiple(t *testing.T) {
 			len(matchingServer.LiveMatches()))
 	}
 }
+
+func TestMatchingServerVariant(t *testing.T) {
+	player1 := NewPlayer("player1")
+	player2 := NewPlayer("player2")
+	matchingServer := NewMatchingServer()
+	go matchingServer.MatchPlayer(player1, Seek{})
+	go matchingServer.MatchPlayer(player2, Seek{})
+	exitChan := make(chan bool, 1)
+	exitChan <- true
+	generator := CreateCustomVariantMatchGenerator(60, model.KingOfTheHill)
+	matchingServer.StartCustomMatchServers(1, generator, exitChan)
+	tries := 0
+	for len(matchingServer.LiveMatches()) == 0 && tries < 10 {
+		time.Sleep(time.Millisecond)
+		tries++
+	}
+	liveMatch := matchingServer.LiveMatches()[0]
+	matchedResponse := player2.MatchedResponse()
+	if liveMatch.Variant() != model.KingOfTheHill ||
+		matchedResponse.Variant != "King of the Hill" ||
+		matchedResponse.MaxTimeMs != 60000 {
+		t.Error("Expected a King of the Hill match got ", matchedResponse)
+	}
+}
+
+func TestMatchingServerBughouse(t *testing.T) {
+	matchingServer := NewMatchingServer()
+	for i := 0; i < 4; i++ {
+		go matchingServer.MatchPlayer(NewPlayer("player"+strconv.Itoa(i)), Seek{})
+	}
+	exitChan := make(chan bool, 1)
+	exitChan <- true
+	matchingServer.StartCustomMatchServers(2, CreateBughouseMatchGenerator(60),
+		exitChan)
+	tries := 0
+	for len(matchingServer.LiveMatches()) != 2 && tries < 50 {
+		time.Sleep(time.Millisecond)
+		tries++
+	}
+	if len(matchingServer.LiveMatches()) != 2 {
+		t.Fatal("Expected two linked boards got ",
+			len(matchingServer.LiveMatches()))
+	}
+	boards := [2]*Match{}
+	for _, liveMatch := range matchingServer.LiveMatches() {
+		boards[liveMatch.board] = liveMatch
+	}
+	if boards[0] == nil || boards[1] == nil ||
+		boards[0].bughouse != boards[1].bughouse ||
+		boards[1].Variant() != model.Bughouse {
+		t.Fatal("Expected two boards of one Bughouse game")
+	}
+	// Moves are only taken once both boards have started.
+	for _, board := range boards {
+		if board.white.WaitForMatchStart() != nil ||
+			board.black.WaitForMatchStart() != nil {
+			t.Fatal("Expected both boards to start")
+		}
+	}
+	for _, san := range []string{"e4", "d5", "exd5"} {
+		moveRequest, _ := boards[0].game.ParseSAN(san)
+		player := boards[0].black
+		if boards[0].game.Turn() == model.White {
+			player = boards[0].white
+		}
+		if !player.MakeMove(moveRequest) {
+			t.Fatal("Expected a valid move got ", san)
+		}
+	}
+	partner := boards[1].black
+	response := <-partner.ResponseChanAsync
+	if response.Pockets[model.Black][model.Pawn] != 1 ||
+		boards[1].game.Pocket(model.Black)[model.Pawn] != 1 {
+		t.Error("Expected the captured pawn passed to the partner got ",
+			response)
+	}
+	boards[0].black.RequestAsync(RequestAsync{Resign: true})
+	<-boards[1].white.ResponseChanAsync
+	response = <-boards[1].white.ResponseChanAsync
+	if !response.GameOver || response.Winner != partner.Name() ||
+		response.Termination != model.Resignation {
+		t.Error("Expected the partner board to end with the team's result got ",
+			response)
+	}
+}
+
+func TestMatchingServerBughouseBoardsStartTogether(t *testing.T) {
+	matchingServer := NewMatchingServer()
+	matchingServer.liveMatchesMetric = prometheus.NewGauge(
+		prometheus.GaugeOpts{Name: "live_matches"})
+	matchingServer.matchGenerator = CreateBughouseMatchGenerator(60)
+	players := []*Player{}
+	for i := 0; i < 4; i++ {
+		players = append(players, NewPlayer("player"+strconv.Itoa(i)))
+	}
+	go matchingServer.playMatch(Seek{}, players[0], players[1])
+	ctx, cancel := context.WithTimeout(context.Background(),
+		10*time.Millisecond)
+	defer cancel()
+	if players[0].HasMatchStarted(ctx) {
+		t.Error("Expected the first board to wait for the second")
+	}
+	go matchingServer.playMatch(Seek{}, players[2], players[3])
+	for _, player := range players {
+		if err := player.WaitForMatchStart(); err != nil {
+			t.Error("Expected both boards to start got ", err)
+		}
+	}
+	if len(players[2].GetMatch().game.Pocket(model.White)) != 0 {
+		t.Error("Expected the second board to start with empty pockets")
+	}
+}
+
+func TestMatchingServerBughouseAbort(t *testing.T) {
+	matchingServer := NewMatchingServer()
+	matchingServer.liveMatchesMetric = prometheus.NewGauge(
+		prometheus.GaugeOpts{Name: "live_matches"})
+	matchingServer.matchGenerator = CreateBughouseMatchGenerator(60)
+	players := []*Player{}
+	for i := 0; i < 6; i++ {
+		players = append(players, NewPlayer("player"+strconv.Itoa(i)))
+	}
+	go matchingServer.playMatch(Seek{}, players[0], players[1])
+	players[0].RequestAsync(RequestAsync{Abandon: true})
+	if err := players[1].WaitForMatchStart(); err != nil {
+		t.Fatal("Expected the first board to start to be aborted got ", err)
+	}
+	response := <-players[1].ResponseChanAsync
+	if !response.GameOver || response.Termination != model.Aborted {
+		t.Error("Expected the waiting board to be aborted got ", response)
+	}
+	// The next players start a new game rather than join the aborted one.
+	go matchingServer.playMatch(Seek{}, players[2], players[3])
+	ctx, cancel := context.WithTimeout(context.Background(),
+		10*time.Millisecond)
+	defer cancel()
+	if players[2].HasMatchStarted(ctx) {
+		t.Error("Expected a new first board to wait for the second")
+	}
+	go matchingServer.playMatch(Seek{}, players[4], players[5])
+	for _, player := range players[2:] {
+		if err := player.WaitForMatchStart(); err != nil {
+			t.Error("Expected both boards to start got ", err)
+		}
+	}
+	// A board left waiting too long is aborted.
+	link := newBughouseLink()
+	link.startTimeout = time.Millisecond
+	match := link.newMatch(0, NewPlayer("player6"), NewPlayer("player7"),
+		NewTimeControl(60000, 0))
+	if link.waitForBoards(&match) || !link.ended() || link.join() {
+		t.Error("Expected the unmatched game to be aborted")
+	}
+}
@@ -0,0 +1,301 @@
+package matchserver
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/Ekotlikoff/gochess/internal/model"
+)
+
+const (
+	// BulletPool is the pool of standard games expected to last under three
+	// minutes a side
+	BulletPool = "bullet"
+	// BlitzPool is the pool of standard games expected to last under eight
+	// minutes a side
+	BlitzPool = "blitz"
+	// RapidPool is the pool of standard games expected to last under 25
+	// minutes a side
+	RapidPool = "rapid"
+	// ClassicalPool is the pool of longer standard games
+	ClassicalPool = "classical"
+	// CustomPool is the pool of other variants, time controls with stages or
+	// delays, and the server's own games
+	CustomPool = "custom"
+)
+
+// Pools are the matching pools, each with its own queue
+var Pools = []string{BulletPool, BlitzPool, RapidPool, ClassicalPool,
+	CustomPool}
+
+// defaultTimeControl is the time control of games sought with a variant but
+// no time control
+var defaultTimeControl = NewTimeControl(1200000, 0)
+
+type (
+	// Seek is the game a player asks to be matched for. A seek with neither
+	// a time control nor a variant is for the server's own games, made by
+	// its match generator. Rated games move the players' ratings in the
+	// seek's pool, casual games do not.
+	Seek struct {
+		TimeControl TimeControl
+		Variant     model.Variant
+		Rated       bool
+	}
+
+	// matchingPool is the queue of players seeking the pool's games, in the
+	// order they joined it, whom the matchmaker pairs
+	matchingPool struct {
+		name    string
+		mutex   sync.Mutex
+		seeking []*seekingPlayer
+	}
+
+	// seekingPlayer is a player waiting in a pool for their seek since the
+	// time they joined it, at their rating in the pool, or for a bot of the
+	// level if it is set
+	seekingPlayer struct {
+		player   *Player
+		seek     Seek
+		rating   float64
+		since    time.Time
+		botLevel *BotLevel
+	}
+
+	// pairing is two players matched for their seek, or a player matched
+	// with a bot of the level, handed to a match server to play their match
+	pairing struct {
+		seek             Seek
+		player1, player2 *Player
+		botLevel         BotLevel
+	}
+)
+
+// NewSeek create a seek from a time control in the notation ParseTimeControl
+// parses and a variant's name, either of which may be empty. Bughouse games
+// pair two matches so only the server's own games may be Bughouse games.
+func NewSeek(timeControl string, variant string) (Seek, error) {
+	seek := Seek{}
+	var err error
+	if timeControl != "" {
+		if seek.TimeControl, err = ParseTimeControl(timeControl); err != nil {
+			return Seek{}, err
+		}
+	}
+	if variant != "" {
+		if seek.Variant, err = model.VariantFromName(variant); err != nil {
+			return Seek{}, err
+		} else if seek.Variant == model.Bughouse {
+			return Seek{}, errors.New("Bughouse games cannot be sought")
+		}
+	}
+	return seek, nil
+}
+
+// isDefault get whether the seek is for the server's own games
+func (seek Seek) isDefault() bool {
+	return len(seek.TimeControl.Stages) == 0 && seek.Variant == nil
+}
+
+// String get the seek's time control and variant, and whether it is rated
+func (seek Seek) String() string {
+	s := "default"
+	if !seek.isDefault() {
+		timeControl, variant := seek.TimeControl, seek.Variant
+		if len(timeControl.Stages) == 0 {
+			timeControl = defaultTimeControl
+		}
+		if variant == nil {
+			variant = model.Standard
+		}
+		s = timeControl.String() + " " + variant.Name()
+	}
+	if seek.Rated {
+		s += " rated"
+	}
+	return s
+}
+
+// Pool get the name of the pool the seek queues in. Standard games with a
+// single stage time control are pooled by their expected length, the base
+// time and forty increments.
+func (seek Seek) Pool() string {
+	if seek.isDefault() ||
+		(seek.Variant != nil && seek.Variant != model.Standard) {
+		return CustomPool
+	}
+	timeControl := seek.TimeControl
+	if len(timeControl.Stages) == 0 {
+		timeControl = defaultTimeControl
+	}
+	stage := timeControl.Stages[0]
+	if len(timeControl.Stages) > 1 || stage.Moves > 0 || stage.DelayMs > 0 {
+		return CustomPool
+	}
+	expected := time.Duration(stage.BaseMs+40*stage.IncrementMs) *
+		time.Millisecond
+	switch {
+	case expected < 3*time.Minute:
+		return BulletPool
+	case expected < 8*time.Minute:
+		return BlitzPool
+	case expected < 25*time.Minute:
+		return RapidPool
+	}
+	return ClassicalPool
+}
+
+// generator get the generator of the seek's matches, the default generator
+// for the server's own games
+func (seek Seek) generator(defaultGenerator MatchGenerator) MatchGenerator {
+	if seek.isDefault() {
+		return defaultGenerator
+	}
+	timeControl, variant := seek.TimeControl, seek.Variant
+	if len(timeControl.Stages) == 0 {
+		timeControl = defaultTimeControl
+	}
+	if variant == nil {
+		variant = model.Standard
+	}
+	return CreateTimeControlMatchGenerator(timeControl, variant)
+}
+
+// newMatchingPools create an empty queue for each pool
+func newMatchingPools() map[string]*matchingPool {
+	pools := map[string]*matchingPool{}
+	for _, name := range Pools {
+		pools[name] = &matchingPool{name: name}
+	}
+	return pools
+}
+
+// queue the player in their seek's pool at their rating in it, for a bot of
+// the level if it is set, and pair whoever the matchmaker can right away
+func (matchingServer *MatchingServer) queue(
+	player *Player, seek Seek, botLevel *BotLevel,
+) {
+	pool := matchingServer.pools[seek.Pool()]
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+	now := time.Now()
+	pool.seeking = append(pool.seeking, &seekingPlayer{
+		player: player, seek: seek, since: now, botLevel: botLevel,
+		rating: matchingServer.ratings.get(pool.name, player.name).Rating,
+	})
+	matchingServer.matchingQueueLengthMetric.WithLabelValues(pool.name).Inc()
+	matchingServer.matchmakePool(pool, now)
+}
+
+// matchmake pair the pools' players every matchmaking interval, as their
+// rating windows widen and the max matching duration passes, until done is
+// closed
+func (matchingServer *MatchingServer) matchmake(done chan struct{}) {
+	ticker := time.NewTicker(matchmakingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			matchingServer.matchmakePools(now)
+		case <-done:
+			return
+		}
+	}
+}
+
+// matchmakePools pair each pool's players
+func (matchingServer *MatchingServer) matchmakePools(now time.Time) {
+	for _, name := range Pools {
+		pool := matchingServer.pools[name]
+		pool.mutex.Lock()
+		matchingServer.matchmakePool(pool, now)
+		pool.mutex.Unlock()
+	}
+}
+
+// matchmakePool hand the pool's pairings to idle match servers, pairing
+// players who have waited the max matching duration with bots of a random
+// level if bots are available. Players stay in the pool until a match
+// server takes their pairing. The pool must be locked.
+func (matchingServer *MatchingServer) matchmakePool(
+	pool *matchingPool, now time.Time,
+) {
+	botWait := time.Duration(-1)
+	if matchingServer.botsAvailable() {
+		botWait = matchingServer.maxMatchingDuration
+	}
+	pairs, bots, _ := matchingServer.matchmakingPolicy.matchmake(
+		pool.seeking, now, botWait)
+	handedOver := map[*seekingPlayer]bool{}
+	for _, pair := range pairs {
+		if !matchingServer.handOver(pairing{
+			seek: pair[0].seek, player1: pair[0].player,
+			player2: pair[1].player,
+		}) {
+			break
+		}
+		handedOver[pair[0]], handedOver[pair[1]] = true, true
+		pair[0].player.setLastOpponent(pair[1].player)
+		pair[1].player.setLastOpponent(pair[0].player)
+	}
+	for _, seeking := range bots {
+		level := randomBotLevel()
+		if seeking.botLevel != nil {
+			level = *seeking.botLevel
+		}
+		// Games against bots are never rated.
+		seek := seeking.seek
+		seek.Rated = false
+		if !matchingServer.handOver(pairing{
+			seek: seek, player1: seeking.player, botLevel: level,
+		}) {
+			break
+		}
+		handedOver[seeking] = true
+	}
+	waiting := []*seekingPlayer{}
+	for _, seeking := range pool.seeking {
+		if !handedOver[seeking] {
+			waiting = append(waiting, seeking)
+		}
+	}
+	pool.seeking = waiting
+	matchingServer.matchingQueueLengthMetric.WithLabelValues(pool.name).
+		Sub(float64(len(handedOver)))
+}
+
+// handOver hand the pairing to an idle match server, and get whether there
+// was one
+func (matchingServer *MatchingServer) handOver(pairing pairing) bool {
+	matchingServer.mutex.Lock()
+	if matchingServer.idleMatchServers == 0 {
+		matchingServer.mutex.Unlock()
+		return false
+	}
+	matchingServer.idleMatchServers--
+	matchingServer.mutex.Unlock()
+	// The idle match server is on its way to receive the pairing.
+	matchingServer.pairings <- pairing
+	return true
+}
+
+// CancelSeek take the player out of the pool they are waiting in, and get
+// whether they were waiting, which they no longer are once paired
+func (matchingServer *MatchingServer) CancelSeek(player *Player) bool {
+	for _, name := range Pools {
+		pool := matchingServer.pools[name]
+		pool.mutex.Lock()
+		for i, seeking := range pool.seeking {
+			if seeking.player == player {
+				pool.seeking = append(pool.seeking[:i], pool.seeking[i+1:]...)
+				matchingServer.matchingQueueLengthMetric.
+					WithLabelValues(pool.name).Dec()
+				pool.mutex.Unlock()
+				return true
+			}
+		}
+		pool.mutex.Unlock()
+	}
+	return false
+}
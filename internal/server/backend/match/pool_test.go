@@ -0,0 +1,130 @@
+package matchserver
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/Ekotlikoff/gochess/internal/model"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestNewSeek(t *testing.T) {
+	seek, err := NewSeek("180+2", "atomic")
+	if err != nil || seek.TimeControl.String() != "180+2" ||
+		seek.Variant != model.Atomic {
+		t.Error("Expected a 180+2 Atomic seek got ", seek, err)
+	}
+	if seek, err := NewSeek("", ""); err != nil || !seek.isDefault() {
+		t.Error("Expected the default seek got ", seek, err)
+	}
+	for _, args := range [][2]string{
+		{"x", ""}, {"", "Shogi"}, {"300", "Bughouse"},
+	} {
+		if _, err := NewSeek(args[0], args[1]); err == nil {
+			t.Error("Expected an error seeking ", args)
+		}
+	}
+}
+
+func TestSeekPool(t *testing.T) {
+	for s, expected := range map[[2]string]string{
+		{"60", ""}:              BulletPool,
+		{"120+1", ""}:           BulletPool,
+		{"180+2", "Standard"}:   BlitzPool,
+		{"300", ""}:             BlitzPool,
+		{"600+5", ""}:           RapidPool,
+		{"", "Standard"}:        RapidPool,
+		{"1500", ""}:            ClassicalPool,
+		{"40/5400+30:1800", ""}: CustomPool,
+		{"300d5", ""}:           CustomPool,
+		{"180+2", "Horde"}:      CustomPool,
+		{"", ""}:                CustomPool,
+	} {
+		seek, _ := NewSeek(s[0], s[1])
+		if seek.Pool() != expected {
+			t.Error("Expected ", s, " in the ", expected, " pool got ",
+				seek.Pool())
+		}
+	}
+}
+
+func TestMatchingServerPools(t *testing.T) {
+	matchingServer := NewMatchingServer()
+	blitz, _ := NewSeek("180+2", "")
+	otherBlitz, _ := NewSeek("300", "")
+	rapid, _ := NewSeek("600", "")
+	players := []*Player{}
+	for i, seek := range []Seek{blitz, otherBlitz, rapid} {
+		player := NewPlayer("player" + strconv.Itoa(i+1))
+		players = append(players, player)
+		matchingServer.MatchPlayer(player, seek)
+	}
+	for pool, expected := range map[string]float64{
+		BulletPool: 0, BlitzPool: 2, RapidPool: 1, CustomPool: 0,
+	} {
+		queueLength := testutil.ToFloat64(
+			matchingServer.matchingQueueLengthMetric.WithLabelValues(pool))
+		if queueLength != expected {
+			t.Error("Expected ", expected, " players in the ", pool,
+				" pool got ", queueLength)
+		}
+	}
+	player4 := NewPlayer("player4")
+	matchingServer.MatchPlayer(player4, blitz)
+	exitChan := make(chan bool, 1)
+	exitChan <- true
+	matchingServer.StartMatchServers(1, exitChan)
+	if err := player4.WaitForMatchStart(); err != nil {
+		t.Fatal("Expected a match got ", err)
+	}
+	opponent := player4.GetMatch().PlayerName(1 - player4.Color())
+	matchedResponse := player4.MatchedResponse()
+	if opponent != "player1" ||
+		matchedResponse.TimeControl.String() != "180+2" ||
+		matchedResponse.Variant != "Standard" {
+		t.Error("Expected a 180+2 match with player1 got ", opponent,
+			matchedResponse)
+	}
+	if queueLength := testutil.ToFloat64(matchingServer.
+		matchingQueueLengthMetric.WithLabelValues(BlitzPool)); queueLength != 1 {
+		t.Error("Expected player2 left in the blitz pool got ", queueLength)
+	}
+	player4.RequestAsync(RequestAsync{Resign: true})
+	<-players[0].ResponseChanAsync
+}
+
+func TestMatchingServerCancelSeek(t *testing.T) {
+	matchingServer := NewMatchingServer()
+	player1 := NewPlayer("player1")
+	if matchingServer.CancelSeek(player1) {
+		t.Error("Expected no seek to cancel")
+	}
+	rapid, _ := NewSeek("600", "")
+	matchingServer.MatchPlayer(player1, rapid)
+	if !matchingServer.CancelSeek(player1) || matchingServer.CancelSeek(player1) {
+		t.Error("Expected the seek to be cancelled once")
+	}
+	player2 := NewPlayer("player2")
+	matchingServer.MatchPlayer(player2, rapid)
+	queueLength := testutil.ToFloat64(
+		matchingServer.matchingQueueLengthMetric.WithLabelValues(RapidPool))
+	if queueLength != 1 || len(matchingServer.pools[RapidPool].seeking) != 1 {
+		t.Error("Expected player2 alone in the rapid pool got ", queueLength)
+	}
+	// Paired players wait in the pool until a match server is idle.
+	player3 := NewPlayer("player3")
+	matchingServer.MatchPlayer(player3, rapid)
+	if !matchingServer.CancelSeek(player3) {
+		t.Error("Expected player3 to wait for a match server")
+	}
+	matchingServer.MatchPlayer(player3, rapid)
+	exitChan := make(chan bool, 1)
+	exitChan <- true
+	matchingServer.StartMatchServers(1, exitChan)
+	if err := player3.WaitForMatchStart(); err != nil ||
+		player3.GetMatch().PlayerName(1-player3.Color()) != "player2" {
+		t.Error("Expected player2 and player3 matched got ", err)
+	}
+	player3.RequestAsync(RequestAsync{Resign: true})
+	<-player2.ResponseChanAsync
+}
@@ -0,0 +1,172 @@
+package matchserver
+
+import (
+	"math"
+	"sync"
+)
+
+const (
+	// glickoScale converts ratings to and from the Glicko-2 scale
+	glickoScale = 173.7178
+	// ratingTau constrains how quickly a player's volatility changes
+	ratingTau = 0.5
+	// ratingEpsilon is the tolerance of the volatility's iteration
+	ratingEpsilon = 0.000001
+	// maxDeviation is the deviation of an unrated player
+	maxDeviation = 350
+)
+
+// defaultRating is the rating of a player before their first rated game in
+// a pool
+var defaultRating = Rating{Rating: 1500, Deviation: maxDeviation,
+	Volatility: 0.06}
+
+type (
+	// Rating is a player's Glicko-2 rating in a pool, with its deviation and
+	// volatility, and the number of rated games it was made from
+	Rating struct {
+		Rating     float64
+		Deviation  float64
+		Volatility float64
+		Games      int
+	}
+
+	// RatingDeltas are how much a player's rating would move if they won,
+	// drew or lost a rated game
+	RatingDeltas struct {
+		Win, Draw, Loss float64
+	}
+
+	// ratingResult is a game's result against an opponent, scored 1 for a
+	// win, 0.5 for a draw and 0 for a loss
+	ratingResult struct {
+		opponent Rating
+		score    float64
+	}
+
+	// ratingStore is the ratings of each pool's players, by name
+	ratingStore struct {
+		mutex   sync.Mutex
+		ratings map[string]map[string]Rating
+	}
+)
+
+// update get the rating after a rating period with the results, or with
+// none, after a period the player sat out
+func (rating Rating) update(results []ratingResult) Rating {
+	mu := (rating.Rating - 1500) / glickoScale
+	phi := rating.Deviation / glickoScale
+	var vInverse, improvement float64
+	for _, result := range results {
+		g := glickoG(result.opponent.Deviation / glickoScale)
+		expected := 1 / (1 + math.Exp(-g*(mu-
+			(result.opponent.Rating-1500)/glickoScale)))
+		vInverse += g * g * expected * (1 - expected)
+		improvement += g * (result.score - expected)
+	}
+	volatility := rating.Volatility
+	if len(results) > 0 {
+		v := 1 / vInverse
+		volatility = newVolatility(rating.Volatility, phi, v, v*improvement)
+	}
+	phi = math.Sqrt(phi*phi + volatility*volatility)
+	if len(results) > 0 {
+		phi = 1 / math.Sqrt(1/(phi*phi)+vInverse)
+		mu += phi * phi * improvement
+	}
+	return Rating{
+		Rating:     mu*glickoScale + 1500,
+		Deviation:  math.Min(phi*glickoScale, maxDeviation),
+		Volatility: volatility,
+		Games:      rating.Games + len(results),
+	}
+}
+
+// deltas get how much the rating would move after a game against the
+// opponent
+func (rating Rating) deltas(opponent Rating) RatingDeltas {
+	delta := func(score float64) float64 {
+		return rating.update([]ratingResult{{opponent, score}}).Rating -
+			rating.Rating
+	}
+	return RatingDeltas{Win: delta(1), Draw: delta(0.5), Loss: delta(0)}
+}
+
+func glickoG(phi float64) float64 {
+	return 1 / math.Sqrt(1+3*phi*phi/(math.Pi*math.Pi))
+}
+
+// newVolatility find the volatility after a period with the variance and
+// estimated improvement, by the Illinois algorithm as in Glickman's paper
+func newVolatility(volatility, phi, v, delta float64) float64 {
+	a := math.Log(volatility * volatility)
+	f := func(x float64) float64 {
+		d := phi*phi + v + math.Exp(x)
+		return math.Exp(x)*(delta*delta-d)/(2*d*d) -
+			(x-a)/(ratingTau*ratingTau)
+	}
+	lower, upper := a, 0.0
+	if delta*delta > phi*phi+v {
+		upper = math.Log(delta*delta - phi*phi - v)
+	} else {
+		k := 1.0
+		for f(a-k*ratingTau) < 0 {
+			k++
+		}
+		upper = a - k*ratingTau
+	}
+	fLower, fUpper := f(lower), f(upper)
+	for math.Abs(upper-lower) > ratingEpsilon {
+		c := lower + (lower-upper)*fLower/(fUpper-fLower)
+		fC := f(c)
+		if fC*fUpper <= 0 {
+			lower, fLower = upper, fUpper
+		} else {
+			fLower /= 2
+		}
+		upper, fUpper = c, fC
+	}
+	return math.Exp(lower / 2)
+}
+
+func newRatingStore() *ratingStore {
+	return &ratingStore{ratings: map[string]map[string]Rating{}}
+}
+
+// get the player's rating in the pool
+func (store *ratingStore) get(pool string, name string) Rating {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	if rating, ok := store.ratings[pool][name]; ok {
+		return rating
+	}
+	return defaultRating
+}
+
+// record the result of a rated game between the players in the pool, each
+// game its own rating period, and get their ratings before and after it
+// indexed by color
+func (store *ratingStore) record(
+	pool string, names [2]string, whiteScore float64,
+) (before [2]Rating, after [2]Rating) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	if store.ratings[pool] == nil {
+		store.ratings[pool] = map[string]Rating{}
+	}
+	for color, name := range names {
+		before[color] = defaultRating
+		if rating, ok := store.ratings[pool][name]; ok {
+			before[color] = rating
+		}
+	}
+	// Colors index black then white.
+	scores := [2]float64{1 - whiteScore, whiteScore}
+	for color := range names {
+		after[color] = before[color].update([]ratingResult{
+			{opponent: before[1-color], score: scores[color]},
+		})
+		store.ratings[pool][names[color]] = after[color]
+	}
+	return before, after
+}
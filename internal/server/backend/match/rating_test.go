@@ -0,0 +1,108 @@
+package matchserver
+
+import (
+	"math"
+	"testing"
+
+	"github.com/Ekotlikoff/gochess/internal/model"
+)
+
+func TestRatingUpdate(t *testing.T) {
+	// The example from Glickman's paper on the Glicko-2 system.
+	rating := Rating{Rating: 1500, Deviation: 200, Volatility: 0.06}
+	rating = rating.update([]ratingResult{
+		{Rating{Rating: 1400, Deviation: 30}, 1},
+		{Rating{Rating: 1550, Deviation: 100}, 0},
+		{Rating{Rating: 1700, Deviation: 300}, 0},
+	})
+	if math.Abs(rating.Rating-1464.06) > 0.01 ||
+		math.Abs(rating.Deviation-151.52) > 0.01 ||
+		math.Abs(rating.Volatility-0.05999) > 0.00001 || rating.Games != 3 {
+		t.Error("Expected 1464.06 (151.52) got ", rating)
+	}
+	rating = defaultRating.update(nil)
+	if rating.Rating != 1500 || rating.Deviation != maxDeviation {
+		t.Error("Expected an unrated player's deviation to be capped got ",
+			rating)
+	}
+}
+
+func TestRatingDeltas(t *testing.T) {
+	strong := Rating{Rating: 1800, Deviation: 60, Volatility: 0.06}
+	deltas := defaultRating.deltas(strong)
+	if deltas.Win <= 0 || deltas.Loss >= 0 || deltas.Draw <= 0 ||
+		deltas.Win < -deltas.Loss {
+		t.Error("Expected an upset to gain more than a loss costs got ",
+			deltas)
+	}
+}
+
+func TestRatingStore(t *testing.T) {
+	store := newRatingStore()
+	before, after := store.record(BlitzPool, [2]string{"black", "white"}, 1)
+	if before[model.Black] != defaultRating ||
+		after[model.White].Rating <= 1500 ||
+		after[model.Black].Rating >= 1500 ||
+		after[model.White].Rating-1500 != 1500-after[model.Black].Rating {
+		t.Error("Expected white's win to move both ratings got ", after)
+	}
+	if store.get(BlitzPool, "white") != after[model.White] ||
+		store.get(RapidPool, "white") != defaultRating {
+		t.Error("Expected the rating in the blitz pool only got ",
+			store.get(RapidPool, "white"))
+	}
+}
+
+func TestMatchingServerRated(t *testing.T) {
+	player1 := NewPlayer("player1")
+	player2 := NewPlayer("player2")
+	matchingServer := NewMatchingServer()
+	seek, _ := NewSeek("180+2", "")
+	seek.Rated = true
+	matchingServer.MatchPlayer(player1, seek)
+	matchingServer.MatchPlayer(player2, seek)
+	exitChan := make(chan bool, 1)
+	exitChan <- true
+	matchingServer.StartMatchServers(1, exitChan)
+	player1.WaitForMatchStart()
+	player2.WaitForMatchStart()
+	matchedResponse := player1.MatchedResponse()
+	if !matchedResponse.Rated || matchedResponse.Pool != BlitzPool ||
+		matchedResponse.Rating != defaultRating ||
+		matchedResponse.OpponentRating != defaultRating ||
+		matchedResponse.RatingDeltas.Win <= 0 {
+		t.Error("Expected a rated blitz match got ", matchedResponse)
+	}
+	player1.RequestAsync(RequestAsync{Resign: true})
+	response := <-player1.ResponseChanAsync
+	opponentResponse := <-player2.ResponseChanAsync
+	if response.RatingDelta >= 0 || opponentResponse.RatingDelta <= 0 ||
+		response.OpponentRating != opponentResponse.Rating ||
+		response.Rating != matchingServer.Rating("player1", BlitzPool) {
+		t.Error("Expected player1 to lose rating points got ", response)
+	}
+}
+
+func TestMatchingServerCasual(t *testing.T) {
+	player1 := NewPlayer("player1")
+	player2 := NewPlayer("player2")
+	matchingServer := NewMatchingServer()
+	seek, _ := NewSeek("180+2", "")
+	matchingServer.MatchPlayer(player1, seek)
+	matchingServer.MatchPlayer(player2, seek)
+	exitChan := make(chan bool, 1)
+	exitChan <- true
+	matchingServer.StartMatchServers(1, exitChan)
+	player1.WaitForMatchStart()
+	player2.WaitForMatchStart()
+	if player1.MatchedResponse().Rated {
+		t.Error("Expected a casual match")
+	}
+	player1.RequestAsync(RequestAsync{Resign: true})
+	response := <-player1.ResponseChanAsync
+	<-player2.ResponseChanAsync
+	if response.RatingDelta != 0 ||
+		matchingServer.Rating("player1", BlitzPool) != defaultRating {
+		t.Error("Expected the casual match to leave ratings got ", response)
+	}
+}
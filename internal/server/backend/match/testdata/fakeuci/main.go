@@ -0,0 +1,91 @@
+// Command fakeuci is a tiny UCI engine for the matchserver's tests. It plays
+// the first legal move in every position and appends each command it reads
+// to the file named by FAKEUCI_LOG if it is set.
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Ekotlikoff/gochess/internal/model"
+)
+
+func main() {
+	var log *os.File
+	if path := os.Getenv("FAKEUCI_LOG"); path != "" {
+		log, _ = os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	}
+	chess960 := false
+	game := model.NewGame()
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		command := scanner.Text()
+		if log != nil {
+			fmt.Fprintln(log, command)
+		}
+		fields := strings.Fields(command)
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "uci":
+			fmt.Println("id name fakeuci")
+			fmt.Println("option name UCI_Chess960 type check default false")
+			fmt.Println("option name UCI_LimitStrength type check default false")
+			fmt.Println("option name UCI_Elo type spin default 1500 min 500 max 2500")
+			fmt.Println("uciok")
+		case "setoption":
+			if command == "setoption name UCI_Chess960 value true" {
+				chess960 = true
+			}
+		case "isready":
+			fmt.Println("readyok")
+		case "position":
+			game = position(fields[1:], chess960)
+		case "go":
+			moves := []model.MoveRequest{}
+			if game != nil {
+				moves = game.LegalMoves()
+			}
+			if len(moves) == 0 {
+				fmt.Println("bestmove (none)")
+			} else {
+				fmt.Println("info depth 1")
+				fmt.Println("bestmove " + moves[0].UCI())
+			}
+		case "quit":
+			return
+		}
+	}
+}
+
+// position get the game set up by the arguments of a position command, or
+// nil if they are invalid
+func position(args []string, chess960 bool) *model.Game {
+	game := model.NewGame()
+	if len(args) >= 7 && args[0] == "fen" {
+		var err error
+		fen := strings.Join(args[1:7], " ")
+		if chess960 {
+			game, err = model.NewGameChess960FromFEN(fen)
+		} else {
+			game, err = model.NewGameFromFEN(fen)
+		}
+		if err != nil {
+			return nil
+		}
+		args = args[7:]
+	} else if len(args) >= 1 && args[0] == "startpos" {
+		args = args[1:]
+	}
+	if len(args) > 0 && args[0] == "moves" {
+		for _, move := range args[1:] {
+			if game.MoveUCI(move) != nil {
+				return nil
+			}
+		}
+	}
+	return game
+}
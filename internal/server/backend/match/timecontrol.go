@@ -0,0 +1,193 @@
+package matchserver
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxTimeControlSeconds is the longest time any part of a time control may
+// give, a day
+const maxTimeControlSeconds = 24 * 60 * 60
+
+type (
+	// TimeControlStage is a stage of a time control. Its base time is added
+	// to each player's clock as they reach it, and they must play its moves
+	// before their clock runs out, or the rest of the game if it has none.
+	// Each move adds the increment to the player's clock once played. A
+	// simple delay holds the clock for the delay before it runs each move, a
+	// Bronstein delay gives back the time the move took up to the delay.
+	TimeControlStage struct {
+		Moves       int
+		BaseMs      int64
+		IncrementMs int64
+		DelayMs     int64
+		Bronstein   bool
+	}
+
+	// TimeControl is a match's time control, as stages played in turn. A last
+	// stage with moves repeats, as in the PGN TimeControl tag.
+	TimeControl struct {
+		Stages []TimeControlStage
+	}
+
+	// Clock is a player's clock, with the increment and delay of their
+	// current time control stage. MovesToGo is the moves they must play
+	// before the next stage's base time is added, or 0 if none will be.
+	Clock struct {
+		RemainingMs int64
+		IncrementMs int64
+		DelayMs     int64
+		Bronstein   bool
+		MovesToGo   int
+	}
+
+	// playerClock is a player's clock and stage under the match's time
+	// control
+	playerClock struct {
+		remainingMs int64
+		stage       int
+		moves       int
+	}
+)
+
+// NewTimeControl create a single stage time control with the base time and
+// increment
+func NewTimeControl(baseMs, incrementMs int64) TimeControl {
+	return TimeControl{Stages: []TimeControlStage{
+		{BaseMs: baseMs, IncrementMs: incrementMs},
+	}}
+}
+
+// ParseTimeControl parse a time control in the notation of the PGN
+// TimeControl tag, stages separated by colons with times in seconds, for
+// example "40/5400+30:1800+30". A stage's delay follows a "d" for a simple
+// delay or a "b" for a Bronstein delay, for example "300d5".
+func ParseTimeControl(s string) (TimeControl, error) {
+	timeControl := TimeControl{}
+	for _, stageString := range strings.Split(s, ":") {
+		stage := TimeControlStage{}
+		var err error
+		if i := strings.Index(stageString, "/"); i >= 0 {
+			stage.Moves, err = strconv.Atoi(stageString[:i])
+			if err != nil || stage.Moves <= 0 {
+				return TimeControl{}, fmt.Errorf(
+					"timecontrol: invalid moves in %q", stageString)
+			}
+			stageString = stageString[i+1:]
+		}
+		if i := strings.IndexAny(stageString, "db"); i >= 0 {
+			stage.Bronstein = stageString[i] == 'b'
+			stage.DelayMs, err = parseSeconds(stageString[i+1:])
+			if err != nil {
+				return TimeControl{}, err
+			}
+			stageString = stageString[:i]
+		}
+		if i := strings.Index(stageString, "+"); i >= 0 {
+			stage.IncrementMs, err = parseSeconds(stageString[i+1:])
+			if err != nil {
+				return TimeControl{}, err
+			}
+			stageString = stageString[:i]
+		}
+		if stage.BaseMs, err = parseSeconds(stageString); err != nil {
+			return TimeControl{}, err
+		}
+		timeControl.Stages = append(timeControl.Stages, stage)
+	}
+	if timeControl.Stages[0].BaseMs == 0 {
+		return TimeControl{}, errors.New(
+			"timecontrol: the first stage must have a base time")
+	}
+	return timeControl, nil
+}
+
+func parseSeconds(s string) (int64, error) {
+	seconds, err := strconv.ParseFloat(s, 64)
+	// Not a number fails both comparisons.
+	if err != nil || !(seconds >= 0 && seconds <= maxTimeControlSeconds) {
+		return 0, fmt.Errorf("timecontrol: invalid seconds %q", s)
+	}
+	return int64(seconds * 1000), nil
+}
+
+// String get the time control in the notation ParseTimeControl parses
+func (timeControl TimeControl) String() string {
+	stages := []string{}
+	for _, stage := range timeControl.Stages {
+		s := formatSeconds(stage.BaseMs)
+		if stage.Moves > 0 {
+			s = strconv.Itoa(stage.Moves) + "/" + s
+		}
+		if stage.IncrementMs > 0 {
+			s += "+" + formatSeconds(stage.IncrementMs)
+		}
+		if stage.DelayMs > 0 && stage.Bronstein {
+			s += "b" + formatSeconds(stage.DelayMs)
+		} else if stage.DelayMs > 0 {
+			s += "d" + formatSeconds(stage.DelayMs)
+		}
+		stages = append(stages, s)
+	}
+	return strings.Join(stages, ":")
+}
+
+func formatSeconds(ms int64) string {
+	return strconv.FormatFloat(float64(ms)/1000, 'f', -1, 64)
+}
+
+// newClocks get the players' clocks at the start of a match, indexed by
+// color
+func (timeControl TimeControl) newClocks() [2]playerClock {
+	clock := playerClock{remainingMs: timeControl.Stages[0].BaseMs}
+	return [2]playerClock{clock, clock}
+}
+
+// flagAfter get how long the player's clock may run this move before it
+// falls, which a simple delay lengthens
+func (timeControl TimeControl) flagAfter(clock playerClock) time.Duration {
+	stage := timeControl.Stages[clock.stage]
+	flagMs := clock.remainingMs
+	if !stage.Bronstein {
+		flagMs += stage.DelayMs
+	}
+	return time.Duration(flagMs) * time.Millisecond
+}
+
+// punch stop the player's clock after a move that took the time, adding the
+// increment and the next stage's base time once its moves are played
+func (timeControl TimeControl) punch(clock *playerClock, usedMs int64) {
+	stage := timeControl.Stages[clock.stage]
+	// Either delay leaves the player charged for the time past the delay.
+	usedMs -= stage.DelayMs
+	if usedMs < 0 {
+		usedMs = 0
+	}
+	clock.remainingMs += stage.IncrementMs - usedMs
+	clock.moves++
+	if stage.Moves == 0 || clock.moves < stage.Moves {
+		return
+	}
+	clock.moves = 0
+	if clock.stage+1 < len(timeControl.Stages) {
+		clock.stage++
+	}
+	clock.remainingMs += timeControl.Stages[clock.stage].BaseMs
+}
+
+// clock get the player's clock as clients see it
+func (timeControl TimeControl) clock(clock playerClock) Clock {
+	stage := timeControl.Stages[clock.stage]
+	movesToGo := 0
+	if stage.Moves > 0 {
+		movesToGo = stage.Moves - clock.moves
+	}
+	return Clock{
+		RemainingMs: clock.remainingMs, IncrementMs: stage.IncrementMs,
+		DelayMs: stage.DelayMs, Bronstein: stage.Bronstein,
+		MovesToGo: movesToGo,
+	}
+}
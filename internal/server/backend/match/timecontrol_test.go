@@ -0,0 +1,169 @@
+package matchserver
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Ekotlikoff/gochess/internal/model"
+)
+
+func TestParseTimeControl(t *testing.T) {
+	for s, expected := range map[string][]TimeControlStage{
+		"300":   {{BaseMs: 300000}},
+		"180+2": {{BaseMs: 180000, IncrementMs: 2000}},
+		"40/5400+30:1800+30": {
+			{Moves: 40, BaseMs: 5400000, IncrementMs: 30000},
+			{BaseMs: 1800000, IncrementMs: 30000},
+		},
+		"600d5": {{BaseMs: 600000, DelayMs: 5000}},
+		"0.5+0.25b3": {{
+			BaseMs: 500, IncrementMs: 250, DelayMs: 3000, Bronstein: true,
+		}},
+		"40/7200:3600": {{Moves: 40, BaseMs: 7200000}, {BaseMs: 3600000}},
+	} {
+		timeControl, err := ParseTimeControl(s)
+		if err != nil || len(timeControl.Stages) != len(expected) {
+			t.Error("Expected ", expected, " got ", timeControl, err)
+			continue
+		}
+		for i := range expected {
+			if timeControl.Stages[i] != expected[i] {
+				t.Error("Expected ", expected, " got ", timeControl.Stages)
+			}
+		}
+		if timeControl.String() != s {
+			t.Error("Expected ", s, " got ", timeControl.String())
+		}
+	}
+	for _, s := range []string{"", "-", "0+2", "x/300", "0/300", "300+x",
+		"300d", "300:x", "inf", "NaN", "1e300", "300+inf", "300dNaN",
+		"86401", "40/300:-inf"} {
+		if _, err := ParseTimeControl(s); err == nil {
+			t.Error("Expected an error parsing ", s)
+		}
+	}
+}
+
+func TestTimeControlIncrement(t *testing.T) {
+	timeControl := NewTimeControl(60000, 2000)
+	clock := timeControl.newClocks()[model.White]
+	timeControl.punch(&clock, 5000)
+	if timeControl.clock(clock) != (Clock{RemainingMs: 57000,
+		IncrementMs: 2000}) {
+		t.Error("Expected the increment to be added got ", clock)
+	}
+	if timeControl.flagAfter(clock) != 57*time.Second {
+		t.Error("Expected the clock to fall after 57s got ",
+			timeControl.flagAfter(clock))
+	}
+}
+
+func TestTimeControlDelay(t *testing.T) {
+	for _, bronstein := range []bool{false, true} {
+		timeControl := TimeControl{Stages: []TimeControlStage{
+			{BaseMs: 60000, DelayMs: 5000, Bronstein: bronstein},
+		}}
+		clock := timeControl.newClocks()[model.White]
+		// A simple delay holds the clock, a Bronstein delay gives the time
+		// back, so the clock only runs out after the delay for the former.
+		flagAfter := 60 * time.Second
+		if !bronstein {
+			flagAfter += 5 * time.Second
+		}
+		if timeControl.flagAfter(clock) != flagAfter {
+			t.Error("Expected the clock to fall after ", flagAfter, " got ",
+				timeControl.flagAfter(clock))
+		}
+		timeControl.punch(&clock, 3000)
+		if clock.remainingMs != 60000 {
+			t.Error("Expected a move within the delay to be free got ",
+				clock.remainingMs)
+		}
+		timeControl.punch(&clock, 8000)
+		if clock.remainingMs != 57000 {
+			t.Error("Expected the time past the delay to be charged got ",
+				clock.remainingMs)
+		}
+	}
+}
+
+func TestTimeControlStages(t *testing.T) {
+	timeControl, _ := ParseTimeControl("2/60+10:30")
+	clock := timeControl.newClocks()[model.Black]
+	timeControl.punch(&clock, 20000)
+	if timeControl.clock(clock) != (Clock{RemainingMs: 50000,
+		IncrementMs: 10000, MovesToGo: 1}) {
+		t.Error("Expected one move to the control got ",
+			timeControl.clock(clock))
+	}
+	timeControl.punch(&clock, 20000)
+	if timeControl.clock(clock) != (Clock{RemainingMs: 70000}) {
+		t.Error("Expected the second stage's time to be added got ",
+			timeControl.clock(clock))
+	}
+	timeControl.punch(&clock, 20000)
+	if clock.remainingMs != 50000 || clock.stage != 1 {
+		t.Error("Expected the last stage to last the game got ", clock)
+	}
+	timeControl, _ = ParseTimeControl("1/60")
+	clock = timeControl.newClocks()[model.Black]
+	timeControl.punch(&clock, 20000)
+	timeControl.punch(&clock, 20000)
+	if clock.remainingMs != 140000 {
+		t.Error("Expected the last stage to repeat got ", clock)
+	}
+}
+
+func TestMatchingServerTimeControl(t *testing.T) {
+	player1 := NewPlayer("player1")
+	player2 := NewPlayer("player2")
+	matchingServer := NewMatchingServer()
+	go matchingServer.MatchPlayer(player1, Seek{})
+	go matchingServer.MatchPlayer(player2, Seek{})
+	exitChan := make(chan bool, 1)
+	exitChan <- true
+	timeControl, _ := ParseTimeControl("40/60+1:30")
+	generator := func(black *Player, white *Player) Match {
+		return NewTimeControlMatch(black, white, timeControl, model.Standard)
+	}
+	matchingServer.StartCustomMatchServers(1, generator, exitChan)
+	player1.WaitForMatchStart()
+	player2.WaitForMatchStart()
+	white, black := player1, player2
+	if player1.Color() == model.Black {
+		white, black = player2, player1
+	}
+	matchedResponse := black.MatchedResponse()
+	if matchedResponse.TimeControl.String() != "40/60+1:30" ||
+		matchedResponse.MaxTimeMs != 60000 ||
+		matchedResponse.Clock != matchedResponse.OpponentClock ||
+		matchedResponse.Clock != (Clock{RemainingMs: 60000, IncrementMs: 1000,
+			MovesToGo: 40}) {
+		t.Error("Expected the time control and clocks got ", matchedResponse)
+	}
+	response := white.MakeMoveResponse(model.MoveRequest{
+		Position: model.Position{File: 4, Rank: 1}, Move: model.Move{Y: 2},
+	})
+	if !response.MoveSuccess || response.Clock.RemainingMs <= 60000 ||
+		response.Clock.MovesToGo != 39 ||
+		response.OpponentClock != matchedResponse.Clock {
+		t.Error("Expected the increment on white's clock got ", response)
+	}
+	black.RequestAsync(RequestAsync{Resign: true})
+	white.GetAsyncUpdate()
+}
+
+func TestTimeControlMatchGenerators(t *testing.T) {
+	timeControl, _ := ParseTimeControl("180+2")
+	for _, generator := range []MatchGenerator{
+		CreateTimeControlMatchGenerator(timeControl, model.Atomic),
+		CreateTimeControlChess960MatchGenerator(timeControl),
+		CreateTimeControlBughouseMatchGenerator(timeControl),
+	} {
+		match := generator(NewPlayer("player1"), NewPlayer("player2"))
+		clock := match.Clock(model.White)
+		if clock.RemainingMs != 180000 || clock.IncrementMs != 2000 {
+			t.Error("Expected a 180+2 clock got ", clock)
+		}
+	}
+}
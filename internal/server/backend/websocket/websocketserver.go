@@ -51,26 +51,45 @@ func makeWebsocketHandler(matchServer *matchserver.MatchingServer,
 		defer c.Close()
 		waitc := make(chan struct{})
 		go readLoop(c, matchServer, player, wsHandlerSpan, waitc)
-		writeLoop(c, player, wsHandlerSpan)
+		matchStarted := writeLoop(c, player, wsHandlerSpan, waitc)
 		<-waitc
-		player.ClientDoneWithMatch()
+		if matchStarted {
+			// Without a match there is nothing for the client to be done
+			// with.
+			player.ClientDoneWithMatch()
+		}
 	}
 	return http.HandlerFunc(handler)
 }
 
+// writeLoop write the player's match to the client, and get whether it
+// started before the client disconnected
 func writeLoop(c *websocket.Conn, player *matchserver.Player,
-	span opentracing.Span) {
+	span opentracing.Span, waitc chan struct{}) bool {
 	tracer := opentracing.GlobalTracer()
-	err := player.WaitForMatchStart()
-	if err != nil {
-		log.Println("FATAL: Failed to find match")
+	// Wait for a match for as long as the client is connected, whatever
+	// match requests it makes and cancels.
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-waitc
+		cancel()
+	}()
+	waitForMatchSpan := tracer.StartSpan(
+		"WaitForMatchStart",
+		opentracing.ChildOf(span.Context()),
+	)
+	matchStarted := player.HasMatchStarted(ctx)
+	waitForMatchSpan.Finish()
+	if !matchStarted {
+		return false
 	}
+	player.SetSearchingForMatch(false)
+	// The read deadline is the underlying connection's, so it may be set
+	// here while the read loop reads.
+	c.SetReadDeadline(time.Now().Add(pongWait))
 	matchedResponse := matchserver.WebsocketResponse{
 		WebsocketResponseType: matchserver.MatchStartT,
-		MatchedResponse: matchserver.MatchedResponse{
-			Color: player.Color(), OpponentName: player.MatchedOpponentName(),
-			MaxTimeMs: player.MatchMaxTimeMs(),
-		},
+		MatchedResponse:       player.MatchedResponse(),
 	}
 	c.WriteJSON(&matchedResponse)
 	ticker := time.NewTicker(pingPeriod)
@@ -101,12 +120,18 @@ func writeLoop(c *websocket.Conn, player *matchserver.Player,
 				WebsocketResponseType: matchserver.OpponentPlayedMoveT,
 				OpponentPlayedMove:    OpponentMove,
 			}
+		case legalMoves := <-player.ResponseChanLegalMoves:
+			resType = "legalMoves"
+			response = matchserver.WebsocketResponse{
+				WebsocketResponseType: matchserver.ResponseLegalMovesT,
+				LegalMoves:            legalMoves,
+			}
 		case <-ticker.C:
 			getResSpan.LogFields(opentracinglog.String("resType", "ping"))
 			if err := c.WriteMessage(websocket.PingMessage, nil); err != nil {
 				log.Println("FATAL Write PingMessage error:", err)
 				getResSpan.Finish()
-				return
+				return true
 			}
 			getResSpan.Finish()
 			continue
@@ -116,10 +141,10 @@ func writeLoop(c *websocket.Conn, player *matchserver.Player,
 		err := c.WriteJSON(response)
 		if err != nil {
 			log.Println("Write error:", err)
-			return
+			return true
 		} else if response.WebsocketResponseType == matchserver.ResponseAsyncT &&
 			response.ResponseAsync.GameOver {
-			return
+			return true
 		}
 	}
 }
@@ -128,6 +153,10 @@ func readLoop(c *websocket.Conn, matchServer *matchserver.MatchingServer,
 	player *matchserver.Player, span opentracing.Span, waitc chan struct{}) {
 	defer c.Close()
 	tracer := opentracing.GlobalTracer()
+	c.SetPongHandler(func(string) error {
+		c.SetReadDeadline(time.Now().Add(pongWait))
+		return nil
+	})
 	for {
 		message := matchserver.WebsocketRequest{}
 		if player.GetMatch() != nil && player.GetMatch().GameOver() {
@@ -144,8 +173,10 @@ func readLoop(c *websocket.Conn, matchServer *matchserver.MatchingServer,
 			}
 			if player.GetMatch() != nil && !player.GetMatch().GameOver() {
 				player.RequestChanAsync <- matchserver.RequestAsync{
-					Resign: true,
+					Abandon: true,
 				}
+			} else if matchServer.CancelSeek(player) {
+				player.SetSearchingForMatch(false)
 			}
 			close(waitc)
 			readWSSpan.LogFields(opentracinglog.String("readType", "ConnClosed"))
@@ -162,6 +193,11 @@ func readLoop(c *websocket.Conn, matchServer *matchserver.MatchingServer,
 			)
 			player.MakeMoveWS(message.RequestSync)
 			makeMoveSpan.Finish()
+		case matchserver.RequestLegalMovesT:
+			if err := player.RequestLegalMovesWS(
+				message.LegalMovesFrom); err != nil {
+				log.Println("Legal moves query error:", err)
+			}
 		case matchserver.RequestAsyncT:
 			if message.RequestAsync.Match {
 				ctx, cancel := context.WithTimeout(
@@ -169,23 +205,23 @@ func readLoop(c *websocket.Conn, matchServer *matchserver.MatchingServer,
 				defer cancel()
 				if !player.GetSearchingForMatch() &&
 					!player.HasMatchStarted(ctx) {
+					seek, err := matchserver.NewSeek(
+						message.RequestAsync.TimeControl,
+						message.RequestAsync.Variant)
+					if err != nil {
+						log.Println("Invalid seek:", err)
+						continue
+					}
+					seek.Rated = message.RequestAsync.Rated
 					player.SetSearchingForMatch(true)
-					matchServer.MatchPlayer(player)
-					waitForMatchSpan := tracer.StartSpan(
-						"WaitForMatchStart",
-						opentracing.ChildOf(span.Context()),
-					)
-					err := player.WaitForMatchStart()
-					waitForMatchSpan.Finish()
-					if err == nil {
+					// The write loop waits for the match to start, so that a
+					// cancel request can still be read.
+					if message.RequestAsync.BotLevel == "" {
+						matchServer.MatchPlayer(player, seek)
+					} else if err := matchServer.MatchPlayerWithBot(player,
+						message.RequestAsync.BotLevel, seek); err != nil {
+						log.Println("Failed to match with a bot:", err)
 						player.SetSearchingForMatch(false)
-						c.SetReadDeadline(time.Now().Add(pongWait))
-						c.SetPongHandler(func(string) error {
-							c.SetReadDeadline(time.Now().Add(pongWait))
-							return nil
-						})
-					} else {
-						log.Println("FATAL: Failed to find match")
 					}
 				}
 			} else {
@@ -196,6 +232,10 @@ func readLoop(c *websocket.Conn, matchServer *matchserver.MatchingServer,
 				player.RequestAsync(message.RequestAsync)
 				requestAsyncSpan.Finish()
 			}
+		case matchserver.CancelSeekT:
+			if matchServer.CancelSeek(player) {
+				player.SetSearchingForMatch(false)
+			}
 		}
 	}
 }
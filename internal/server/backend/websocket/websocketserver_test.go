@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"log"
 	"net/http"
 	"net/http/cookiejar"
 	"net/http/httptest"
@@ -93,6 +94,16 @@ func TestWSMatch(t *testing.T) {
 	if enemyResp.OpponentPlayedMove.Move.Y != 2 {
 		t.Error("Expected opponent's move")
 	}
+	black.WriteJSON(&matchserver.WebsocketRequest{
+		WebsocketRequestType: matchserver.RequestLegalMovesT,
+		LegalMovesFrom:       &model.Position{File: 0, Rank: 6},
+	})
+	black.ReadJSON(&playerResp)
+	if playerResp.WebsocketResponseType != matchserver.ResponseLegalMovesT ||
+		playerResp.LegalMoves.Turn != model.Black ||
+		len(playerResp.LegalMoves.Moves) != 2 {
+		t.Error("Expected the pawn's two moves got ", playerResp.LegalMoves)
+	}
 	playerResp, enemyResp = makeMove(0, 6, 0, -1, black, white)
 	if !playerResp.ResponseSync.MoveSuccess {
 		t.Error("Expected valid move response")
@@ -442,6 +453,99 @@ func makeMove(x uint8, y uint8, moveX int8, moveY int8, player *websocket.Conn,
 	return playerResponse, enemyResponse
 }
 
+func TestWSCancelSeek(t *testing.T) {
+	u := "ws" + strings.TrimPrefix(serverMatchAndPlay.URL, "http")
+	conns := []*websocket.Conn{}
+	for _, name := range []string{"cancel1", "cancel2", "cancel3"} {
+		jar, _ := cookiejar.New(&cookiejar.Options{})
+		client := &http.Client{Jar: jar}
+		startSession(client, name)
+		wsDialer := &websocket.Dialer{
+			Proxy:            http.ProxyFromEnvironment,
+			HandshakeTimeout: 45 * time.Second,
+			Jar:              client.Jar,
+		}
+		ws, _, err := wsDialer.Dial(u, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer ws.Close()
+		conns = append(conns, ws)
+	}
+	conns[0].WriteJSON(&matchserver.WebsocketRequest{
+		WebsocketRequestType: matchserver.RequestAsyncT,
+		RequestAsync:         matchserver.RequestAsync{Match: true},
+	})
+	conns[0].WriteJSON(&matchserver.WebsocketRequest{
+		WebsocketRequestType: matchserver.CancelSeekT,
+	})
+	// Give the server time to read the cancel request, after which the
+	// cancelled player would be paired first were they still waiting.
+	time.Sleep(50 * time.Millisecond)
+	message := matchserver.WebsocketRequest{
+		WebsocketRequestType: matchserver.RequestAsyncT,
+		RequestAsync:         matchserver.RequestAsync{Match: true},
+	}
+	conns[1].WriteJSON(&message)
+	conns[2].WriteJSON(&message)
+	wsResponse := matchserver.WebsocketResponse{}
+	conns[1].ReadJSON(&wsResponse)
+	if wsResponse.WebsocketResponseType != matchserver.MatchStartT ||
+		wsResponse.MatchedResponse.OpponentName != "cancel3" {
+		t.Error("Expected cancel2 to be matched with cancel3 got ",
+			wsResponse.MatchedResponse.OpponentName)
+	}
+}
+
+// errorLogWriter passes each line the server logs, such as a panic serving
+// a request, to the channel
+type errorLogWriter chan string
+
+func (errorLog errorLogWriter) Write(p []byte) (int, error) {
+	select {
+	case errorLog <- string(p):
+	default:
+	}
+	return len(p), nil
+}
+
+func TestWSCancelSeekDisconnect(t *testing.T) {
+	matchingServer := matchserver.NewMatchingServer()
+	errorLog := make(errorLogWriter, 10)
+	server := httptest.NewUnstartedServer(
+		makeWebsocketHandler(&matchingServer))
+	server.Config.ErrorLog = log.New(errorLog, "", 0)
+	server.Start()
+	defer server.Close()
+	jar, _ := cookiejar.New(&cookiejar.Options{})
+	client := &http.Client{Jar: jar}
+	startSession(client, "disconnect1")
+	wsDialer := &websocket.Dialer{Jar: client.Jar}
+	u := "ws" + strings.TrimPrefix(server.URL, "http")
+	for _, cancel := range []bool{true, false} {
+		ws, _, err := wsDialer.Dial(u, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ws.WriteJSON(&matchserver.WebsocketRequest{
+			WebsocketRequestType: matchserver.RequestAsyncT,
+			RequestAsync:         matchserver.RequestAsync{Match: true},
+		})
+		if cancel {
+			ws.WriteJSON(&matchserver.WebsocketRequest{
+				WebsocketRequestType: matchserver.CancelSeekT,
+			})
+		}
+		time.Sleep(20 * time.Millisecond)
+		ws.Close()
+	}
+	select {
+	case logged := <-errorLog:
+		t.Error("Expected the handler to end cleanly got ", logged)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
 func startSession(client *http.Client, username string) {
 	credentialsBuf := new(bytes.Buffer)
 	credentials := gateway.Credentials{Username: username}
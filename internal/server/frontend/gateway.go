@@ -75,6 +75,8 @@ func Serve(httpBackend *url.URL, websocketBackend *url.URL, port int) {
 	mux.Handle("/http/match", prometheusMiddleware(httpBackendProxy))
 	mux.Handle("/http/sync", prometheusMiddleware(httpBackendProxy))
 	mux.Handle("/http/async", prometheusMiddleware(httpBackendProxy))
+	mux.Handle("/http/moves", prometheusMiddleware(httpBackendProxy))
+	mux.Handle("/http/analysis", prometheusMiddleware(httpBackendProxy))
 	// Websocket backend proxying
 	mux.Handle("/ws", wsBackendProxy)
 	// Prometheus metrics endpoint